@@ -0,0 +1,47 @@
+package txparser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidBlockRange = errors.New("invalid block range")
+
+// Backfill parses blocks from fromBlock to toBlock inclusively and saves transactions
+// of subscribed addresses. Unlike the worker it neither moves the last parsed block
+// nor notifies listeners, so it may run alongside the worker. Saving is idempotent,
+// blocks parsed earlier may be backfilled again.
+func (p *TXParser) Backfill(ctx context.Context, fromBlock, toBlock int) error {
+	if fromBlock <= 0 || toBlock < fromBlock {
+		return fmt.Errorf("%w: from %d to %d", ErrInvalidBlockRange, fromBlock, toBlock)
+	}
+
+	client := p.getClient()
+	for blockID := fromBlock; blockID <= toBlock; blockID++ {
+		err := ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		block, err := client.GetBlockByNumber(ctx, blockID)
+		if err != nil {
+			return fmt.Errorf("get block %d: %w", blockID, err)
+		}
+
+		var events []Event
+		err = p.transactionsStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+			var err error
+			events, err = p.saveBlockTransactions(ctx, block)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("save block %d: %w", blockID, err)
+		}
+		if p.metrics != nil {
+			p.metrics.transactionsStored.add(float64(len(events)))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package txparser_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"txparser"
+)
+
+func Test_Parser_Backfill(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	txStorage := txparser.NewInmemoryTransactionsStorage()
+	subscriptionsStorage := txparser.NewInmemorySubscriptionsStorage()
+	_ = blockStorage.SaveBlockID(ctx, 20)
+	_ = subscriptionsStorage.PutAddress(ctx, "0x123")
+	client := &blocksClient{
+		head: 20,
+		blocks: map[int][]txparser.Transaction{
+			3: {{BlockNumber: "0x3", TransactionIndex: "0x0", Hash: "0xa30", From: "0x123", To: "0x321"}},
+			4: {{BlockNumber: "0x4", TransactionIndex: "0x0", Hash: "0xa40", From: "0x456", To: "0x321"}},
+			5: {{BlockNumber: "0x5", TransactionIndex: "0x0", Hash: "0xa50", From: "0x321", To: "0x123"}},
+			6: {{BlockNumber: "0x6", TransactionIndex: "0x0", Hash: "0xa60", From: "0x123", To: "0x123"}},
+		},
+	}
+	parser := txparser.NewTXParser(blockStorage, txStorage, subscriptionsStorage, client)
+	events, stop := parser.Listen(10)
+	defer stop()
+
+	// Act
+	err := parser.Backfill(ctx, 1, 5)
+	_ = parser.Backfill(ctx, 5, 5)
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	transactions := parser.GetTransactions("0x123")
+	if !areSlicesEqual(hashesOf(transactions), []string{"0xa30", "0xa50"}) {
+		t.Errorf("transactions should be %v, but are %v", []string{"0xa30", "0xa50"}, hashesOf(transactions))
+	}
+	if parser.GetCurrentBlock() != 20 {
+		t.Errorf("current block should be %d, but is %d", 20, parser.GetCurrentBlock())
+	}
+	if got := drainEvents(events); len(got) != 0 {
+		t.Errorf("events slice should have %d item(s), but has %d", 0, len(got))
+	}
+}
+
+func Test_Parser_BackfillInvalidRange(t *testing.T) {
+	for _, blocks := range [][2]int{{0, 5}, {5, 4}} {
+		// Arrange
+		parser := txparser.NewTXParser(
+			txparser.NewInmemoryBlockStorage(),
+			txparser.NewInmemoryTransactionsStorage(),
+			txparser.NewInmemorySubscriptionsStorage(),
+			&blocksClient{},
+		)
+
+		// Act
+		err := parser.Backfill(context.Background(), blocks[0], blocks[1])
+
+		// Assert
+		if !errors.Is(err, txparser.ErrInvalidBlockRange) {
+			t.Errorf("error for %v should be %v, but is %v", blocks, txparser.ErrInvalidBlockRange, err)
+		}
+	}
+}
@@ -7,36 +7,101 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"math/big"
 	"math/rand"
 	"net/http"
 	"strconv"
+	"time"
 )
 
+// JSONRPCCall is a JSON-RPC 2.0 request, ID is a string or a number.
 type JSONRPCCall struct {
 	Jsonrpc string `json:"jsonrpc"`
 	Method  string `json:"method"`
 	Params  []any  `json:"params"`
-	ID      string `json:"id"`
+	ID      any    `json:"id"`
 }
 
+// JSONRPCResponse is a JSON-RPC 2.0 response, it has either Result or Error.
 type JSONRPCResponse struct {
-	Jsonrpc string `json:"jsonrpc"`
-	Result  any    `json:"result"`
-	ID      string `json:"ID"`
+	Jsonrpc string        `json:"jsonrpc"`
+	Result  any           `json:"result,omitempty"`
+	Error   *JSONRPCError `json:"error,omitempty"`
+	ID      any           `json:"id"`
+}
+
+// MarshalJSON sends the result of every response without an error, even a null one,
+// as JSON-RPC 2.0 requires.
+func (r JSONRPCResponse) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		type response JSONRPCResponse
+		return json.Marshal(response(r))
+	}
+
+	return json.Marshal(struct {
+		Jsonrpc string `json:"jsonrpc"`
+		Result  any    `json:"result"`
+		ID      any    `json:"id"`
+	}{Jsonrpc: r.Jsonrpc, Result: r.Result, ID: r.ID})
+}
+
+// Standard JSON-RPC 2.0 error codes.
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+	JSONRPCInvalidParams  = -32602
+	JSONRPCInternalError  = -32603
+)
+
+type JSONRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
 }
 
 type JSONRPCClient struct {
 	httpClient *http.Client
 	host       string
+	metrics    *Metrics
+	logger     *slog.Logger
+}
+
+type ClientOption func(*JSONRPCClient)
+
+// WithClientLogger sets the logger of requests, records are dropped by default or if it is nil.
+func WithClientLogger(logger *slog.Logger) ClientOption {
+	return func(c *JSONRPCClient) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithClientMetrics collects latencies and errors of requests by method.
+func WithClientMetrics(metrics *Metrics) ClientOption {
+	return func(c *JSONRPCClient) {
+		c.metrics = metrics
+	}
 }
 
-func NewJSONRPCClient(httpClient *http.Client, host string) *JSONRPCClient {
-	return &JSONRPCClient{
+func NewJSONRPCClient(httpClient *http.Client, host string, opts ...ClientOption) *JSONRPCClient {
+	c := &JSONRPCClient{
 		httpClient: httpClient,
 		host:       host,
+		logger:     discardLogger(),
 	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
 }
 
 func (c *JSONRPCClient) CurrentBlockNumber(ctx context.Context) (int, error) {
@@ -106,6 +171,24 @@ func (c *JSONRPCClient) GetBlockByNumber(ctx context.Context, number int) (*Bloc
 }
 
 func (c *JSONRPCClient) doRequest(ctx context.Context, method string, params ...any) (*JSONRPCResponse, error) {
+	start := time.Now()
+	r, errType, err := c.call(ctx, method, params)
+	if c.metrics != nil {
+		c.metrics.observeRPC(method, start, errType)
+	}
+
+	if err != nil {
+		c.logger.WarnContext(ctx, "rpc request failed",
+			"method", method, "params", params, "error_type", errType, "duration", time.Since(start), "error", err)
+	} else {
+		c.logger.DebugContext(ctx, "rpc request", "method", method, "params", params, "duration", time.Since(start))
+	}
+
+	return r, err
+}
+
+// call sends the request and returns the type of its error, see RPCErrorTransport.
+func (c *JSONRPCClient) call(ctx context.Context, method string, params []any) (*JSONRPCResponse, string, error) {
 	id := randomID()
 
 	payload, err := json.Marshal(JSONRPCCall{
@@ -115,12 +198,12 @@ func (c *JSONRPCClient) doRequest(ctx context.Context, method string, params ...
 		ID:      id,
 	})
 	if err != nil {
-		return nil, err
+		return nil, RPCErrorTransport, err
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.host, bytes.NewBuffer(payload))
 	if err != nil {
-		return nil, err
+		return nil, RPCErrorTransport, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -128,31 +211,35 @@ func (c *JSONRPCClient) doRequest(ctx context.Context, method string, params ...
 	//nolint:bodyclose
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, RPCErrorTransport, err
 	}
 	defer func(body io.ReadCloser) {
 		err := body.Close()
 		if err != nil {
-			log.Print(err)
+			c.logger.WarnContext(ctx, "close rpc response body", "method", method, "error", err)
 		}
 	}(resp.Body)
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("invalid http status code")
+		return nil, RPCErrorStatus, errors.New("invalid http status code")
 	}
 
 	r := JSONRPCResponse{}
 	decoder := json.NewDecoder(resp.Body)
 	err = decoder.Decode(&r)
 	if err != nil {
-		return nil, err
+		return nil, RPCErrorDecode, err
 	}
 
 	if r.ID != id {
-		return nil, errors.New("id does not match")
+		return nil, RPCErrorDecode, errors.New("id does not match")
 	}
 
-	return &r, nil
+	if r.Error != nil {
+		return nil, RPCErrorRPC, r.Error
+	}
+
+	return &r, "", nil
 }
 
 func convertHexToNum(s string) (*big.Int, error) {
@@ -189,6 +276,11 @@ func convertMapRawToTransaction(v map[string]any) (Transaction, error) {
 		return Transaction{}, errors.New("invalid structure, invalid hash")
 	}
 
+	transactionIndex, ok := v["transactionIndex"].(string)
+	if !ok {
+		return Transaction{}, errors.New("invalid structure, invalid transaction index")
+	}
+
 	from, ok := v["from"].(string)
 	if !ok {
 		return Transaction{}, errors.New("invalid structure, invalid 'from' value")
@@ -205,12 +297,13 @@ func convertMapRawToTransaction(v map[string]any) (Transaction, error) {
 	}
 
 	return Transaction{
-		BlockNumber: blockNumber,
-		BlockHash:   blockHash,
-		Hash:        hash,
-		From:        from,
-		To:          to,
-		Value:       value,
+		BlockNumber:      blockNumber,
+		BlockHash:        blockHash,
+		Hash:             hash,
+		TransactionIndex: transactionIndex,
+		From:             from,
+		To:               to,
+		Value:            value,
 	}, nil
 }
 
@@ -1,42 +1,132 @@
-//go:build integration
-// +build integration
-
 package txparser_test
 
 import (
 	"context"
-	"net/http"
+	"encoding/json"
 	"testing"
 
 	"txparser"
+	"txparser/txparsertest"
 )
 
 func Test_Client_CurrentBlockNumber(t *testing.T) {
+	// Arrange
 	ctx := context.Background()
-	client := txparser.NewJSONRPCClient(http.DefaultClient, "https://cloudflare-eth.com")
+	node := txparsertest.NewNode()
+	defer node.Close()
+	node.Mine()
+	node.Mine()
+	client := node.Client()
 
+	// Act
 	number, err := client.CurrentBlockNumber(ctx)
 
+	// Assert
 	if err != nil {
 		t.Error(err)
 		t.FailNow()
 	}
-	if number == 0 {
-		t.Error("number should be more than 0")
+	if number != 2 {
+		t.Errorf("number should be %d, but is %d", 2, number)
 	}
 }
 
 func Test_Client_GetBlockByNumber(t *testing.T) {
+	// Arrange
 	ctx := context.Background()
-	client := txparser.NewJSONRPCClient(http.DefaultClient, "https://cloudflare-eth.com")
+	node := txparsertest.NewNode()
+	defer node.Close()
+	hashes := node.Send(
+		txparsertest.Tx{From: "0x123", To: "0x321", Value: "0xde0b6b3a7640000"},
+		txparsertest.Tx{From: "0x321", To: "0x1337"},
+	)
+	number := node.Mine()
+	client := node.Client()
 
-	block, err := client.GetBlockByNumber(ctx, 17948861)
+	// Act
+	block, err := client.GetBlockByNumber(ctx, number)
 
+	// Assert
 	if err != nil {
 		t.Error(err)
 		t.FailNow()
 	}
-	if len(block.Transactions) != 118 {
-		t.Errorf("transactions slice should have %d item(s), but has %d", 118, len(block.Transactions))
+	if block.Number != "0x1" || block.Hash != node.BlockHash(number) {
+		t.Errorf("block should be %d %s, but is %s %s", number, node.BlockHash(number), block.Number, block.Hash)
+	}
+	if len(block.Transactions) != 2 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 2, len(block.Transactions))
+		t.FailNow()
+	}
+	if !areStructsEqual(block.Transactions[0], txparser.Transaction{
+		BlockNumber:      "0x1",
+		BlockHash:        node.BlockHash(number),
+		Hash:             hashes[0],
+		TransactionIndex: "0x0",
+		From:             "0x123",
+		To:               "0x321",
+		Value:            "0xde0b6b3a7640000",
+	}) {
+		t.Errorf("transaction should be decoded, but is %+v", block.Transactions[0])
+	}
+}
+
+func Test_Client_GetBlockByNumber_Missing(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	node := txparsertest.NewNode()
+	defer node.Close()
+	client := node.Client()
+
+	// Act
+	_, err := client.GetBlockByNumber(ctx, 1)
+
+	// Assert
+	if err == nil {
+		t.Error("error should be returned for a block after the head")
+	}
+}
+
+func Test_JSONRPCResponse_Marshal(t *testing.T) {
+	tests := []struct {
+		name     string
+		response txparser.JSONRPCResponse
+		want     string
+	}{
+		{
+			name:     "null result",
+			response: txparser.JSONRPCResponse{Jsonrpc: "2.0", ID: 1},
+			want:     `{"jsonrpc":"2.0","result":null,"id":1}`,
+		},
+		{
+			name:     "result",
+			response: txparser.JSONRPCResponse{Jsonrpc: "2.0", Result: "0x1", ID: "a"},
+			want:     `{"jsonrpc":"2.0","result":"0x1","id":"a"}`,
+		},
+		{
+			name: "error",
+			response: txparser.JSONRPCResponse{
+				Jsonrpc: "2.0",
+				Error:   &txparser.JSONRPCError{Code: txparser.JSONRPCInternalError, Message: "failed"},
+				ID:      1,
+			},
+			want: `{"jsonrpc":"2.0","error":{"code":-32603,"message":"failed"},"id":1}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			data, err := json.Marshal(test.response)
+
+			// Assert
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if string(data) != test.want {
+				t.Errorf("response should be %s, but is %s", test.want, data)
+			}
+		})
 	}
 }
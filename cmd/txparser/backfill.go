@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+func (c *cli) backfill(ctx context.Context, args []string) error {
+	var (
+		o         options
+		fromBlock int
+		toBlock   int
+		batch     int
+	)
+	fs := c.flagSet("backfill", "[address]...")
+	o.register(fs)
+	fs.IntVar(&fromBlock, "from", 0, "first block to parse, required")
+	fs.IntVar(&toBlock, "to", 0, "last block to parse, the current head if zero")
+	fs.IntVar(&batch, "batch", 100, "number of blocks between progress reports")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fromBlock <= 0 || batch <= 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	addresses, err := parseAddresses(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	_, s, err := o.open(ctx, c.stderr)
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+
+	for _, address := range addresses {
+		err = s.Subscriptions.PutAddress(ctx, address)
+		if err != nil {
+			return err
+		}
+	}
+
+	subscribed, err := s.Subscriptions.GetAddresses(ctx)
+	if err != nil {
+		return err
+	}
+	if len(subscribed) == 0 {
+		return errors.New("no subscribed addresses, pass addresses to backfill")
+	}
+
+	if toBlock == 0 {
+		toBlock, err = s.Client.CurrentBlockNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("get current block: %w", err)
+		}
+	}
+
+	for from := fromBlock; from <= toBlock; from += batch {
+		to := from + batch - 1
+		if to > toBlock {
+			to = toBlock
+		}
+
+		err = s.Parser.Backfill(ctx, from, to)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(c.stderr, "backfilled blocks %d-%d of %d-%d\n", from, to, fromBlock, toBlock)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"txparser"
+)
+
+func (c *cli) export(ctx context.Context, args []string) (err error) {
+	var (
+		o         options
+		format    string
+		output    string
+		fromBlock int
+		toBlock   int
+	)
+	fs := c.flagSet("export", "[address]...")
+	o.register(fs)
+	fs.StringVar(&format, "format", "json", "output format: text, json or csv")
+	fs.StringVar(&output, "o", "", "output file, stdout if empty")
+	fs.IntVar(&fromBlock, "from", 0, "first block of exported transactions")
+	fs.IntVar(&toBlock, "to", 0, "last block of exported transactions")
+	err = parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	addresses, err := parseAddresses(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	_, s, err := o.open(ctx, c.stderr)
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+
+	// All subscribed addresses are exported by default
+	if len(addresses) == 0 {
+		addresses, err = s.Subscriptions.GetAddresses(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = c.stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			closeErr := f.Close()
+			if err == nil {
+				err = closeErr
+			}
+		}()
+		w = f
+	}
+
+	out, err := newTransactionWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		err = exportAddress(ctx, s.Parser, out, txparser.TransactionsQuery{
+			Address:   address,
+			Limit:     txparser.MaxQueryLimit,
+			FromBlock: fromBlock,
+			ToBlock:   toBlock,
+		})
+		if err != nil {
+			return fmt.Errorf("export %s: %w", address, err)
+		}
+	}
+
+	return nil
+}
+
+// exportAddress writes every page of the query.
+func exportAddress(
+	ctx context.Context,
+	parser *txparser.TXParser,
+	out *transactionWriter,
+	query txparser.TransactionsQuery,
+) error {
+	for {
+		page, err := parser.QueryTransactions(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		for _, tx := range page.Transactions {
+			err = out.Write(query.Address, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = out.Flush()
+		if err != nil {
+			return err
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
@@ -0,0 +1,115 @@
+// Command txparser parses transactions of subscribed Ethereum addresses.
+//
+// Usage:
+//
+//	txparser <command> [flags] [arguments]
+//
+// Commands:
+//
+//	serve     serve the HTTP API and parse new blocks
+//	watch     print new transactions of addresses
+//	backfill  parse a range of blocks into the storage
+//	export    print stored transactions
+//	status    print the last parsed block, the lag and subscriptions
+//
+// Every flag may be set by the TXPARSER_<FLAG> environment variable as well,
+// such as TXPARSER_RPC_URL for -rpc-url. The -config flag reads a JSON or TOML
+// file of the config package, variables override the file and flags override both.
+// serve reloads the file on SIGHUP and when it changes.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+var errUsage = errors.New("usage")
+
+type command struct {
+	summary string
+	run     func(c *cli, ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"serve":    {summary: "serve the HTTP API and parse new blocks", run: (*cli).serve},
+	"watch":    {summary: "print new transactions of addresses", run: (*cli).watch},
+	"backfill": {summary: "parse a range of blocks into the storage", run: (*cli).backfill},
+	"export":   {summary: "print stored transactions", run: (*cli).export},
+	"status":   {summary: "print the last parsed block, the lag and subscriptions", run: (*cli).status},
+}
+
+type cli struct {
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	c := &cli{stdout: os.Stdout, stderr: os.Stderr}
+	err := c.run(ctx, os.Args[1:])
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(c.stderr, "txparser:", err)
+		os.Exit(1)
+	}
+}
+
+func (c *cli) run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		c.usage()
+		return errUsage
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		if args[0] != "help" && args[0] != "-h" && args[0] != "--help" {
+			fmt.Fprintf(c.stderr, "txparser: unknown command %q\n", args[0])
+		}
+		c.usage()
+		return errUsage
+	}
+
+	return cmd.run(c, ctx, args[1:])
+}
+
+func (c *cli) usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("Usage: txparser <command> [flags] [arguments]\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "  %-9s %s\n", name, commands[name].summary)
+	}
+	b.WriteString("\nRun \"txparser <command> -h\" for the flags of a command.\n")
+
+	fmt.Fprint(c.stderr, b.String())
+}
+
+// flagSet returns a flag set of the command writing errors and help to stderr.
+func (c *cli) flagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(c.stderr, strings.TrimSpace("Usage: txparser "+name+" [flags] "+arguments))
+		fmt.Fprint(c.stderr, "\nFlags, also set by TXPARSER_<FLAG> variables:\n")
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
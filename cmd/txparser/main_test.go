@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"txparser"
+)
+
+const (
+	address      = "0x0d1d4e623d10f9fba5db95830f7d3839406c6af2"
+	otherAddress = "0x00000000000000000000000000000000000000aa"
+)
+
+func Test_CLI_BackfillExportStatus(t *testing.T) {
+	// Arrange
+	node := newTestNode(t, 12, map[int][]txparser.Transaction{
+		3: {{BlockNumber: "0x3", TransactionIndex: "0x0", Hash: "0xa3", From: address, To: otherAddress, Value: "0x1"}},
+		5: {{BlockNumber: "0x5", TransactionIndex: "0x0", Hash: "0xa5", From: otherAddress, To: address, Value: "0x2"}},
+	})
+	storage := []string{"-rpc-url", node.URL, "-storage", "file", "-dsn", t.TempDir()}
+
+	// Act
+	_, backfillErr := runCLI(t, append(append([]string{"backfill"}, storage...), "-from", "1", "-batch", "4", address)...)
+	exported, exportErr := runCLI(t, append([]string{"export"}, storage...)...)
+	status, statusErr := runCLI(t, append(append([]string{"status"}, storage...), "-format", "json")...)
+
+	// Assert
+	for _, err := range []error{backfillErr, exportErr, statusErr} {
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(exported), "\n")
+	if len(lines) != 2 {
+		t.Errorf("exported lines slice should have %d item(s), but has %d: %s", 2, len(lines), exported)
+		t.FailNow()
+	}
+	for i, wantHash := range []string{"0xa3", "0xa5"} {
+		var line addressTransaction
+		err := json.Unmarshal([]byte(lines[i]), &line)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if line.Address != address || line.Hash != wantHash {
+			t.Errorf("line %d should be transaction %s of %s, but is %+v", i, wantHash, address, line)
+		}
+	}
+
+	var report statusReport
+	err := json.Unmarshal([]byte(status), &report)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	want := statusReport{LastParsedBlock: 0, Head: 12, Lag: 12, Subscriptions: []string{address}}
+	if fmt.Sprint(report) != fmt.Sprint(want) {
+		t.Errorf("status should be %+v, but is %+v", want, report)
+	}
+}
+
+func Test_CLI_Environment(t *testing.T) {
+	// Arrange
+	node := newTestNode(t, 7, nil)
+	t.Setenv("TXPARSER_RPC_URL", "http://127.0.0.1:1")
+	t.Setenv("TXPARSER_STORAGE", "memory")
+
+	// Act
+	_, envErr := runCLI(t, "status")
+	out, flagErr := runCLI(t, "status", "-rpc-url", node.URL)
+
+	// Assert
+	if envErr == nil {
+		t.Error("status should fail with the node of the environment")
+	}
+	if flagErr != nil {
+		t.Error(flagErr)
+		t.FailNow()
+	}
+	if !strings.Contains(out, "head") || !strings.Contains(out, "7") {
+		t.Errorf("status should report head %d, but is %q", 7, out)
+	}
+}
+
+func Test_CLI_ConfigFile(t *testing.T) {
+	// Arrange
+	node := newTestNode(t, 9, nil)
+	path := filepath.Join(t.TempDir(), "txparser.toml")
+	data := fmt.Sprintf("rpc_url = %q\nstorage = \"file\"\ndsn = %q\nsubscriptions = [%q]\n", node.URL, t.TempDir(), address)
+	err := os.WriteFile(path, []byte(data), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act
+	out, err := runCLI(t, "status", "-config", path, "-format", "json")
+	_, overrideErr := runCLI(t, "status", "-config", path, "-rpc-url", "http://127.0.0.1:1")
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	var report statusReport
+	err = json.Unmarshal([]byte(out), &report)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	want := statusReport{LastParsedBlock: 0, Head: 9, Lag: 9, Subscriptions: []string{address}}
+	if fmt.Sprint(report) != fmt.Sprint(want) {
+		t.Errorf("status should be %+v, but is %+v", want, report)
+	}
+	if overrideErr == nil {
+		t.Error("status should fail with the node of the flag")
+	}
+}
+
+func Test_CLI_Usage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "no command", args: nil},
+		{name: "unknown command", args: []string{"unknown"}},
+		{name: "unknown flag", args: []string{"status", "-unknown"}},
+		{name: "missing addresses", args: []string{"watch"}},
+		{name: "missing from block", args: []string{"backfill"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			_, err := runCLI(t, test.args...)
+
+			// Assert
+			if !errors.Is(err, errUsage) {
+				t.Errorf("error should be %v, but is %v", errUsage, err)
+			}
+		})
+	}
+}
+
+func Test_CLI_InvalidAddress(t *testing.T) {
+	// Act
+	_, err := runCLI(t, "backfill", "-from", "1", "0x123")
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "invalid address") {
+		t.Errorf("error should be about the invalid address, but is %v", err)
+	}
+}
+
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	c := &cli{stdout: &stdout, stderr: &stderr}
+	err := c.run(context.Background(), args)
+
+	return stdout.String(), err
+}
+
+// newTestNode serves eth_blockNumber and eth_getBlockByNumber of a fixed chain.
+func newTestNode(t *testing.T, head int, blocks map[int][]txparser.Transaction) *httptest.Server {
+	t.Helper()
+
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var call txparser.JSONRPCCall
+		err := json.NewDecoder(r.Body).Decode(&call)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		response := txparser.JSONRPCResponse{Jsonrpc: "2.0", ID: call.ID}
+		switch call.Method {
+		case "eth_blockNumber":
+			response.Result = fmt.Sprintf("0x%x", head)
+		case "eth_getBlockByNumber":
+			var number int
+			_, _ = fmt.Sscanf(call.Params[0].(string), "0x%x", &number)
+			transactions := blocks[number]
+			if transactions == nil {
+				transactions = []txparser.Transaction{}
+			}
+			response.Result = txparser.Block{
+				Number:       fmt.Sprintf("0x%x", number),
+				Hash:         fmt.Sprintf("0xb%d", number),
+				Transactions: transactions,
+			}
+		default:
+			response.Error = &txparser.JSONRPCError{Code: txparser.JSONRPCMethodNotFound, Message: "method not found"}
+		}
+
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(node.Close)
+
+	return node
+}
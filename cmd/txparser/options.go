@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"txparser/config"
+)
+
+// options are flags shared by every command, they override the config file.
+type options struct {
+	fs         *flag.FlagSet
+	configPath string
+	logLevel   slog.Level
+	recordPath string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	defaults := config.Default()
+
+	o.fs = fs
+	fs.StringVar(&o.configPath, "config", "", "config file, .json or .toml")
+	fs.String("rpc-url", defaults.RPCURL, "Ethereum JSON-RPC endpoint")
+	fs.String("storage", defaults.Storage, "storage: memory, file, sqlite or postgres")
+	fs.String("dsn", defaults.DSN, "directory of the file storage or data source name of the SQL one")
+	fs.TextVar(&o.logLevel, "log-level", slog.LevelWarn, "level of logs written to stderr: debug, info, warn or error")
+	fs.StringVar(&o.recordPath, "record", "", "file to append JSON-RPC calls of the node to, to replay them in tests")
+}
+
+// registerWorker registers flags of commands running the worker.
+func (o *options) registerWorker(fs *flag.FlagSet) {
+	fs.Duration("poll-period", time.Duration(config.Default().PollPeriod), "period of polling the node for new blocks")
+}
+
+// config returns the config file overridden by environment variables and set flags.
+func (o *options) config() (*config.Config, error) {
+	c := config.Default()
+	if o.configPath != "" {
+		err := c.ReadFile(o.configPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := c.ApplyEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	o.fs.Visit(func(f *flag.Flag) {
+		value := f.Value.(flag.Getter).Get()
+		switch f.Name {
+		case "rpc-url":
+			c.RPCURL = value.(string)
+		case "storage":
+			c.Storage = value.(string)
+		case "dsn":
+			c.DSN = value.(string)
+		case "poll-period":
+			c.PollPeriod = config.Duration(value.(time.Duration))
+		case "addr":
+			c.Addr = value.(string)
+		case "api-key":
+			c.APIKey = value.(string)
+		}
+	})
+
+	return c, c.Validate()
+}
+
+// open builds the service of the config logging to logOutput.
+func (o *options) open(ctx context.Context, logOutput io.Writer) (*config.Config, *config.Service, error) {
+	c, err := o.config()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	logger := slog.New(slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: o.logLevel}))
+	buildOptions := []config.Option{config.WithLogger(logger)}
+	if o.recordPath != "" {
+		buildOptions = append(buildOptions, config.WithRPCRecording(o.recordPath))
+	}
+	s, err := config.Build(ctx, c, buildOptions...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return c, s, nil
+}
+
+// close closes the service reporting errors, commands defer it.
+func (c *cli) close(s *config.Service) {
+	err := s.Close()
+	if err != nil {
+		fmt.Fprintln(c.stderr, "txparser: close storages:", err)
+	}
+}
+
+// parseFlags sets flags from TXPARSER_<FLAG> environment variables, such as TXPARSER_RPC_URL
+// for -rpc-url, then parses the arguments overriding them.
+// Errors and help are written to the output of the flag set.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	var err error
+	fs.VisitAll(func(f *flag.Flag) {
+		value, ok := os.LookupEnv(envName(f.Name))
+		if !ok || err != nil {
+			return
+		}
+
+		err = fs.Set(f.Name, value)
+		if err != nil {
+			err = fmt.Errorf("invalid %s %q: %w", envName(f.Name), value, err)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = fs.Parse(args)
+	if err != nil {
+		return errUsage
+	}
+
+	return nil
+}
+
+func envName(flagName string) string {
+	return "TXPARSER_" + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"txparser"
+)
+
+var addressRegexp = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// parseAddresses validates addresses and converts them to the lower case used by nodes.
+func parseAddresses(args []string) ([]string, error) {
+	addresses := make([]string, 0, len(args))
+	for _, arg := range args {
+		for _, address := range strings.Split(arg, ",") {
+			if !addressRegexp.MatchString(address) {
+				return nil, fmt.Errorf("invalid address %q, expected 0x followed by 40 hex digits", address)
+			}
+			addresses = append(addresses, strings.ToLower(address))
+		}
+	}
+
+	return addresses, nil
+}
+
+// addressTransaction is a line of JSON output.
+type addressTransaction struct {
+	Address string `json:"address"`
+	txparser.Transaction
+}
+
+// transactionWriter writes transactions of addresses as tab separated text, JSON lines or CSV.
+type transactionWriter struct {
+	format string
+	w      io.Writer
+	csv    *csv.Writer
+}
+
+var csvHeader = []string{"address", "blockNumber", "blockHash", "hash", "transactionIndex", "from", "to", "value"}
+
+func newTransactionWriter(w io.Writer, format string) (*transactionWriter, error) {
+	tw := &transactionWriter{format: format, w: w}
+
+	switch format {
+	case "text", "json":
+	case "csv":
+		tw.csv = csv.NewWriter(w)
+		err := tw.csv.Write(csvHeader)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown format %q, expected text, json or csv", format)
+	}
+
+	return tw, nil
+}
+
+func (tw *transactionWriter) Write(address string, tx txparser.Transaction) error {
+	switch tw.format {
+	case "json":
+		return json.NewEncoder(tw.w).Encode(addressTransaction{Address: address, Transaction: tx})
+	case "csv":
+		return tw.csv.Write([]string{
+			address, tx.BlockNumber, tx.BlockHash, tx.Hash, tx.TransactionIndex, tx.From, tx.To, tx.Value,
+		})
+	default:
+		_, err := fmt.Fprintf(tw.w, "%s\t%s\t%s\t%s -> %s\t%s\n",
+			address, tx.BlockNumber, tx.Hash, tx.From, tx.To, tx.Value)
+		return err
+	}
+}
+
+// Flush writes buffered CSV records, call it after every batch of transactions.
+func (tw *transactionWriter) Flush() error {
+	if tw.csv == nil {
+		return nil
+	}
+
+	tw.csv.Flush()
+
+	return tw.csv.Error()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+
+	"txparser"
+	"txparser/config"
+	"txparser/httpapi"
+)
+
+func (c *cli) serve(ctx context.Context, args []string) error {
+	var o options
+	fs := c.flagSet("serve", "")
+	o.register(fs)
+	o.registerWorker(fs)
+	fs.String("addr", config.Default().Addr, "address the HTTP API listens on")
+	fs.String("api-key", "", "API key required on every request, the API is open if empty")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	cfg, s, err := o.open(ctx, c.stderr)
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+
+	opts := []httpapi.Option{
+		httpapi.WithAddr(cfg.Addr),
+		httpapi.WithMetrics(s.Metrics),
+		httpapi.WithLogger(s.Logger),
+	}
+	if cfg.APIKey != "" {
+		apiKeys := txparser.NewInmemoryAPIKeyStorage()
+		err = apiKeys.PutAPIKey(ctx, txparser.HashAPIKey(cfg.APIKey), txparser.Tenant{ID: "default"})
+		if err != nil {
+			return err
+		}
+		opts = append(opts, httpapi.WithAuth(apiKeys, s.TenantSubscriptions))
+	}
+
+	period := s.PollPeriod
+
+	// Applies changes of the config file and the environment on SIGHUP, flags keep overriding them
+	reloader := &config.Reloader{
+		Service: s,
+		Path:    o.configPath,
+		Load:    o.config,
+		OnReload: func(err error) {
+			if err != nil {
+				s.Logger.Error("reload config", "error", err)
+				return
+			}
+			s.Logger.Info("config reloaded")
+		},
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go reloader.Run(ctx)
+
+	return httpapi.NewServer(s.Parser, opts...).Run(ctx, period)
+}
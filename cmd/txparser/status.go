@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+)
+
+// statusReport is the output of the status command.
+type statusReport struct {
+	LastParsedBlock int      `json:"lastParsedBlock"`
+	Head            int      `json:"head"`
+	Lag             int      `json:"lag"`
+	Subscriptions   []string `json:"subscriptions"`
+}
+
+func (c *cli) status(ctx context.Context, args []string) error {
+	var (
+		o      options
+		format string
+	)
+	fs := c.flagSet("status", "")
+	o.register(fs)
+	fs.StringVar(&format, "format", "text", "output format: text or json")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 0 || (format != "text" && format != "json") {
+		fs.Usage()
+		return errUsage
+	}
+
+	_, s, err := o.open(ctx, c.stderr)
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+
+	head, err := s.Client.CurrentBlockNumber(ctx)
+	if err != nil {
+		return fmt.Errorf("get current block: %w", err)
+	}
+
+	subscriptions, err := s.Subscriptions.GetAddresses(ctx)
+	if err != nil {
+		return err
+	}
+
+	lastParsedBlock, err := s.Blocks.GetBlockID(ctx)
+	if err != nil {
+		return err
+	}
+
+	report := statusReport{
+		LastParsedBlock: lastParsedBlock,
+		Head:            head,
+		Subscriptions:   subscriptions,
+	}
+	report.Lag = report.Head - report.LastParsedBlock
+	if report.Subscriptions == nil {
+		report.Subscriptions = []string{}
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(c.stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "last parsed block\t%d\n", report.LastParsedBlock)
+	fmt.Fprintf(w, "head\t%d\n", report.Head)
+	fmt.Fprintf(w, "lag\t%d\n", report.Lag)
+	fmt.Fprintf(w, "subscriptions\t%d\n", len(report.Subscriptions))
+	for _, address := range report.Subscriptions {
+		fmt.Fprintf(w, "\t%s\n", address)
+	}
+
+	return w.Flush()
+}
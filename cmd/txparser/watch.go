@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"txparser"
+)
+
+// watchBuffer is the number of events watch may fall behind the parser.
+const watchBuffer = 1024
+
+func (c *cli) watch(ctx context.Context, args []string) error {
+	var (
+		o      options
+		format string
+	)
+	fs := c.flagSet("watch", "<address>...")
+	o.register(fs)
+	o.registerWorker(fs)
+	fs.StringVar(&format, "format", "text", "output format: text, json or csv")
+	err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	addresses, err := parseAddresses(fs.Args())
+	if err != nil {
+		return err
+	}
+	out, err := newTransactionWriter(c.stdout, format)
+	if err != nil {
+		return err
+	}
+
+	_, s, err := o.open(ctx, c.stderr)
+	if err != nil {
+		return err
+	}
+	defer c.close(s)
+
+	for _, address := range addresses {
+		err = s.Subscriptions.PutAddress(ctx, address)
+		if err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	events, err := s.Parser.Stream(ctx, addresses, "", watchBuffer)
+	if err != nil {
+		return err
+	}
+
+	workerDone := make(chan error, 1)
+	go func() {
+		workerDone <- s.Parser.RunWorker(ctx, s.PollPeriod)
+	}()
+
+	for {
+		select {
+		case err := <-workerDone:
+			return err
+		case event, ok := <-events:
+			if !ok {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return errors.New("output is too slow, watch fell behind the parser")
+			}
+
+			err = writeEvent(out, event)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeEvent(out *transactionWriter, event txparser.Event) error {
+	if event.Transaction == nil {
+		return nil
+	}
+
+	err := out.Write(event.Address, *event.Transaction)
+	if err != nil {
+		return err
+	}
+
+	return out.Flush()
+}
@@ -0,0 +1,231 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"txparser"
+)
+
+// Service is the parser wired to its client and storages by Build.
+type Service struct {
+	Parser        *txparser.TXParser
+	Client        txparser.Client
+	Blocks        txparser.BlockStorage
+	Transactions  txparser.TransactionStorage
+	Subscriptions txparser.SubscriptionsStorage
+
+	// Addresses subscribed by tenants of the HTTP API, kept in the same storage as the rest
+	TenantSubscriptions txparser.TenantSubscriptionsStorage
+
+	// Metrics of the parser, the client and the storages
+	Metrics *txparser.Metrics
+
+	// Logger set by WithLogger, nil if not set
+	Logger *slog.Logger
+
+	PollPeriod time.Duration
+
+	// Records calls of the client if WithRPCRecording is set
+	recorder *txparser.Recorder
+
+	// Guards the applied config and fields changed by Apply
+	mu     sync.Mutex
+	config Config
+
+	closers []func() error
+}
+
+type buildOptions struct {
+	logger        *slog.Logger
+	parserOptions []txparser.Option
+	recordPath    string
+}
+
+type Option func(*buildOptions)
+
+// WithLogger sets the logger of the parser, the client and the storages, records are dropped by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *buildOptions) {
+		o.logger = logger
+	}
+}
+
+// WithParserOptions appends options of the parser, they are applied after ones of the config.
+func WithParserOptions(opts ...txparser.Option) Option {
+	return func(o *buildOptions) {
+		o.parserOptions = append(o.parserOptions, opts...)
+	}
+}
+
+// WithRPCRecording appends calls of the client to the file for txparser.NewReplayClient.
+func WithRPCRecording(path string) Option {
+	return func(o *buildOptions) {
+		o.recordPath = path
+	}
+}
+
+// Build opens the storages, migrating SQL ones, subscribes the configured addresses and
+// creates the parser with the start policy of the config. Addresses subscribed by a config
+// before and missing in this one are unsubscribed, as Apply does.
+// The parser, the client and the storages are instrumented by the metrics of the service.
+func Build(ctx context.Context, c *Config, opts ...Option) (*Service, error) {
+	err := c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	var o buildOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	addresses, err := c.Addresses()
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := txparser.NewMetrics()
+	s := &Service{
+		Metrics:    metrics,
+		PollPeriod: time.Duration(c.PollPeriod),
+		config:     *c,
+		Logger:     o.logger,
+	}
+	if o.recordPath != "" {
+		file, err := os.OpenFile(o.recordPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, err
+		}
+		s.recorder = txparser.NewRecorder(file)
+		s.closers = append(s.closers, file.Close)
+	}
+	s.Client = newClient(c, metrics, o.logger, s.recorder)
+
+	switch c.Storage {
+	case StorageFile:
+		err = s.openFile(c.DSN)
+	case StorageSQLite, StoragePostgres:
+		err = s.openSQL(ctx, txparser.SQLDialect(c.Storage), c.DSN)
+	default:
+		s.Blocks = txparser.NewInmemoryBlockStorage()
+		s.Transactions = txparser.NewInmemoryTransactionsStorage()
+		s.Subscriptions = txparser.NewInmemorySubscriptionsStorage()
+		s.TenantSubscriptions = txparser.NewInmemoryTenantSubscriptionsStorage()
+	}
+	if err != nil {
+		return nil, errors.Join(err, s.Close())
+	}
+	s.Blocks = metrics.InstrumentBlockStorage(s.Blocks)
+	s.Transactions = metrics.InstrumentTransactionStorage(s.Transactions)
+	s.Subscriptions = metrics.InstrumentSubscriptionsStorage(s.Subscriptions)
+
+	err = s.applySubscriptions(ctx, addresses)
+	if err != nil {
+		return nil, errors.Join(err, s.Close())
+	}
+
+	parserOptions := append([]txparser.Option{
+		txparser.WithStartPolicy(c.StartPolicy()),
+		txparser.WithMetrics(metrics),
+		txparser.WithLogger(o.logger),
+	}, o.parserOptions...)
+	s.Parser = txparser.NewTXParser(s.Blocks, s.Transactions, s.Subscriptions, s.Client, parserOptions...)
+
+	return s, nil
+}
+
+func newClient(c *Config, metrics *txparser.Metrics, logger *slog.Logger, recorder *txparser.Recorder) txparser.Client {
+	client := txparser.NewJSONRPCClient(
+		&http.Client{Timeout: time.Duration(c.RPCTimeout)},
+		c.RPCURL,
+		txparser.WithClientMetrics(metrics),
+		txparser.WithClientLogger(logger),
+	)
+	if recorder != nil {
+		return recorder.Record(client)
+	}
+
+	return client
+}
+
+func (s *Service) openFile(dir string) error {
+	blocks, err := txparser.NewFileBlockStorage(dir, txparser.WithFileLogger(s.Logger))
+	if err != nil {
+		return err
+	}
+	s.Blocks = blocks
+	s.closers = append(s.closers, blocks.Close)
+
+	transactions, err := txparser.NewFileTransactionsStorage(dir, txparser.WithFileLogger(s.Logger))
+	if err != nil {
+		return err
+	}
+	s.Transactions = transactions
+	s.closers = append(s.closers, transactions.Close)
+
+	subscriptions, err := txparser.NewFileSubscriptionsStorage(dir, txparser.WithFileLogger(s.Logger))
+	if err != nil {
+		return err
+	}
+	s.Subscriptions = subscriptions
+	s.closers = append(s.closers, subscriptions.Close)
+
+	tenantSubscriptions, err := txparser.NewFileTenantSubscriptionsStorage(dir, txparser.WithFileLogger(s.Logger))
+	if err != nil {
+		return err
+	}
+	s.TenantSubscriptions = tenantSubscriptions
+	s.closers = append(s.closers, tenantSubscriptions.Close)
+
+	return nil
+}
+
+func (s *Service) openSQL(ctx context.Context, dialect txparser.SQLDialect, dsn string) error {
+	driver := "postgres"
+	if dialect == txparser.DialectSQLite {
+		driver = "sqlite3"
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return err
+	}
+	s.closers = append(s.closers, db.Close)
+	if dialect == txparser.DialectSQLite {
+		// SQLite allows a single writer
+		db.SetMaxOpenConns(1)
+	}
+
+	err = txparser.MigrateSQL(ctx, db, dialect)
+	if err != nil {
+		return err
+	}
+
+	s.Blocks = txparser.NewSQLBlockStorage(db, dialect, txparser.WithSQLLogger(s.Logger))
+	s.Transactions = txparser.NewSQLTransactionsStorage(db, dialect, txparser.WithSQLLogger(s.Logger))
+	s.Subscriptions = txparser.NewSQLSubscriptionsStorage(db, dialect, txparser.WithSQLLogger(s.Logger))
+	s.TenantSubscriptions = txparser.NewSQLTenantSubscriptionsStorage(db, dialect, txparser.WithSQLLogger(s.Logger))
+
+	return nil
+}
+
+// Close closes the storages in reverse order of opening.
+func (s *Service) Close() error {
+	var errs []error
+	for i := len(s.closers) - 1; i >= 0; i-- {
+		errs = append(errs, s.closers[i]())
+	}
+	s.closers = nil
+
+	return errors.Join(errs...)
+}
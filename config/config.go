@@ -0,0 +1,331 @@
+// Package config loads the configuration of the parser service from JSON or TOML files
+// and environment variables and builds the wired parser from it.
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"txparser"
+)
+
+var ErrInvalidConfig = errors.New("invalid config")
+
+// EnvPrefix prefixes environment variables overriding config keys, such as TXPARSER_START_MODE for start.mode.
+const EnvPrefix = "TXPARSER_"
+
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatTOML Format = "toml"
+)
+
+const (
+	StorageMemory   = "memory"
+	StorageFile     = "file"
+	StorageSQLite   = "sqlite"
+	StoragePostgres = "postgres"
+)
+
+const (
+	StartResume     = "resume"
+	StartHead       = "head"
+	StartBlock      = "block"
+	StartBlocksBack = "blocks_back"
+)
+
+var addressRegexp = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+// Config is the configuration of the parser service. Keys of files and
+// suffixes of environment variables are the JSON names of fields joined by '_'.
+type Config struct {
+	RPCURL     string   `json:"rpc_url"`
+	RPCTimeout Duration `json:"rpc_timeout"`
+	PollPeriod Duration `json:"poll_period"`
+
+	// Storage is memory, file, sqlite or postgres. DSN is the directory of the file storage
+	// or the data source name of SQL ones.
+	Storage string `json:"storage"`
+	DSN     string `json:"dsn"`
+
+	Start Start `json:"start"`
+
+	// Subscriptions and addresses of SubscriptionsFile, one per line with # comments,
+	// are subscribed when the service is built, see Addresses.
+	Subscriptions     []string `json:"subscriptions"`
+	SubscriptionsFile string   `json:"subscriptions_file"`
+
+	// Addr and APIKey configure the HTTP API, it is open if APIKey is empty.
+	Addr   string `json:"addr"`
+	APIKey string `json:"api_key"`
+}
+
+// Start configures txparser.StartPolicy, Mode is resume, head, block or blocks_back.
+type Start struct {
+	Mode       string `json:"mode"`
+	Block      int    `json:"block"`
+	Blocks     int    `json:"blocks"`
+	MaxLag     int    `json:"max_lag"`
+	ConfirmLag bool   `json:"confirm_lag"`
+}
+
+// Duration is a time.Duration written as "1s" in config files.
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Default returns the configuration used for keys missing in files and the environment.
+func Default() *Config {
+	return &Config{
+		RPCURL:     "https://cloudflare-eth.com",
+		RPCTimeout: Duration(30 * time.Second),
+		PollPeriod: Duration(time.Second),
+		Storage:    StorageMemory,
+		Start: Start{
+			Mode:   StartResume,
+			MaxLag: txparser.DefaultMaxStartLag,
+		},
+		Addr: ":8080",
+	}
+}
+
+// Load reads the file, if the path is not empty, overrides it by environment variables
+// and validates the result.
+func Load(path string) (*Config, error) {
+	c := Default()
+
+	if path != "" {
+		err := c.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err := c.ApplyEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// ReadFile overrides the configuration by keys of the file, the format is defined by its extension.
+func (c *Config) ReadFile(path string) error {
+	format, err := formatOf(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	err = c.decode(data, format)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, path, err)
+	}
+
+	return nil
+}
+
+// ApplyEnv overrides the configuration by environment variables, see EnvName. Arrays are comma separated.
+func (c *Config) ApplyEnv() error {
+	err := c.applyEnv(os.LookupEnv)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	return nil
+}
+
+// Parse decodes and validates the configuration, environment variables are ignored.
+func Parse(data []byte, format Format) (*Config, error) {
+	c := Default()
+
+	err := c.decode(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	err = c.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func formatOf(path string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return FormatJSON, nil
+	case ".toml":
+		return FormatTOML, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported config file extension %q, expected .json or .toml", ErrInvalidConfig, ext)
+	}
+}
+
+func (c *Config) decode(data []byte, format Format) error {
+	var values map[string]any
+
+	switch format {
+	case FormatJSON:
+		decoder := json.NewDecoder(strings.NewReader(string(data)))
+		decoder.UseNumber()
+		err := decoder.Decode(&values)
+		if err != nil {
+			return err
+		}
+	case FormatTOML:
+		var err error
+		values, err = parseTOML(string(data))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %q", format)
+	}
+
+	return decodeStruct("", values, c)
+}
+
+// Validate returns ErrInvalidConfig joined with an error of every invalid key.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+	}
+
+	if u, err := url.Parse(c.RPCURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalid("rpc_url", "invalid URL %q, expected http or https one", c.RPCURL)
+	}
+	if c.RPCTimeout <= 0 {
+		invalid("rpc_timeout", "should be positive, but is %s", time.Duration(c.RPCTimeout))
+	}
+	if c.PollPeriod <= 0 {
+		invalid("poll_period", "should be positive, but is %s", time.Duration(c.PollPeriod))
+	}
+
+	switch c.Storage {
+	case StorageMemory:
+	case StorageFile, StorageSQLite, StoragePostgres:
+		if c.DSN == "" {
+			invalid("dsn", "is required by %s storage", c.Storage)
+		}
+	default:
+		invalid("storage", "unknown storage %q, expected memory, file, sqlite or postgres", c.Storage)
+	}
+
+	switch c.Start.Mode {
+	case StartResume, StartHead:
+	case StartBlock:
+		if c.Start.Block <= 0 {
+			invalid("start.block", "should be positive in block mode, but is %d", c.Start.Block)
+		}
+	case StartBlocksBack:
+		if c.Start.Blocks < 0 {
+			invalid("start.blocks", "should not be negative, but is %d", c.Start.Blocks)
+		}
+	default:
+		invalid("start.mode", "unknown mode %q, expected resume, head, block or blocks_back", c.Start.Mode)
+	}
+	if c.Start.MaxLag < 0 {
+		invalid("start.max_lag", "should not be negative, but is %d", c.Start.MaxLag)
+	}
+
+	for i, address := range c.Subscriptions {
+		if !addressRegexp.MatchString(address) {
+			invalid(fmt.Sprintf("subscriptions[%d]", i), "invalid address %q, expected 0x followed by 40 hex digits", address)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(errs...))
+}
+
+// Addresses returns lowercased addresses of Subscriptions followed by ones of SubscriptionsFile
+// without duplicates.
+func (c *Config) Addresses() ([]string, error) {
+	addresses := make([]string, 0, len(c.Subscriptions))
+	seen := make(map[string]bool)
+	add := func(address string) {
+		address = strings.ToLower(address)
+		if !seen[address] {
+			seen[address] = true
+			addresses = append(addresses, address)
+		}
+	}
+
+	for _, address := range c.Subscriptions {
+		add(address)
+	}
+
+	if c.SubscriptionsFile == "" {
+		return addresses, nil
+	}
+
+	data, err := os.ReadFile(c.SubscriptionsFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: subscriptions_file: %w", ErrInvalidConfig, err)
+	}
+
+	for i, line := range strings.Split(string(data), "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		address := strings.TrimSpace(line)
+		if address == "" {
+			continue
+		}
+		if !addressRegexp.MatchString(address) {
+			return nil, fmt.Errorf(
+				"%w: subscriptions_file: %s:%d: invalid address %q, expected 0x followed by 40 hex digits",
+				ErrInvalidConfig, c.SubscriptionsFile, i+1, address,
+			)
+		}
+		add(address)
+	}
+
+	return addresses, nil
+}
+
+// StartPolicy returns the start policy of the parser.
+func (c *Config) StartPolicy() txparser.StartPolicy {
+	policy := txparser.StartPolicy{
+		Block:      c.Start.Block,
+		Blocks:     c.Start.Blocks,
+		MaxLag:     c.Start.MaxLag,
+		ConfirmLag: c.Start.ConfirmLag,
+	}
+
+	switch c.Start.Mode {
+	case StartHead:
+		policy.Mode = txparser.StartModeHead
+	case StartBlock:
+		policy.Mode = txparser.StartModeBlock
+	case StartBlocksBack:
+		policy.Mode = txparser.StartModeBlocksBack
+	default:
+		policy.Mode = txparser.StartModeResume
+	}
+
+	return policy
+}
@@ -0,0 +1,405 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/config"
+	"txparser/txparsertest"
+)
+
+const exampleTOML = `
+# Parser service
+rpc_url = "https://node.example.com/rpc" # trailing comment
+poll_period = "5s"
+storage = 'sqlite'
+dsn = "/var/lib/txparser/txparser.db"
+subscriptions = [
+    "0x0d1d4e623D10F9FBA5Db95830F7d3839406C6AF2",
+    "0x00000000000000000000000000000000000000aa", # exchange
+]
+
+[start]
+mode = "blocks_back"
+blocks = 1_000
+confirm_lag = true
+`
+
+const exampleJSON = `{
+	"rpc_url": "https://node.example.com/rpc",
+	"poll_period": "5s",
+	"storage": "sqlite",
+	"dsn": "/var/lib/txparser/txparser.db",
+	"subscriptions": [
+		"0x0d1d4e623D10F9FBA5Db95830F7d3839406C6AF2",
+		"0x00000000000000000000000000000000000000aa"
+	],
+	"start": {"mode": "blocks_back", "blocks": 1000, "confirm_lag": true}
+}`
+
+func exampleConfig() *config.Config {
+	want := config.Default()
+	want.RPCURL = "https://node.example.com/rpc"
+	want.PollPeriod = config.Duration(5 * time.Second)
+	want.Storage = config.StorageSQLite
+	want.DSN = "/var/lib/txparser/txparser.db"
+	want.Subscriptions = []string{
+		"0x0d1d4e623D10F9FBA5Db95830F7d3839406C6AF2",
+		"0x00000000000000000000000000000000000000aa",
+	}
+	want.Start.Mode = config.StartBlocksBack
+	want.Start.Blocks = 1000
+	want.Start.ConfirmLag = true
+
+	return want
+}
+
+func Test_Config_Parse(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		format config.Format
+	}{
+		{name: "toml", data: exampleTOML, format: config.FormatTOML},
+		{name: "json", data: exampleJSON, format: config.FormatJSON},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			got, err := config.Parse([]byte(test.data), test.format)
+
+			// Assert
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if !reflect.DeepEqual(got, exampleConfig()) {
+				t.Errorf("config should be %+v, but is %+v", exampleConfig(), got)
+			}
+		})
+	}
+}
+
+func Test_Config_ParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		format  config.Format
+		wantErr string
+	}{
+		{
+			name:    "unknown key",
+			data:    "[start]\nmod = \"head\"",
+			format:  config.FormatTOML,
+			wantErr: `unknown key "start.mod"`,
+		},
+		{
+			name:    "wrong type",
+			data:    `{"start": {"block": "10"}}`,
+			format:  config.FormatJSON,
+			wantErr: `start.block: expected an integer, got "10"`,
+		},
+		{
+			name:    "unquoted duration",
+			data:    "poll_period = 5s",
+			format:  config.FormatTOML,
+			wantErr: `line 1: poll_period: invalid value "5s"`,
+		},
+		{
+			name:    "invalid duration",
+			data:    `poll_period = "5 seconds"`,
+			format:  config.FormatTOML,
+			wantErr: `poll_period: invalid duration "5 seconds"`,
+		},
+		{
+			name:    "duplicate key",
+			data:    "storage = \"file\"\n\nstorage = \"memory\"",
+			format:  config.FormatTOML,
+			wantErr: `line 3: duplicate key "storage"`,
+		},
+		{
+			name:    "unterminated string",
+			data:    "dsn = \"data",
+			format:  config.FormatTOML,
+			wantErr: "line 1: dsn: unterminated string",
+		},
+		{
+			name:    "array item",
+			data:    "subscriptions = [\"0x1\", 2]",
+			format:  config.FormatTOML,
+			wantErr: "subscriptions[1]: expected a string, got number 2",
+		},
+		{
+			name:    "storage",
+			data:    `storage = "redis"`,
+			format:  config.FormatTOML,
+			wantErr: `storage: unknown storage "redis", expected memory, file, sqlite or postgres`,
+		},
+		{
+			name:    "dsn",
+			data:    `storage = "file"`,
+			format:  config.FormatTOML,
+			wantErr: "dsn: is required by file storage",
+		},
+		{
+			name:    "start block",
+			data:    "[start]\nmode = \"block\"",
+			format:  config.FormatTOML,
+			wantErr: "start.block: should be positive in block mode, but is 0",
+		},
+		{
+			name:    "subscription",
+			data:    `subscriptions = ["0x123"]`,
+			format:  config.FormatTOML,
+			wantErr: `subscriptions[0]: invalid address "0x123"`,
+		},
+		{
+			name:    "rpc url",
+			data:    `rpc_url = "node:8545"`,
+			format:  config.FormatTOML,
+			wantErr: `rpc_url: invalid URL "node:8545"`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			_, err := config.Parse([]byte(test.data), test.format)
+
+			// Assert
+			if !errors.Is(err, config.ErrInvalidConfig) {
+				t.Errorf("error should be %v, but is %v", config.ErrInvalidConfig, err)
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("error should contain %q, but is %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func Test_Config_ValidateReportsEveryKey(t *testing.T) {
+	// Arrange
+	c := config.Default()
+	c.PollPeriod = 0
+	c.Storage = "sqlite"
+	c.Start.MaxLag = -1
+
+	// Act
+	err := c.Validate()
+
+	// Assert
+	for _, key := range []string{"poll_period:", "dsn:", "start.max_lag:"} {
+		if err == nil || !strings.Contains(err.Error(), key) {
+			t.Errorf("error should report %s, but is %v", key, err)
+		}
+	}
+}
+
+func Test_Config_LoadEnvOverrides(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "txparser.toml")
+	err := os.WriteFile(path, []byte(exampleTOML), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TXPARSER_POLL_PERIOD", "10s")
+	t.Setenv("TXPARSER_START_MODE", "head")
+	t.Setenv("TXPARSER_SUBSCRIPTIONS", "0x00000000000000000000000000000000000000bb, ")
+
+	// Act
+	got, err := config.Load(path)
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	want := exampleConfig()
+	want.PollPeriod = config.Duration(10 * time.Second)
+	want.Start.Mode = config.StartHead
+	want.Subscriptions = []string{"0x00000000000000000000000000000000000000bb"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("config should be %+v, but is %+v", want, got)
+	}
+}
+
+func Test_Config_LoadErrors(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "txparser.yaml")
+	_ = os.WriteFile(yamlPath, []byte("storage: file"), 0o600)
+	t.Setenv("TXPARSER_START_MAX_LAG", "many")
+
+	// Act
+	_, extErr := config.Load(yamlPath)
+	_, envErr := config.Load("")
+
+	// Assert
+	if extErr == nil || !strings.Contains(extErr.Error(), `unsupported config file extension ".yaml"`) {
+		t.Errorf("error should be about the extension, but is %v", extErr)
+	}
+	if envErr == nil || !strings.Contains(envErr.Error(), `TXPARSER_START_MAX_LAG: invalid integer "many"`) {
+		t.Errorf("error should be about the variable, but is %v", envErr)
+	}
+}
+
+func Test_Config_StartPolicy(t *testing.T) {
+	// Arrange
+	c := exampleConfig()
+
+	// Act
+	policy := c.StartPolicy()
+
+	// Assert
+	want := txparser.StartBlocksBack(1000).Confirmed()
+	if policy != want {
+		t.Errorf("start policy should be %+v, but is %+v", want, policy)
+	}
+}
+
+func Test_Config_Build(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	c := exampleConfig()
+	c.Storage = config.StorageFile
+	c.DSN = t.TempDir()
+
+	// Act
+	s, err := config.Build(ctx, c)
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	addresses, err := s.Subscriptions.GetAddresses(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	wantAddresses := []string{
+		"0x00000000000000000000000000000000000000aa",
+		"0x0d1d4e623d10f9fba5db95830f7d3839406c6af2",
+	}
+	if !reflect.DeepEqual(addresses, wantAddresses) {
+		t.Errorf("subscriptions should be %v, but are %v", wantAddresses, addresses)
+	}
+	if s.Parser == nil || s.Client == nil || s.PollPeriod != 5*time.Second {
+		t.Errorf("service should be wired, but is %+v", s)
+	}
+	err = s.Close()
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func Test_Config_BuildTenantSubscriptions(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	c := exampleConfig()
+	c.Storage = config.StorageSQLite
+	c.DSN = filepath.Join(t.TempDir(), "txparser.db")
+	s, err := config.Build(ctx, c)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = s.TenantSubscriptions.PutTenantAddress(ctx, "default", "0x123", 0)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = s.Close()
+	if err != nil {
+		t.Error(err)
+	}
+
+	// Act
+	s, err = config.Build(ctx, c)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer s.Close()
+	exists, err := s.TenantSubscriptions.IsTenantAddressExists(ctx, "default", "0x123")
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+	}
+	if !exists {
+		t.Error("tenant subscription should be kept in the configured storage")
+	}
+}
+
+func Test_Config_BuildRPCRecording(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	node := txparsertest.NewNode()
+	defer node.Close()
+	node.Mine()
+	c := config.Default()
+	c.RPCURL = node.URL()
+	path := filepath.Join(t.TempDir(), "rpc.jsonl")
+	s, err := config.Build(ctx, c, config.WithRPCRecording(path))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Act
+	_, err = s.Client.CurrentBlockNumber(ctx)
+	if err != nil {
+		t.Error(err)
+	}
+	err = s.Close()
+	if err != nil {
+		t.Error(err)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer file.Close()
+	replay, err := txparser.NewReplayClient(file)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	head, err := replay.CurrentBlockNumber(ctx)
+
+	// Assert
+	if err != nil || head != 1 {
+		t.Errorf("recorded head should be %d, but is %d (%v)", 1, head, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("recording mode should be %v, but is %v", os.FileMode(0o600), info.Mode().Perm())
+	}
+}
+
+func Test_Config_BuildInvalid(t *testing.T) {
+	// Arrange
+	c := config.Default()
+	c.Storage = "redis"
+
+	// Act
+	_, err := config.Build(context.Background(), c)
+
+	// Assert
+	if !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("error should be %v, but is %v", config.ErrInvalidConfig, err)
+	}
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(Duration(0))
+
+// decodeStruct sets fields of the struct v points to by their JSON names,
+// errors name the key by its path, such as start.mode.
+func decodeStruct(path string, values map[string]any, v any) error {
+	rv := reflect.ValueOf(v).Elem()
+
+	// Sorted keys report the same error on every run
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		raw := values[key]
+		field, ok := fieldByKey(rv, key)
+		if !ok {
+			return fmt.Errorf("unknown key %q", joinKey(path, key))
+		}
+
+		err := decodeValue(joinKey(path, key), raw, field)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeValue(key string, raw any, field reflect.Value) error {
+	mismatch := func(want string) error {
+		return fmt.Errorf("%s: expected %s, got %s", key, want, describe(raw))
+	}
+
+	if field.Type() == durationType {
+		s, ok := raw.(string)
+		if !ok {
+			return mismatch(`a duration string, such as "1s"`)
+		}
+
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("%s: invalid duration %q", key, s)
+		}
+		field.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			return mismatch("a string")
+		}
+		field.SetString(s)
+	case reflect.Int:
+		n, ok := toInt(raw)
+		if !ok {
+			return mismatch("an integer")
+		}
+		field.SetInt(n)
+	case reflect.Bool:
+		b, ok := raw.(bool)
+		if !ok {
+			return mismatch("a boolean")
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		items, ok := raw.([]any)
+		if !ok {
+			return mismatch("an array of strings")
+		}
+
+		strs := make([]string, len(items))
+		for i, item := range items {
+			s, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("%s[%d]: expected a string, got %s", key, i, describe(item))
+			}
+			strs[i] = s
+		}
+		field.Set(reflect.ValueOf(strs))
+	case reflect.Struct:
+		values, ok := raw.(map[string]any)
+		if !ok {
+			return mismatch("a table")
+		}
+
+		return decodeStruct(key, values, field.Addr().Interface())
+	default:
+		return fmt.Errorf("%s: unsupported field type %s", key, field.Type())
+	}
+
+	return nil
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	return applyEnvStruct("", reflect.ValueOf(c).Elem(), lookup)
+}
+
+func applyEnvStruct(path string, rv reflect.Value, lookup func(string) (string, bool)) error {
+	for i := 0; i < rv.NumField(); i++ {
+		key := joinKey(path, jsonName(rv.Type().Field(i)))
+		field := rv.Field(i)
+
+		if field.Kind() == reflect.Struct {
+			err := applyEnvStruct(key, field, lookup)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := EnvName(key)
+		value, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		err := setFromString(field, value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// EnvName returns the environment variable overriding the key, such as TXPARSER_START_MODE for start.mode.
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func setFromString(field reflect.Value, value string) error {
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		field.SetInt(int64(d))
+
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		field.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		items := make([]string, 0)
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+
+	return nil
+}
+
+func fieldByKey(rv reflect.Value, key string) (reflect.Value, bool) {
+	for i := 0; i < rv.NumField(); i++ {
+		if jsonName(rv.Type().Field(i)) == key {
+			return rv.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+
+	return name
+}
+
+func toInt(raw any) (int64, bool) {
+	switch v := raw.(type) {
+	case int64:
+		return v, true
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func describe(raw any) string {
+	switch v := raw.(type) {
+	case string:
+		return strconv.Quote(v)
+	case int64, json.Number:
+		return fmt.Sprintf("number %v", v)
+	case bool:
+		return fmt.Sprintf("boolean %v", v)
+	case []any:
+		return "an array"
+	case map[string]any:
+		return "a table"
+	case nil:
+		return "null"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+
+	return path + "." + key
+}
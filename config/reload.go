@@ -0,0 +1,242 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+	"time"
+
+	"txparser"
+)
+
+var ErrRestartRequired = errors.New("config change requires a restart")
+
+// Apply applies the config to the running service: it subscribes added addresses,
+// unsubscribing ones removed from the config since the last apply, then switches the client
+// of the node and changes the poll period of the running worker. Only addresses subscribed
+// by the config are unsubscribed, ones subscribed otherwise, such as over the HTTP API, are kept.
+// Transactions of unsubscribed addresses are kept, the last parsed block is not touched.
+// On error the subscriptions are rolled back and the last applied config is kept.
+//
+// Storage, dsn, start, addr and api_key are applied only by Build, changing them
+// returns ErrRestartRequired and nothing is applied.
+func (s *Service) Apply(ctx context.Context, c *Config) error {
+	err := c.Validate()
+	if err != nil {
+		return err
+	}
+
+	addresses, err := c.Addresses()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if keys := restartKeys(&s.config, c); len(keys) > 0 {
+		return fmt.Errorf("%w: %s changed", ErrRestartRequired, strings.Join(keys, ", "))
+	}
+
+	err = s.applySubscriptions(ctx, addresses)
+	if err != nil {
+		return err
+	}
+
+	if c.RPCURL != s.config.RPCURL || c.RPCTimeout != s.config.RPCTimeout {
+		s.Client = newClient(c, s.Metrics, s.Logger, s.recorder)
+		s.Parser.SetClient(s.Client)
+	}
+
+	if c.PollPeriod != s.config.PollPeriod {
+		s.PollPeriod = time.Duration(c.PollPeriod)
+		s.Parser.SetPollPeriod(s.PollPeriod)
+	}
+
+	s.config = *c
+
+	return nil
+}
+
+// applySubscriptions subscribes the addresses on behalf of the config and unsubscribes managed
+// addresses missing in them, addresses subscribed otherwise, such as over the HTTP API, are kept.
+// The changes are undone on error.
+func (s *Service) applySubscriptions(ctx context.Context, addresses []string) error {
+	var added, deleted []string
+	rollback := func(err error) error {
+		ctx := context.WithoutCancel(ctx)
+		for _, address := range added {
+			err = errors.Join(err, s.Subscriptions.DeleteManagedAddress(ctx, address))
+		}
+		for _, address := range deleted {
+			err = errors.Join(err, s.Subscriptions.PutManagedAddress(ctx, address))
+		}
+
+		return err
+	}
+
+	kept := make(map[string]bool, len(addresses))
+	for _, address := range addresses {
+		kept[address] = true
+		exists, err := s.Subscriptions.IsAddressExists(ctx, address)
+		if err != nil {
+			return rollback(err)
+		}
+		if exists {
+			continue
+		}
+		err = s.Subscriptions.PutManagedAddress(ctx, address)
+		if err != nil {
+			return rollback(err)
+		}
+		added = append(added, address)
+	}
+
+	managed, err := s.Subscriptions.GetManagedAddresses(ctx)
+	if err != nil {
+		return rollback(err)
+	}
+	for _, address := range managed {
+		if kept[address] {
+			continue
+		}
+		err = s.Subscriptions.DeleteManagedAddress(ctx, address)
+		if err != nil {
+			return rollback(err)
+		}
+		deleted = append(deleted, address)
+	}
+
+	return nil
+}
+
+// restartKeys returns keys of the configs differing in ones applied only by Build.
+func restartKeys(applied, c *Config) []string {
+	var keys []string
+	if applied.Storage != c.Storage {
+		keys = append(keys, "storage")
+	}
+	if applied.DSN != c.DSN {
+		keys = append(keys, "dsn")
+	}
+	if !reflect.DeepEqual(applied.Start, c.Start) {
+		keys = append(keys, "start")
+	}
+	if applied.Addr != c.Addr {
+		keys = append(keys, "addr")
+	}
+	if applied.APIKey != c.APIKey {
+		keys = append(keys, "api_key")
+	}
+
+	return keys
+}
+
+// Reloader applies the config to the running service on SIGHUP and when the config file
+// or the subscriptions file changes.
+type Reloader struct {
+	Service *Service
+
+	// Path is the config file, Load reads it if set
+	Path string
+
+	// Load returns the new config, Load of Path by default
+	Load func() (*Config, error)
+
+	// Interval of checking modification times of files, a second by default
+	Interval time.Duration
+
+	// Clock makes the timer of checking files, txparser.SystemClock by default
+	Clock txparser.Clock
+
+	// OnReload is called after every reload with the error of Apply, nil if applied
+	OnReload func(err error)
+}
+
+// Run reloads the config until ctx is done. A failed reload keeps the last applied config.
+func (r *Reloader) Run(ctx context.Context) {
+	interval := r.Interval
+	if interval == 0 {
+		interval = time.Second
+	}
+
+	clock := r.Clock
+	if clock == nil {
+		clock = txparser.SystemClock()
+	}
+
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+
+	versions := r.versions()
+
+	timer := clock.NewTimer(interval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+		case <-timer.C():
+			timer.Reset(interval)
+			if r.versions() == versions {
+				continue
+			}
+		}
+
+		// Taken before loading, so changes made meanwhile trigger another reload
+		versions = r.versions()
+		err := r.reload(ctx)
+		if r.OnReload != nil {
+			r.OnReload(err)
+		}
+	}
+}
+
+func (r *Reloader) reload(ctx context.Context) error {
+	load := r.Load
+	if load == nil {
+		load = func() (*Config, error) {
+			return Load(r.Path)
+		}
+	}
+
+	c, err := load()
+	if err != nil {
+		return err
+	}
+
+	return r.Service.Apply(ctx, c)
+}
+
+// fileVersion identifies the content of a file without reading it, zero if it is missing.
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// versions returns versions of the config file and the applied subscriptions file.
+func (r *Reloader) versions() [2]fileVersion {
+	r.Service.mu.Lock()
+	subscriptionsFile := r.Service.config.SubscriptionsFile
+	r.Service.mu.Unlock()
+
+	var versions [2]fileVersion
+	for i, path := range []string{r.Path, subscriptionsFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			versions[i] = fileVersion{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+
+	return versions
+}
@@ -0,0 +1,340 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/config"
+	"txparser/txparsertest"
+)
+
+const (
+	addressA = "0x00000000000000000000000000000000000000aa"
+	addressB = "0x00000000000000000000000000000000000000bb"
+	addressC = "0x00000000000000000000000000000000000000cc"
+)
+
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func Test_Service_Apply(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	subscriptionsFile := filepath.Join(t.TempDir(), "subscriptions.txt")
+	writeFile(t, subscriptionsFile, addressA+"\n# exchange\n"+addressB+"\n")
+	c := config.Default()
+	c.SubscriptionsFile = subscriptionsFile
+	s, err := config.Build(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Subscriptions.PutAddress(ctx, addressC) // subscribed by the API
+	client := s.Client
+
+	updated := *c
+	updated.RPCURL = "https://node.example.com"
+	updated.PollPeriod = config.Duration(5 * time.Second)
+	updated.Subscriptions = []string{"0x00000000000000000000000000000000000000BB"}
+	updated.SubscriptionsFile = ""
+
+	// Act
+	err = s.Apply(ctx, &updated)
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	addresses, _ := s.Subscriptions.GetAddresses(ctx)
+	if !reflect.DeepEqual(addresses, []string{addressB, addressC}) {
+		t.Errorf("subscriptions should be %v, but are %v", []string{addressB, addressC}, addresses)
+	}
+	if s.Client == client {
+		t.Error("client should be replaced")
+	}
+	if s.PollPeriod != 5*time.Second {
+		t.Errorf("poll period should be %s, but is %s", 5*time.Second, s.PollPeriod)
+	}
+}
+
+func Test_Service_ApplyKeepsAPISubscriptions(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	c := config.Default()
+	c.Subscriptions = []string{addressA}
+	s, err := config.Build(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = s.Subscriptions.PutAddress(ctx, addressB) // subscribed by the API
+
+	added := *c
+	added.Subscriptions = []string{addressA, addressB}
+	removed := *c
+	removed.Subscriptions = nil
+
+	// Act
+	err = s.Apply(ctx, &added)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = s.Apply(ctx, &removed)
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	addresses, _ := s.Subscriptions.GetAddresses(ctx)
+	if !reflect.DeepEqual(addresses, []string{addressB}) {
+		t.Errorf("subscriptions should be %v, but are %v", []string{addressB}, addresses)
+	}
+}
+
+func Test_Service_BuildUnsubscribesRemovedAddresses(t *testing.T) {
+	for _, storage := range []string{config.StorageFile, config.StorageSQLite} {
+		t.Run(storage, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			c := config.Default()
+			c.Storage = storage
+			c.DSN = filepath.Join(t.TempDir(), "txparser")
+			c.Subscriptions = []string{addressA, addressB}
+			s, err := config.Build(ctx, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = s.Subscriptions.PutAddress(ctx, addressC) // subscribed by the API
+			_ = s.Close()
+
+			restarted := *c
+			restarted.Subscriptions = []string{addressA}
+
+			// Act
+			s, err = config.Build(ctx, &restarted)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			defer s.Close()
+			addresses, _ := s.Subscriptions.GetAddresses(ctx)
+
+			// Assert
+			if !reflect.DeepEqual(addresses, []string{addressA, addressC}) {
+				t.Errorf("subscriptions should be %v, but are %v", []string{addressA, addressC}, addresses)
+			}
+		})
+	}
+}
+
+func Test_Service_ApplyRollback(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	c := config.Default()
+	c.Subscriptions = []string{addressA}
+	s, err := config.Build(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptions := s.Subscriptions
+	s.Subscriptions = &failingSubscriptionsStorage{SubscriptionsStorage: subscriptions, address: addressC}
+	client := s.Client
+
+	updated := *c
+	updated.RPCURL = "https://node.example.com"
+	updated.PollPeriod = config.Duration(5 * time.Second)
+	updated.Subscriptions = []string{addressB, addressC}
+
+	// Act
+	err = s.Apply(ctx, &updated)
+	s.Subscriptions = subscriptions
+	retryErr := s.Apply(ctx, c)
+
+	// Assert
+	if !errors.Is(err, errPutAddress) {
+		t.Errorf("error should be %v, but is %v", errPutAddress, err)
+	}
+	if retryErr != nil {
+		t.Errorf("applying the last config again should succeed, but failed: %v", retryErr)
+	}
+	addresses, _ := s.Subscriptions.GetAddresses(ctx)
+	if !reflect.DeepEqual(addresses, []string{addressA}) {
+		t.Errorf("subscriptions should be %v, but are %v", []string{addressA}, addresses)
+	}
+	if s.Client != client {
+		t.Error("client should not be replaced")
+	}
+	if s.PollPeriod != time.Duration(c.PollPeriod) {
+		t.Errorf("poll period should be %s, but is %s", time.Duration(c.PollPeriod), s.PollPeriod)
+	}
+}
+
+func Test_Service_ApplyRestartRequired(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	c := config.Default()
+	s, err := config.Build(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated := *c
+	updated.Subscriptions = []string{addressA}
+	updated.Start.Mode = config.StartHead
+	updated.Addr = ":9090"
+
+	// Act
+	err = s.Apply(ctx, &updated)
+
+	// Assert
+	if !errors.Is(err, config.ErrRestartRequired) || err.Error() != "config change requires a restart: start, addr changed" {
+		t.Errorf("error should be %v about start and addr, but is %v", config.ErrRestartRequired, err)
+	}
+	addresses, _ := s.Subscriptions.GetAddresses(ctx)
+	if len(addresses) != 0 {
+		t.Errorf("addresses slice should have %d item(s), but has %d", 0, len(addresses))
+	}
+}
+
+func Test_Service_ApplyInvalidSubscriptionsFile(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	subscriptionsFile := filepath.Join(t.TempDir(), "subscriptions.txt")
+	writeFile(t, subscriptionsFile, addressA+"\n\n0x123\n")
+	s, err := config.Build(ctx, config.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated := config.Default()
+	updated.SubscriptionsFile = subscriptionsFile
+
+	// Act
+	err = s.Apply(ctx, updated)
+
+	// Assert
+	want := subscriptionsFile + `:3: invalid address "0x123"`
+	if !errors.Is(err, config.ErrInvalidConfig) || !strings.Contains(err.Error(), want) {
+		t.Errorf("error should be %v about %s, but is %v", config.ErrInvalidConfig, want, err)
+	}
+}
+
+func Test_Reloader(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "txparser.toml")
+	subscriptionsFile := filepath.Join(dir, "subscriptions.txt")
+	writeFile(t, path, `subscriptions_file = "`+filepath.ToSlash(subscriptionsFile)+`"`)
+	writeFile(t, subscriptionsFile, addressA)
+	c, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := config.Build(ctx, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := txparsertest.NewFakeClock(epoch)
+	reloads := make(chan error)
+	reloader := &config.Reloader{
+		Service:  s,
+		Path:     path,
+		Interval: time.Second,
+		Clock:    clock,
+		OnReload: func(err error) {
+			select {
+			case reloads <- err:
+			case <-ctx.Done():
+			}
+		},
+	}
+	go reloader.Run(ctx)
+	clock.BlockUntil(1) // Run took versions of files and handles SIGHUP
+
+	// Act
+	writeFile(t, subscriptionsFile, addressA+"\n"+addressB)
+	clock.Advance(time.Second)
+	fileErr := <-reloads
+	fileAddresses, _ := s.Subscriptions.GetAddresses(ctx)
+
+	t.Setenv("TXPARSER_SUBSCRIPTIONS", addressC)
+	hangupErr := sendHangup(t, reloads)
+	hangupAddresses, _ := s.Subscriptions.GetAddresses(ctx)
+
+	writeFile(t, subscriptionsFile, "0x123")
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	invalidErr := <-reloads
+	invalidAddresses, _ := s.Subscriptions.GetAddresses(ctx)
+
+	// Assert
+	for _, err := range []error{fileErr, hangupErr} {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if !reflect.DeepEqual(fileAddresses, []string{addressA, addressB}) {
+		t.Errorf("subscriptions should be %v, but are %v", []string{addressA, addressB}, fileAddresses)
+	}
+	want := []string{addressA, addressB, addressC}
+	if !reflect.DeepEqual(hangupAddresses, want) {
+		t.Errorf("subscriptions should be %v, but are %v", want, hangupAddresses)
+	}
+	if !errors.Is(invalidErr, config.ErrInvalidConfig) {
+		t.Errorf("error should be %v, but is %v", config.ErrInvalidConfig, invalidErr)
+	}
+	if !reflect.DeepEqual(invalidAddresses, want) {
+		t.Errorf("failed reload should keep subscriptions %v, but they are %v", want, invalidAddresses)
+	}
+}
+
+func sendHangup(t *testing.T, reloads <-chan error) error {
+	t.Helper()
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = process.Signal(syscall.SIGHUP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return <-reloads
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+
+	err := os.WriteFile(path, []byte(data), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+var errPutAddress = errors.New("put address failed")
+
+// failingSubscriptionsStorage fails to subscribe the address.
+type failingSubscriptionsStorage struct {
+	txparser.SubscriptionsStorage
+	address string
+}
+
+func (s *failingSubscriptionsStorage) PutManagedAddress(ctx context.Context, address string) error {
+	if address == s.address {
+		return errPutAddress
+	}
+
+	return s.SubscriptionsStorage.PutManagedAddress(ctx, address)
+}
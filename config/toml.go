@@ -0,0 +1,248 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML parses the subset of TOML used by config files into nested maps:
+// comments, [tables], dotted keys, basic and literal strings, integers, booleans
+// and arrays, which may span lines. Errors carry line numbers.
+func parseTOML(data string) (map[string]any, error) {
+	root := make(map[string]any)
+	table := root
+
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNumber := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || strings.HasPrefix(line, "[[") {
+				return nil, fmt.Errorf("line %d: invalid table header %q", lineNumber, line)
+			}
+
+			keys, err := parseKey(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+
+			table, err = subtable(root, keys, true)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+			}
+			continue
+		}
+
+		rawKey, rawValue, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", lineNumber, line)
+		}
+
+		keys, err := parseKey(rawKey)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+
+		// Arrays continue until brackets are balanced
+		rawValue = strings.TrimSpace(rawValue)
+		for strings.HasPrefix(rawValue, "[") && !isBalanced(rawValue) && i+1 < len(lines) {
+			i++
+			rawValue += " " + strings.TrimSpace(stripComment(lines[i]))
+		}
+
+		value, rest, err := parseValue(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNumber, strings.Join(keys, "."), err)
+		}
+		if strings.TrimSpace(rest) != "" {
+			return nil, fmt.Errorf("line %d: %s: unexpected %q after value", lineNumber, strings.Join(keys, "."), rest)
+		}
+
+		parent, err := subtable(table, keys[:len(keys)-1], false)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		key := keys[len(keys)-1]
+		if _, exists := parent[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %q", lineNumber, strings.Join(keys, "."))
+		}
+		parent[key] = value
+	}
+
+	return root, nil
+}
+
+// subtable returns the table at the keys creating missing ones.
+func subtable(table map[string]any, keys []string, header bool) (map[string]any, error) {
+	for i, key := range keys {
+		next, exists := table[key]
+		if !exists {
+			created := make(map[string]any)
+			table[key] = created
+			table = created
+			continue
+		}
+
+		nextTable, ok := next.(map[string]any)
+		if !ok || (header && i == len(keys)-1) {
+			return nil, fmt.Errorf("duplicate key %q", strings.Join(keys[:i+1], "."))
+		}
+		table = nextTable
+	}
+
+	return table, nil
+}
+
+func parseKey(raw string) ([]string, error) {
+	parts := strings.Split(strings.TrimSpace(raw), ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" || strings.TrimLeft(part, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_-") != "" {
+			return nil, fmt.Errorf("invalid key %q", strings.TrimSpace(raw))
+		}
+		parts[i] = part
+	}
+
+	return parts, nil
+}
+
+// parseValue parses the value at the start of s and returns the rest of s.
+func parseValue(s string) (any, string, error) {
+	s = strings.TrimLeft(s, " \t")
+
+	switch {
+	case s == "":
+		return nil, "", fmt.Errorf("missing value")
+	case s[0] == '"':
+		return parseBasicString(s)
+	case s[0] == '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	case s[0] == '[':
+		return parseArray(s)
+	}
+
+	end := strings.IndexAny(s, ",] \t")
+	if end < 0 {
+		end = len(s)
+	}
+	token, rest := s[:end], s[end:]
+
+	switch token {
+	case "true":
+		return true, rest, nil
+	case "false":
+		return false, rest, nil
+	}
+
+	n, err := strconv.ParseInt(strings.ReplaceAll(token, "_", ""), 0, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid value %q, expected a string, an integer, a boolean or an array", token)
+	}
+
+	return n, rest, nil
+}
+
+func parseBasicString(s string) (any, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), s[i+1:], nil
+		case '\\':
+			if i+1 == len(s) {
+				return nil, "", fmt.Errorf("unterminated string")
+			}
+			i++
+			switch s[i] {
+			case '"', '\\':
+				b.WriteByte(s[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				return nil, "", fmt.Errorf("unsupported escape sequence \\%c", s[i])
+			}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return nil, "", fmt.Errorf("unterminated string")
+}
+
+func parseArray(s string) (any, string, error) {
+	values := make([]any, 0)
+	rest := strings.TrimLeft(s[1:], " \t")
+
+	for {
+		if strings.HasPrefix(rest, "]") {
+			return values, rest[1:], nil
+		}
+
+		value, next, err := parseValue(rest)
+		if err != nil {
+			return nil, "", err
+		}
+		values = append(values, value)
+
+		rest = strings.TrimLeft(next, " \t")
+		switch {
+		case strings.HasPrefix(rest, ","):
+			rest = strings.TrimLeft(rest[1:], " \t")
+		case strings.HasPrefix(rest, "]"):
+		default:
+			return nil, "", fmt.Errorf("unterminated array")
+		}
+	}
+}
+
+// stripComment removes the comment outside of strings from the line.
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote == 0 && c == '#':
+			return line[:i]
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == '"' && c == '\\':
+			i++
+		case c == quote:
+			quote = 0
+		}
+	}
+
+	return line
+}
+
+// isBalanced reports whether every bracket outside of strings is closed.
+func isBalanced(s string) bool {
+	depth := 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote == 0 && (c == '"' || c == '\''):
+			quote = c
+		case quote == '"' && c == '\\':
+			i++
+		case c == quote:
+			quote = 0
+		case quote == 0 && c == '[':
+			depth++
+		case quote == 0 && c == ']':
+			depth--
+		}
+	}
+
+	return depth <= 0
+}
@@ -0,0 +1,76 @@
+package txparser
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrInvalidConsumer = errors.New("invalid consumer name")
+
+// Batch is a part of the address transactions fetched by a consumer.
+type Batch struct {
+	Transactions []Transaction `json:"transactions"`
+
+	// Cursor acknowledges the batch, it is empty if the batch is empty.
+	Cursor string `json:"cursor,omitempty"`
+}
+
+// Fetch returns up to limit transactions of the address after the cursor of the consumer.
+// The same transactions are returned until the consumer acknowledges them with Ack,
+// so every consumer reads the stream independently and nothing is deleted for others.
+func (p *TXParser) Fetch(ctx context.Context, consumer, address string, limit int) (*Batch, error) {
+	if consumer == "" {
+		return nil, ErrInvalidConsumer
+	}
+
+	cursor, err := p.cursorStorage.GetCursor(ctx, consumer, address)
+	if err != nil {
+		return nil, err
+	}
+
+	page, err := p.QueryTransactions(ctx, TransactionsQuery{
+		Address: address,
+		Cursor:  cursor,
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &Batch{Transactions: page.Transactions}
+	if len(page.Transactions) > 0 {
+		batch.Cursor = CursorOf(page.Transactions[len(page.Transactions)-1])
+	}
+
+	return batch, nil
+}
+
+// Ack moves the cursor of the consumer to the cursor of a fetched batch.
+// Acknowledging a cursor behind the current one is a no-op, so retried acks are safe.
+func (p *TXParser) Ack(ctx context.Context, consumer, address, cursor string) error {
+	if consumer == "" {
+		return ErrInvalidConsumer
+	}
+
+	position, err := decodeCursor(cursor)
+	if err != nil {
+		return err
+	}
+
+	p.ackMu.Lock()
+	defer p.ackMu.Unlock()
+
+	current, err := p.cursorStorage.GetCursor(ctx, consumer, address)
+	if err != nil {
+		return err
+	}
+
+	if current != "" {
+		currentPosition, err := decodeCursor(current)
+		if err == nil && !currentPosition.less(position) {
+			return nil
+		}
+	}
+
+	return p.cursorStorage.SaveCursor(ctx, consumer, address, cursor)
+}
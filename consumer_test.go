@@ -0,0 +1,96 @@
+package txparser_test
+
+import (
+	"context"
+	"testing"
+
+	"txparser"
+)
+
+func Test_Parser_FetchAndAck(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	txStorage := txparser.NewInmemoryTransactionsStorage()
+	_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{
+		{BlockNumber: "0x1", Hash: "0xa1", From: "0x123", To: "0x321"},
+		{BlockNumber: "0x2", Hash: "0xa2", From: "0x321", To: "0x123"},
+		{BlockNumber: "0x3", Hash: "0xa3", From: "0x123", To: "0x456"},
+	})
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txStorage,
+		txparser.NewInmemorySubscriptionsStorage(),
+		&headClient{},
+	)
+
+	// Act
+	billing, err := parser.Fetch(ctx, "billing", "0x123", 2)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	err = parser.Ack(ctx, "billing", "0x123", billing.Cursor)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	billingNext, _ := parser.Fetch(ctx, "billing", "0x123", 2)
+	notifier, _ := parser.Fetch(ctx, "notifier", "0x123", 10)
+	notifierRetry, _ := parser.Fetch(ctx, "notifier", "0x123", 10)
+
+	// Assert
+	if !areSlicesEqual([]string{"0xa1", "0xa2"}, hashesOf(billing.Transactions)) {
+		t.Errorf("first batch should be %v, but is %v", []string{"0xa1", "0xa2"}, hashesOf(billing.Transactions))
+	}
+	if !areSlicesEqual([]string{"0xa3"}, hashesOf(billingNext.Transactions)) {
+		t.Errorf("batch after ack should be %v, but is %v", []string{"0xa3"}, hashesOf(billingNext.Transactions))
+	}
+	if len(notifier.Transactions) != 3 || len(notifierRetry.Transactions) != 3 {
+		t.Error("unacknowledged transactions should be fetched again by another consumer")
+	}
+	transactions := parser.GetTransactions("0x123")
+	if len(transactions) != 3 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 3, len(transactions))
+	}
+}
+
+func Test_Parser_AckIsMonotonic(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	txStorage := txparser.NewInmemoryTransactionsStorage()
+	_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{
+		{BlockNumber: "0x1", Hash: "0xa1", From: "0x123", To: "0x321"},
+		{BlockNumber: "0x2", Hash: "0xa2", From: "0x321", To: "0x123"},
+	})
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txStorage,
+		txparser.NewInmemorySubscriptionsStorage(),
+		&headClient{},
+	)
+	first, _ := parser.Fetch(ctx, "billing", "0x123", 1)
+	_ = parser.Ack(ctx, "billing", "0x123", first.Cursor)
+	second, _ := parser.Fetch(ctx, "billing", "0x123", 1)
+	_ = parser.Ack(ctx, "billing", "0x123", second.Cursor)
+
+	// Act
+	err := parser.Ack(ctx, "billing", "0x123", first.Cursor)
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+	}
+	batch, _ := parser.Fetch(ctx, "billing", "0x123", 1)
+	if len(batch.Transactions) != 0 {
+		t.Errorf("stale ack should not move the cursor back, but got %v", hashesOf(batch.Transactions))
+	}
+}
+
+func hashesOf(transactions []txparser.Transaction) []string {
+	hashes := make([]string, 0, len(transactions))
+	for _, tx := range transactions {
+		hashes = append(hashes, tx.Hash)
+	}
+
+	return hashes
+}
@@ -21,20 +21,61 @@ type BlockStorage interface {
 	DBTXStorage
 
 	SaveBlockID(ctx context.Context, blockID int) error
-	GetBlockID(ctx context.Context) int
+	GetBlockID(ctx context.Context) (int, error)
 }
 
 type TransactionStorage interface {
 	DBTXStorage
 
 	GetTransactionsByAddress(ctx context.Context, address string) ([]Transaction, error)
+	QueryTransactions(ctx context.Context, query TransactionsQuery) (*TransactionsPage, error)
 	SaveTransactions(ctx context.Context, address string, transaction []Transaction) error
 	DeleteTransactionsByAddress(ctx context.Context, address string) error
+	DeleteTransactionsByBlockRange(ctx context.Context, address string, fromBlock, toBlock int) (int, error)
+	Addresses(ctx context.Context) ([]string, error)
 }
 
 type SubscriptionsStorage interface {
+	// PutAddress subscribes the address, a managed address is no longer managed after it.
 	PutAddress(ctx context.Context, address string) error
-	IsAddressExists(ctx context.Context, address string) bool
+	DeleteAddress(ctx context.Context, address string) error
+	IsAddressExists(ctx context.Context, address string) (bool, error)
+
+	// GetAddresses returns subscribed addresses in ascending order.
+	GetAddresses(ctx context.Context) ([]string, error)
+
+	// PutManagedAddress subscribes the address on behalf of a config, managed addresses are
+	// unsubscribed by DeleteManagedAddress. An address subscribed by PutAddress stays unmanaged.
+	PutManagedAddress(ctx context.Context, address string) error
+
+	// DeleteManagedAddress unsubscribes the address if it is managed.
+	DeleteManagedAddress(ctx context.Context, address string) error
+
+	// GetManagedAddresses returns managed addresses in ascending order.
+	GetManagedAddresses(ctx context.Context) ([]string, error)
+}
+
+// CursorStorage keeps positions of named consumers in the transactions of addresses.
+type CursorStorage interface {
+	GetCursor(ctx context.Context, consumer, address string) (string, error)
+	SaveCursor(ctx context.Context, consumer, address, cursor string) error
+}
+
+// APIKeyStorage keeps tenants by hashes of their API keys, see HashAPIKey.
+type APIKeyStorage interface {
+	PutAPIKey(ctx context.Context, keyHash string, tenant Tenant) error
+
+	// GetTenantByAPIKey returns ErrUnknownAPIKey if there is no key with the hash.
+	GetTenantByAPIKey(ctx context.Context, keyHash string) (*Tenant, error)
+}
+
+// TenantSubscriptionsStorage keeps the addresses every tenant subscribed to.
+type TenantSubscriptionsStorage interface {
+	// PutTenantAddress returns ErrSubscriptionQuotaExceeded if the tenant already has maxAddresses
+	// other addresses, zero maxAddresses disables the limit.
+	PutTenantAddress(ctx context.Context, tenantID, address string, maxAddresses int) error
+	IsTenantAddressExists(ctx context.Context, tenantID, address string) (bool, error)
+	GetTenantAddresses(ctx context.Context, tenantID string) ([]string, error)
 }
 
 type Client interface {
@@ -0,0 +1,335 @@
+package txparser
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidEventID = errors.New("invalid event id")
+
+type EventType string
+
+const (
+	// EventTransaction notifies about a parsed transaction of a subscribed address.
+	EventTransaction EventType = "transaction"
+
+	// EventBlock notifies about a saved block, it follows the transaction events of the block.
+	EventBlock EventType = "block"
+)
+
+type Event struct {
+	Type        EventType `json:"type"`
+	BlockNumber int       `json:"blockNumber"`
+
+	// ID is "<block number>-<transaction index>" of the transaction, followed by
+	// "-<kind>-<log index>" for other entries of it, see EventIDOf.
+	// It is set for transaction events only, as well as Address and Transaction.
+	ID          string       `json:"id,omitempty"`
+	Address     string       `json:"address,omitempty"`
+	Transaction *Transaction `json:"transaction,omitempty"`
+}
+
+func newTransactionEvent(address string, tx Transaction) Event {
+	return Event{
+		Type:        EventTransaction,
+		BlockNumber: blockNumberOf(tx),
+		ID:          EventIDOf(tx),
+		Address:     address,
+		Transaction: &tx,
+	}
+}
+
+// EventIDOf returns the ID of events about the transaction. IDs grow with
+// the transaction position in the chain, so the last received ID resumes a stream.
+// Entries of the transaction, such as token transfers, follow it by their kind and log index.
+func EventIDOf(tx Transaction) string {
+	return encodeEventID(eventPositionOf(tx))
+}
+
+// eventPosition is the position of the transaction entry an event ID refers to.
+type eventPosition struct {
+	block    int
+	index    int
+	kind     string
+	logIndex int
+}
+
+func eventPositionOf(tx Transaction) eventPosition {
+	return eventPosition{
+		block:    blockNumberOf(tx),
+		index:    transactionIndexOf(tx),
+		kind:     tx.Kind,
+		logIndex: logIndexOf(tx),
+	}
+}
+
+func encodeEventID(p eventPosition) string {
+	id := strconv.Itoa(p.block) + "-" + strconv.Itoa(p.index)
+	if p.kind != "" || p.logIndex != 0 {
+		id += "-" + p.kind + "-" + strconv.Itoa(p.logIndex)
+	}
+
+	return id
+}
+
+func parseEventID(id string) (eventPosition, error) {
+	parts := strings.SplitN(id, "-", 3)
+	if len(parts) < 2 {
+		return eventPosition{}, ErrInvalidEventID
+	}
+
+	block, err := strconv.Atoi(parts[0])
+	if err != nil || block < 0 {
+		return eventPosition{}, ErrInvalidEventID
+	}
+
+	index, err := strconv.Atoi(parts[1])
+	if err != nil || index < 0 {
+		return eventPosition{}, ErrInvalidEventID
+	}
+
+	position := eventPosition{block: block, index: index}
+	if len(parts) == 3 {
+		// The kind may contain dashes, the log index follows the last one
+		separator := strings.LastIndex(parts[2], "-")
+		if separator < 0 {
+			return eventPosition{}, ErrInvalidEventID
+		}
+		position.kind = parts[2][:separator]
+		position.logIndex, err = strconv.Atoi(parts[2][separator+1:])
+		if err != nil || position.logIndex < 0 {
+			return eventPosition{}, ErrInvalidEventID
+		}
+	}
+
+	return position, nil
+}
+
+func (p eventPosition) after(other eventPosition) bool {
+	if p.block != other.block {
+		return p.block > other.block
+	}
+	if p.index != other.index {
+		return p.index > other.index
+	}
+	if p.kind != other.kind {
+		return p.kind > other.kind
+	}
+
+	return p.logIndex > other.logIndex
+}
+
+// Listen returns a channel receiving events of every block once it is saved:
+// transaction events of the block followed by the block event.
+// A listener whose buffer is full is dropped and its channel is closed,
+// so a slow listener never holds up parsing. The returned function stops listening.
+func (p *TXParser) Listen(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+
+	p.listenersMu.Lock()
+	p.listeners[ch] = struct{}{}
+	p.listenersMu.Unlock()
+
+	return ch, func() {
+		p.listenersMu.Lock()
+		defer p.listenersMu.Unlock()
+
+		p.removeListener(ch)
+	}
+}
+
+func (p *TXParser) publish(events []Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	p.listenersMu.Lock()
+	defer p.listenersMu.Unlock()
+
+	for ch := range p.listeners {
+		for _, event := range events {
+			select {
+			case ch <- event:
+				continue
+			default:
+			}
+
+			p.removeListener(ch)
+			break
+		}
+	}
+}
+
+// removeListener closes the listener channel unless it is already closed, the caller holds listenersMu.
+func (p *TXParser) removeListener(ch chan Event) {
+	if _, ok := p.listeners[ch]; !ok {
+		return
+	}
+
+	delete(p.listeners, ch)
+	close(ch)
+}
+
+// Stream returns transaction events of the addresses until ctx is done or the stream falls behind
+// and is dropped, then the channel is closed. If lastEventID is set, stored transactions
+// after it are replayed before live events. Every transaction is sent once even if
+// it concerns several of the addresses.
+func (p *TXParser) Stream(
+	ctx context.Context,
+	addresses []string,
+	lastEventID string,
+	buffer int,
+) (<-chan Event, error) {
+	last := eventPosition{block: -1, index: -1}
+	if lastEventID != "" {
+		var err error
+		last, err = parseEventID(lastEventID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Listen before replaying, so events saved during the replay are not missed
+	live, stop := p.Listen(buffer)
+
+	out := make(chan Event)
+	go func() {
+		defer close(out)
+		defer stop()
+
+		s := &stream{
+			addresses: make(map[string]struct{}, len(addresses)),
+			last:      last,
+			out:       out,
+		}
+		for _, address := range addresses {
+			s.addresses[address] = struct{}{}
+		}
+
+		if lastEventID != "" {
+			err := p.replay(ctx, s, addresses)
+			if err != nil {
+				if ctx.Err() == nil {
+					p.logger.ErrorContext(ctx, "replay events", "last_event_id", lastEventID, "error", err)
+				}
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-live:
+				if !ok {
+					return
+				}
+
+				if !s.send(ctx, event) {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+type stream struct {
+	addresses map[string]struct{}
+	last      eventPosition
+	out       chan<- Event
+}
+
+// send sends the transaction event unless it is of another address or not after the last sent one.
+// It returns false once ctx is done.
+func (s *stream) send(ctx context.Context, event Event) bool {
+	if event.Type != EventTransaction {
+		return true
+	}
+	if _, ok := s.addresses[event.Address]; !ok {
+		return true
+	}
+
+	position := eventPositionOf(*event.Transaction)
+	if !position.after(s.last) {
+		return true
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case s.out <- event:
+		s.last = position
+		return true
+	}
+}
+
+// replay sends stored transactions of the addresses after the last sent one,
+// merging the addresses pages in the chain order.
+func (p *TXParser) replay(ctx context.Context, s *stream, addresses []string) error {
+	cursors := make([]*replayCursor, 0, len(addresses))
+	for _, address := range addresses {
+		cursors = append(cursors, &replayCursor{
+			query: TransactionsQuery{
+				Address:   address,
+				FromBlock: s.last.block,
+				Limit:     MaxQueryLimit,
+			},
+		})
+	}
+
+	for {
+		var next *replayCursor
+		for _, c := range cursors {
+			err := c.fill(ctx, p)
+			if err != nil {
+				return err
+			}
+
+			if len(c.transactions) == 0 {
+				continue
+			}
+			if next == nil || positionOf(c.transactions[0]).less(positionOf(next.transactions[0])) {
+				next = c
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+
+		tx := next.transactions[0]
+		next.transactions = next.transactions[1:]
+
+		if !s.send(ctx, newTransactionEvent(next.query.Address, tx)) {
+			return ctx.Err()
+		}
+	}
+}
+
+type replayCursor struct {
+	query        TransactionsQuery
+	transactions []Transaction
+	done         bool
+}
+
+// fill loads the next page once the loaded transactions are sent.
+func (c *replayCursor) fill(ctx context.Context, p *TXParser) error {
+	if len(c.transactions) > 0 || c.done {
+		return nil
+	}
+
+	page, err := p.QueryTransactions(ctx, c.query)
+	if err != nil {
+		return err
+	}
+
+	c.transactions = page.Transactions
+	c.query.Cursor = page.NextCursor
+	c.done = page.NextCursor == ""
+
+	return nil
+}
@@ -0,0 +1,258 @@
+package txparser_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/txparsertest"
+)
+
+func Test_Parser_Listen(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	ctx := context.Background()
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	subscriptionsStorage := txparser.NewInmemorySubscriptionsStorage()
+	_ = blockStorage.SaveBlockID(ctx, 6)
+	_ = subscriptionsStorage.PutAddress(ctx, "0x123")
+	_ = subscriptionsStorage.PutAddress(ctx, "0x321")
+	parser := txparser.NewTXParser(
+		blockStorage,
+		txparser.NewInmemoryTransactionsStorage(),
+		subscriptionsStorage,
+		newEventsClient(),
+		txparser.WithClock(clock),
+	)
+	events, stop := parser.Listen(10)
+	defer stop()
+
+	// Act
+	runUntilBlock(t, parser, clock, blockStorage, 7)
+
+	// Assert
+	got := drainEvents(events)
+	want := []txparser.Event{
+		{Type: txparser.EventTransaction, BlockNumber: 7, ID: "7-0", Address: "0x123"},
+		{Type: txparser.EventTransaction, BlockNumber: 7, ID: "7-1", Address: "0x123"},
+		{Type: txparser.EventTransaction, BlockNumber: 7, ID: "7-1", Address: "0x321"},
+		{Type: txparser.EventTransaction, BlockNumber: 7, ID: "7-2", Address: "0x321"},
+		{Type: txparser.EventBlock, BlockNumber: 7},
+	}
+	if len(got) != len(want) {
+		t.Errorf("events slice should have %d item(s), but has %d", len(want), len(got))
+		t.FailNow()
+	}
+	for i := range want {
+		got[i].Transaction = nil
+		if got[i] != want[i] {
+			t.Errorf("event %d should be %+v, but is %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func Test_Parser_ListenDropsSlowListener(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	ctx := context.Background()
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	subscriptionsStorage := txparser.NewInmemorySubscriptionsStorage()
+	_ = blockStorage.SaveBlockID(ctx, 6)
+	_ = subscriptionsStorage.PutAddress(ctx, "0x123")
+	parser := txparser.NewTXParser(
+		blockStorage,
+		txparser.NewInmemoryTransactionsStorage(),
+		subscriptionsStorage,
+		newEventsClient(),
+		txparser.WithClock(clock),
+	)
+	events, stop := parser.Listen(1)
+	defer stop()
+
+	// Act
+	runUntilBlock(t, parser, clock, blockStorage, 7)
+
+	// Assert
+	got := drainEvents(events)
+	if len(got) != 1 {
+		t.Errorf("events slice should have %d item(s), but has %d", 1, len(got))
+	}
+	_, ok := <-events
+	if ok {
+		t.Error("events channel should be closed")
+	}
+}
+
+func Test_Parser_Stream(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	txStorage := txparser.NewInmemoryTransactionsStorage()
+	subscriptionsStorage := txparser.NewInmemorySubscriptionsStorage()
+	_ = blockStorage.SaveBlockID(ctx, 6)
+	_ = subscriptionsStorage.PutAddress(ctx, "0x123")
+	_ = subscriptionsStorage.PutAddress(ctx, "0x321")
+	shared := txparser.Transaction{BlockNumber: "0x5", TransactionIndex: "0x1", Hash: "0xabc51", From: "0x123", To: "0x321"}
+	_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{
+		{BlockNumber: "0x4", TransactionIndex: "0x0", Hash: "0xabc40", From: "0x123", To: "0x456"},
+		{BlockNumber: "0x5", TransactionIndex: "0x0", Hash: "0xabc50", From: "0x123", To: "0x456"},
+		shared,
+	})
+	_ = txStorage.SaveTransactions(ctx, "0x321", []txparser.Transaction{
+		shared,
+		{BlockNumber: "0x6", TransactionIndex: "0x3", Hash: "0xabc63", From: "0x456", To: "0x321"},
+	})
+	clock := txparsertest.NewFakeClock(epoch)
+	parser := txparser.NewTXParser(
+		blockStorage,
+		txStorage,
+		subscriptionsStorage,
+		newEventsClient(),
+		txparser.WithClock(clock),
+	)
+
+	// Act
+	events, err := parser.Stream(ctx, []string{"0x123", "0x321"}, "4-0", 10)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	replayed := receiveEvents(t, events, 3)
+	runUntilBlock(t, parser, clock, blockStorage, 7)
+	live := receiveEvents(t, events, 3)
+
+	// Assert
+	want := []string{"0xabc50", "0xabc51", "0xabc63", "0xabc70", "0xabc71", "0xabc72"}
+	got := make([]string, 0, len(want))
+	for _, event := range append(replayed, live...) {
+		got = append(got, event.Transaction.Hash)
+	}
+	if !areSlicesEqual(want, got) {
+		t.Errorf("streamed hashes should be %v, but are %v", want, got)
+	}
+
+	// Act #2
+	cancel()
+
+	// Assert
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Error("no more events should be streamed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("events channel should be closed")
+	}
+}
+
+func Test_Parser_StreamTokenTransfers(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	txStorage := txparser.NewInmemoryTransactionsStorage()
+	transfer := txparser.Transaction{
+		BlockNumber:      "0x5",
+		TransactionIndex: "0x1",
+		Hash:             "0xabc51",
+		From:             "0x456",
+		To:               "0x123",
+		Kind:             txparser.KindTokenTransfer,
+		LogIndex:         "0x2",
+	}
+	_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{
+		{BlockNumber: "0x5", TransactionIndex: "0x1", Hash: "0xabc51", From: "0x123", To: "0x456"},
+		transfer,
+	})
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txStorage,
+		txparser.NewInmemorySubscriptionsStorage(),
+		newEventsClient(),
+	)
+
+	// Act
+	events, err := parser.Stream(ctx, []string{"0x123"}, "5-1", 10)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	replayed := receiveEvents(t, events, 1)
+
+	// Assert
+	if replayed[0].ID != "5-1-token_transfer-2" || replayed[0].ID != txparser.EventIDOf(transfer) {
+		t.Errorf("event id should be %q, but is %q", "5-1-token_transfer-2", replayed[0].ID)
+	}
+	if replayed[0].Transaction.Kind != txparser.KindTokenTransfer {
+		t.Errorf("kind should be %q, but is %q", txparser.KindTokenTransfer, replayed[0].Transaction.Kind)
+	}
+}
+
+func Test_Parser_StreamInvalidEventID(t *testing.T) {
+	// Arrange
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		newEventsClient(),
+	)
+
+	for _, id := range []string{"abc", "1", "1-", "-1-0", "1-0-0", "1-0-token_transfer-x"} {
+		// Act
+		_, err := parser.Stream(context.Background(), []string{"0x123"}, id, 10)
+
+		// Assert
+		if !errors.Is(err, txparser.ErrInvalidEventID) {
+			t.Errorf("error for %q should be %v, but is %v", id, txparser.ErrInvalidEventID, err)
+		}
+	}
+}
+
+func newEventsClient() *blocksClient {
+	return &blocksClient{
+		head: 7,
+		blocks: map[int][]txparser.Transaction{
+			7: {
+				{BlockNumber: "0x7", TransactionIndex: "0x0", Hash: "0xabc70", From: "0x123", To: "0x123"},
+				{BlockNumber: "0x7", TransactionIndex: "0x1", Hash: "0xabc71", From: "0x123", To: "0x321"},
+				{BlockNumber: "0x7", TransactionIndex: "0x2", Hash: "0xabc72", From: "0x456", To: "0x321"},
+			},
+		},
+	}
+}
+
+func drainEvents(events <-chan txparser.Event) []txparser.Event {
+	var got []txparser.Event
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return got
+			}
+			got = append(got, event)
+		default:
+			return got
+		}
+	}
+}
+
+func receiveEvents(t *testing.T, events <-chan txparser.Event, n int) []txparser.Event {
+	t.Helper()
+
+	got := make([]txparser.Event, 0, n)
+	for len(got) < n {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("events channel is closed after %d event(s)", len(got))
+			}
+			got = append(got, event)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d event(s) of %d", len(got), n)
+		}
+	}
+
+	return got
+}
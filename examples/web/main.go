@@ -2,74 +2,74 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"log"
-	"net/http"
-	"time"
+	"log/slog"
+	"os"
+
 	"txparser"
+	"txparser/config"
+	"txparser/httpapi"
 )
 
-type SubscribeRequest struct {
-	Address string `json:"address"`
-}
-
 func main() {
 	ctx := context.Background()
+	logger := slog.New(slog.NewTextHandler(os.Stderr, nil))
 
-	// Dependencies
-	blockStorage := txparser.NewInmemoryBlockStorage()
-	transactionsStorage := txparser.NewInmemoryTransactionsStorage()
-	subscriptionsStorage := txparser.NewInmemorySubscriptionsStorage()
-	client := txparser.NewJSONRPCClient(http.DefaultClient, "https://cloudflare-eth.com")
-	parser := txparser.NewTXParser(
-		blockStorage,
-		transactionsStorage,
-		subscriptionsStorage,
-		client,
-	)
-
-	// Run background job
-	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
-	go parser.RunWorker(ctx, 1*time.Second)
+	// Defaults overridden by the file of TXPARSER_CONFIG and TXPARSER_* variables
+	path := os.Getenv("TXPARSER_CONFIG")
+	cfg, err := config.Load(path)
+	if err != nil {
+		logger.Error("load config", "path", path, "error", err)
+		os.Exit(1)
+	}
 
-	http.HandleFunc("/currentBlock", func(w http.ResponseWriter, r *http.Request) {
-		block := parser.GetCurrentBlock()
-		err := json.NewEncoder(w).Encode(block)
-		if err != nil {
-			log.Print(err)
-		}
-	})
+	// Dependencies
+	service, err := config.Build(ctx, cfg, config.WithLogger(logger))
+	if err != nil {
+		logger.Error("build service", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		_ = service.Close()
+	}()
 
-	http.HandleFunc("/subscribe", func(w http.ResponseWriter, r *http.Request) {
-		var in SubscribeRequest
+	opts := []httpapi.Option{
+		httpapi.WithAddr(cfg.Addr),
+		httpapi.WithMetrics(service.Metrics),
+		httpapi.WithLogger(logger),
+		httpapi.WithRateLimit(httpapi.RateLimit{Rate: 10, Burst: 20}),
+		httpapi.WithRouteRateLimit("/transactions", httpapi.RateLimit{Rate: 2, Burst: 5}),
+	}
 
-		err := json.NewDecoder(r.Body).Decode(&in)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
+	// Requires the key on every request if set
+	if cfg.APIKey != "" {
+		apiKeys := txparser.NewInmemoryAPIKeyStorage()
+		_ = apiKeys.PutAPIKey(ctx, txparser.HashAPIKey(cfg.APIKey), txparser.Tenant{
+			ID:               "default",
+			MaxSubscriptions: 100,
+		})
+		opts = append(opts, httpapi.WithAuth(apiKeys, service.TenantSubscriptions))
+	}
 
-		success := parser.Subscribe(in.Address)
-		err = json.NewEncoder(w).Encode(success)
-		if err != nil {
-			log.Print(err)
-		}
-	})
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	period := service.PollPeriod
 
-	http.HandleFunc("/transactions", func(w http.ResponseWriter, r *http.Request) {
-		address := r.URL.Query().Get("address")
-		transactions := parser.GetTransactions(address)
-		err := json.NewEncoder(w).Encode(transactions)
-		if err != nil {
-			log.Print(err)
-		}
-	})
+	// Applies changes of the config on SIGHUP and when files change
+	reloader := &config.Reloader{
+		Service: service,
+		Path:    path,
+		OnReload: func(err error) {
+			if err != nil {
+				logger.Error("reload config", "error", err)
+			}
+		},
+	}
+	go reloader.Run(ctx)
 
-	err := http.ListenAndServe(":8080", nil)
+	// Serves the API and runs the background job until SIGTERM or SIGINT
+	server := httpapi.NewServer(service.Parser, opts...)
+	err = server.Run(ctx, period)
 	if err != nil {
-		log.Print(err)
+		logger.Error("run server", "error", err)
 	}
-
-	// TODO: Implement Graceful shutdown
 }
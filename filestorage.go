@@ -0,0 +1,736 @@
+package txparser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+)
+
+type fileStorageOptions struct {
+	compactionThreshold int
+	logger              *slog.Logger
+}
+
+type FileStorageOption func(*fileStorageOptions)
+
+// WithCompactionThreshold sets the number of log records
+// after which the log is compacted into a snapshot. Zero disables compaction.
+func WithCompactionThreshold(records int) FileStorageOption {
+	return func(o *fileStorageOptions) {
+		o.compactionThreshold = records
+	}
+}
+
+// WithFileLogger sets the logger of recovery and compaction of logs, records are dropped by default or if it is nil.
+func WithFileLogger(logger *slog.Logger) FileStorageOption {
+	return func(o *fileStorageOptions) {
+		if logger != nil {
+			o.logger = logger
+		}
+	}
+}
+
+func newFileStorageOptions(opts []FileStorageOption) fileStorageOptions {
+	o := fileStorageOptions{
+		compactionThreshold: defaultCompactionThreshold,
+		logger:              discardLogger(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// FileBlockStorage is a BlockStorage persisted to the "blocks" log in a directory.
+type FileBlockStorage struct {
+	mu  sync.Mutex
+	mem *InmemoryBlockStorage
+	log *walLog
+}
+
+func NewFileBlockStorage(dir string, opts ...FileStorageOption) (*FileBlockStorage, error) {
+	o := newFileStorageOptions(opts)
+	s := &FileBlockStorage{
+		mem: NewInmemoryBlockStorage(),
+	}
+
+	var err error
+	s.log, err = openWAL(dir, "blocks", o, s.restore, s.restore)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileBlockStorage) SaveBlockID(ctx context.Context, blockID int) error {
+	if tx, ok := stagedTxFromContext[int](ctx, s); ok {
+		return tx.add(blockID)
+	}
+
+	return s.commit([]int{blockID})
+}
+
+func (s *FileBlockStorage) GetBlockID(ctx context.Context) (int, error) {
+	tx, _ := stagedTxFromContext[int](ctx, s)
+	if staged := tx.staged(); len(staged) > 0 {
+		return staged[len(staged)-1], nil
+	}
+
+	return s.mem.GetBlockID(ctx)
+}
+
+func (s *FileBlockStorage) WithDBTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runStagedTx(ctx, s, fn, s.commit)
+}
+
+func (s *FileBlockStorage) Close() error {
+	return s.log.Close()
+}
+
+func (s *FileBlockStorage) commit(ops []int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payload, err := json.Marshal(ops[len(ops)-1])
+	if err != nil {
+		return err
+	}
+
+	err = s.log.Append(payload)
+	if err != nil {
+		return err
+	}
+
+	err = s.mem.SaveBlockID(context.Background(), ops[len(ops)-1])
+	if err != nil {
+		return err
+	}
+
+	if s.log.NeedsCompaction() {
+		compactFileStorage(s.log, s.mem.get(context.Background()))
+	}
+
+	return nil
+}
+
+func (s *FileBlockStorage) restore(payload []byte) error {
+	var blockID int
+
+	err := json.Unmarshal(payload, &blockID)
+	if err != nil {
+		return err
+	}
+
+	return s.mem.SaveBlockID(context.Background(), blockID)
+}
+
+// FileSubscriptionsStorage is a SubscriptionsStorage persisted to the "subscriptions" log in a directory.
+type FileSubscriptionsStorage struct {
+	mu  sync.Mutex
+	mem *InmemorySubscriptionsStorage
+	log *walLog
+}
+
+func NewFileSubscriptionsStorage(dir string, opts ...FileStorageOption) (*FileSubscriptionsStorage, error) {
+	o := newFileStorageOptions(opts)
+	s := &FileSubscriptionsStorage{
+		mem: NewInmemorySubscriptionsStorage(),
+	}
+
+	var err error
+	s.log, err = openWAL(dir, "subscriptions", o, s.restore, s.replay)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileSubscriptionsStorage) PutAddress(_ context.Context, address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mem.has(address) && !s.mem.isManaged(address) {
+		return nil
+	}
+
+	return s.write(subscriptionsOp{Kind: subscriptionsOpPut, Address: address})
+}
+
+func (s *FileSubscriptionsStorage) DeleteAddress(_ context.Context, address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.mem.has(address) {
+		return nil
+	}
+
+	return s.write(subscriptionsOp{Kind: subscriptionsOpDelete, Address: address})
+}
+
+func (s *FileSubscriptionsStorage) IsAddressExists(ctx context.Context, address string) (bool, error) {
+	return s.mem.IsAddressExists(ctx, address)
+}
+
+func (s *FileSubscriptionsStorage) GetAddresses(ctx context.Context) ([]string, error) {
+	return s.mem.GetAddresses(ctx)
+}
+
+func (s *FileSubscriptionsStorage) PutManagedAddress(_ context.Context, address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mem.has(address) {
+		return nil
+	}
+
+	return s.write(subscriptionsOp{Kind: subscriptionsOpPut, Address: address, Managed: true})
+}
+
+func (s *FileSubscriptionsStorage) DeleteManagedAddress(_ context.Context, address string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.mem.isManaged(address) {
+		return nil
+	}
+
+	return s.write(subscriptionsOp{Kind: subscriptionsOpDelete, Address: address, Managed: true})
+}
+
+func (s *FileSubscriptionsStorage) GetManagedAddresses(ctx context.Context) ([]string, error) {
+	return s.mem.GetManagedAddresses(ctx)
+}
+
+func (s *FileSubscriptionsStorage) Close() error {
+	return s.log.Close()
+}
+
+// write logs and applies the op, the lock is held.
+func (s *FileSubscriptionsStorage) write(op subscriptionsOp) error {
+	payload, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	err = s.log.Append(payload)
+	if err != nil {
+		return err
+	}
+
+	err = s.apply(op)
+	if err != nil {
+		return err
+	}
+
+	if s.log.NeedsCompaction() {
+		compactFileStorage(s.log, s.snapshot())
+	}
+
+	return nil
+}
+
+func (s *FileSubscriptionsStorage) apply(op subscriptionsOp) error {
+	ctx := context.Background()
+
+	switch {
+	case op.Kind == subscriptionsOpPut && op.Managed:
+		return s.mem.PutManagedAddress(ctx, op.Address)
+	case op.Kind == subscriptionsOpPut:
+		return s.mem.PutAddress(ctx, op.Address)
+	case op.Kind == subscriptionsOpDelete && op.Managed:
+		return s.mem.DeleteManagedAddress(ctx, op.Address)
+	case op.Kind == subscriptionsOpDelete:
+		return s.mem.DeleteAddress(ctx, op.Address)
+	}
+
+	return nil
+}
+
+func (s *FileSubscriptionsStorage) snapshot() subscriptionsSnapshot {
+	var snapshot subscriptionsSnapshot
+	for _, address := range s.mem.list() {
+		if s.mem.isManaged(address) {
+			snapshot.Managed = append(snapshot.Managed, address)
+		} else {
+			snapshot.Addresses = append(snapshot.Addresses, address)
+		}
+	}
+
+	return snapshot
+}
+
+func (s *FileSubscriptionsStorage) restore(payload []byte) error {
+	var snapshot subscriptionsSnapshot
+
+	// Snapshots written before managed addresses are lists of addresses
+	var err error
+	if bytes.HasPrefix(bytes.TrimSpace(payload), []byte("[")) {
+		err = json.Unmarshal(payload, &snapshot.Addresses)
+	} else {
+		err = json.Unmarshal(payload, &snapshot)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, address := range snapshot.Addresses {
+		err = s.mem.PutAddress(context.Background(), address)
+		if err != nil {
+			return err
+		}
+	}
+	for _, address := range snapshot.Managed {
+		err = s.mem.PutManagedAddress(context.Background(), address)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *FileSubscriptionsStorage) replay(payload []byte) error {
+	var op subscriptionsOp
+
+	err := json.Unmarshal(payload, &op)
+	if err != nil {
+		return err
+	}
+
+	return s.apply(op)
+}
+
+const (
+	subscriptionsOpPut    = "put"
+	subscriptionsOpDelete = "delete"
+)
+
+// subscriptionsOp is a record of the log, Managed marks ops of managed addresses.
+type subscriptionsOp struct {
+	Kind    string `json:"kind"`
+	Address string `json:"address"`
+	Managed bool   `json:"managed,omitempty"`
+}
+
+// subscriptionsSnapshot keeps unmanaged and managed addresses apart.
+type subscriptionsSnapshot struct {
+	Addresses []string `json:"addresses"`
+	Managed   []string `json:"managed,omitempty"`
+}
+
+// FileTenantSubscriptionsStorage is a TenantSubscriptionsStorage persisted to the "tenant_subscriptions" log
+// in a directory.
+type FileTenantSubscriptionsStorage struct {
+	mu  sync.Mutex
+	mem *InmemoryTenantSubscriptionsStorage
+	log *walLog
+}
+
+func NewFileTenantSubscriptionsStorage(
+	dir string,
+	opts ...FileStorageOption,
+) (*FileTenantSubscriptionsStorage, error) {
+	o := newFileStorageOptions(opts)
+	s := &FileTenantSubscriptionsStorage{
+		mem: NewInmemoryTenantSubscriptionsStorage(),
+	}
+
+	var err error
+	s.log, err = openWAL(dir, "tenant_subscriptions", o, s.restore, s.replay)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileTenantSubscriptionsStorage) PutTenantAddress(
+	ctx context.Context,
+	tenantID, address string,
+	maxAddresses int,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.mem.has(tenantID, address) {
+		return nil
+	}
+
+	addresses, err := s.mem.GetTenantAddresses(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	if maxAddresses > 0 && len(addresses) >= maxAddresses {
+		return ErrSubscriptionQuotaExceeded
+	}
+
+	payload, err := json.Marshal(tenantSubscriptionsOp{
+		TenantID: tenantID,
+		Address:  address,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.log.Append(payload)
+	if err != nil {
+		return err
+	}
+
+	err = s.mem.PutTenantAddress(ctx, tenantID, address, 0)
+	if err != nil {
+		return err
+	}
+
+	if s.log.NeedsCompaction() {
+		compactFileStorage(s.log, s.mem.snapshot())
+	}
+
+	return nil
+}
+
+func (s *FileTenantSubscriptionsStorage) IsTenantAddressExists(
+	ctx context.Context,
+	tenantID, address string,
+) (bool, error) {
+	return s.mem.IsTenantAddressExists(ctx, tenantID, address)
+}
+
+func (s *FileTenantSubscriptionsStorage) GetTenantAddresses(ctx context.Context, tenantID string) ([]string, error) {
+	return s.mem.GetTenantAddresses(ctx, tenantID)
+}
+
+func (s *FileTenantSubscriptionsStorage) Close() error {
+	return s.log.Close()
+}
+
+func (s *FileTenantSubscriptionsStorage) restore(payload []byte) error {
+	var addressesByTenant map[string][]string
+
+	err := json.Unmarshal(payload, &addressesByTenant)
+	if err != nil {
+		return err
+	}
+
+	for tenantID, addresses := range addressesByTenant {
+		for _, address := range addresses {
+			err = s.mem.PutTenantAddress(context.Background(), tenantID, address, 0)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *FileTenantSubscriptionsStorage) replay(payload []byte) error {
+	var op tenantSubscriptionsOp
+
+	err := json.Unmarshal(payload, &op)
+	if err != nil {
+		return err
+	}
+
+	return s.mem.PutTenantAddress(context.Background(), op.TenantID, op.Address, 0)
+}
+
+type tenantSubscriptionsOp struct {
+	TenantID string `json:"tenantId"`
+	Address  string `json:"address"`
+}
+
+// FileCursorStorage is a CursorStorage persisted to the "cursors" log in a directory.
+type FileCursorStorage struct {
+	mu  sync.Mutex
+	mem *InmemoryCursorStorage
+	log *walLog
+}
+
+func NewFileCursorStorage(dir string, opts ...FileStorageOption) (*FileCursorStorage, error) {
+	o := newFileStorageOptions(opts)
+	s := &FileCursorStorage{
+		mem: NewInmemoryCursorStorage(),
+	}
+
+	var err error
+	s.log, err = openWAL(dir, "cursors", o, s.restore, s.replay)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileCursorStorage) GetCursor(ctx context.Context, consumer, address string) (string, error) {
+	return s.mem.GetCursor(ctx, consumer, address)
+}
+
+func (s *FileCursorStorage) SaveCursor(ctx context.Context, consumer, address, cursor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payload, err := json.Marshal(cursorOp{
+		Consumer: consumer,
+		Address:  address,
+		Cursor:   cursor,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.log.Append(payload)
+	if err != nil {
+		return err
+	}
+
+	err = s.mem.SaveCursor(ctx, consumer, address, cursor)
+	if err != nil {
+		return err
+	}
+
+	if s.log.NeedsCompaction() {
+		compactFileStorage(s.log, s.mem.snapshot())
+	}
+
+	return nil
+}
+
+func (s *FileCursorStorage) Close() error {
+	return s.log.Close()
+}
+
+func (s *FileCursorStorage) restore(payload []byte) error {
+	var cursors map[string]map[string]string
+
+	err := json.Unmarshal(payload, &cursors)
+	if err != nil {
+		return err
+	}
+
+	for consumer, addresses := range cursors {
+		for address, cursor := range addresses {
+			err = s.mem.SaveCursor(context.Background(), consumer, address, cursor)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *FileCursorStorage) replay(payload []byte) error {
+	var op cursorOp
+
+	err := json.Unmarshal(payload, &op)
+	if err != nil {
+		return err
+	}
+
+	return s.mem.SaveCursor(context.Background(), op.Consumer, op.Address, op.Cursor)
+}
+
+type cursorOp struct {
+	Consumer string `json:"consumer"`
+	Address  string `json:"address"`
+	Cursor   string `json:"cursor"`
+}
+
+// FileTransactionsStorage is a TransactionStorage persisted to the "transactions" log in a directory.
+// Every committed database transaction is written as a single log record, so it is recovered
+// either entirely or not at all.
+type FileTransactionsStorage struct {
+	mu  sync.Mutex
+	mem *InmemoryTransactionsStorage
+	log *walLog
+}
+
+func NewFileTransactionsStorage(dir string, opts ...FileStorageOption) (*FileTransactionsStorage, error) {
+	o := newFileStorageOptions(opts)
+	s := &FileTransactionsStorage{
+		mem: NewInmemoryTransactionsStorage(),
+	}
+
+	var err error
+	s.log, err = openWAL(dir, "transactions", o, s.restore, s.replay)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *FileTransactionsStorage) GetTransactionsByAddress(
+	ctx context.Context,
+	address string,
+) ([]Transaction, error) {
+	tx, _ := stagedTxFromContext[transactionsOp](ctx, s)
+
+	return s.mem.get(address, tx.staged()), nil
+}
+
+func (s *FileTransactionsStorage) SaveTransactions(
+	ctx context.Context,
+	address string,
+	newTransactions []Transaction,
+) error {
+	op, err := newSaveTransactionsOp(address, newTransactions)
+	if err != nil {
+		return err
+	}
+
+	return s.write(ctx, op)
+}
+
+func (s *FileTransactionsStorage) QueryTransactions(
+	ctx context.Context,
+	query TransactionsQuery,
+) (*TransactionsPage, error) {
+	tx, _ := stagedTxFromContext[transactionsOp](ctx, s)
+
+	return s.mem.query(query, tx.staged())
+}
+
+func (s *FileTransactionsStorage) DeleteTransactionsByAddress(ctx context.Context, address string) error {
+	return s.write(ctx, transactionsOp{
+		Kind:    transactionsOpDelete,
+		Address: address,
+	})
+}
+
+// DeleteTransactionsByBlockRange deletes transactions of the address within the inclusive block range
+// and returns the number of deleted transactions.
+func (s *FileTransactionsStorage) DeleteTransactionsByBlockRange(
+	ctx context.Context,
+	address string,
+	fromBlock, toBlock int,
+) (int, error) {
+	op, err := newDeleteRangeOp(address, fromBlock, toBlock)
+	if err != nil {
+		return 0, err
+	}
+
+	if tx, ok := stagedTxFromContext[transactionsOp](ctx, s); ok {
+		return tx.addCounted(op, func(staged []transactionsOp) int {
+			return s.mem.countRange(address, fromBlock, toBlock, staged)
+		})
+	}
+
+	// Counted under the lock of writes, so the count matches the logged op
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := s.mem.countRange(address, fromBlock, toBlock, nil)
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	err = s.commitLocked([]transactionsOp{op})
+	if err != nil {
+		return 0, err
+	}
+
+	return deleted, nil
+}
+
+// Addresses returns the addresses with stored transactions.
+func (s *FileTransactionsStorage) Addresses(ctx context.Context) ([]string, error) {
+	tx, _ := stagedTxFromContext[transactionsOp](ctx, s)
+
+	return s.mem.addresses(tx.staged()), nil
+}
+
+func (s *FileTransactionsStorage) WithDBTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runStagedTx(ctx, s, fn, s.commit)
+}
+
+func (s *FileTransactionsStorage) Close() error {
+	return s.log.Close()
+}
+
+func (s *FileTransactionsStorage) write(ctx context.Context, op transactionsOp) error {
+	if tx, ok := stagedTxFromContext[transactionsOp](ctx, s); ok {
+		return tx.add(op)
+	}
+
+	return s.commit([]transactionsOp{op})
+}
+
+func (s *FileTransactionsStorage) commit(ops []transactionsOp) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.commitLocked(ops)
+}
+
+// commitLocked is commit for callers holding the lock.
+func (s *FileTransactionsStorage) commitLocked(ops []transactionsOp) error {
+	payload, err := json.Marshal(ops)
+	if err != nil {
+		return err
+	}
+
+	err = s.log.Append(payload)
+	if err != nil {
+		return err
+	}
+
+	s.mem.apply(ops...)
+
+	if s.log.NeedsCompaction() {
+		compactFileStorage(s.log, s.mem.snapshot())
+	}
+
+	return nil
+}
+
+func (s *FileTransactionsStorage) restore(payload []byte) error {
+	var transactionsByAddress map[string][]Transaction
+
+	err := json.Unmarshal(payload, &transactionsByAddress)
+	if err != nil {
+		return err
+	}
+
+	for address, transactions := range transactionsByAddress {
+		s.mem.apply(transactionsOp{
+			Kind:         transactionsOpSave,
+			Address:      address,
+			Transactions: transactions,
+		})
+	}
+
+	return nil
+}
+
+func (s *FileTransactionsStorage) replay(payload []byte) error {
+	var ops []transactionsOp
+
+	err := json.Unmarshal(payload, &ops)
+	if err != nil {
+		return err
+	}
+
+	s.mem.apply(ops...)
+
+	return nil
+}
+
+// compactFileStorage writes state as the snapshot of the log.
+// The write is already durable in the log, so a failed compaction is only logged
+// and retried after the next write.
+func compactFileStorage(l *walLog, state any) {
+	payload, err := json.Marshal(state)
+	if err == nil {
+		err = l.Compact(payload)
+	}
+	if err != nil {
+		l.logger.Warn("compact wal", "log", l.name, "error", err)
+	}
+}
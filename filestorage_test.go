@@ -0,0 +1,349 @@
+package txparser_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"txparser"
+)
+
+func Test_FileStorage_Reopen(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	blockStorage, txStorage, subscriptionsStorage := openFileStorages(t, dir)
+	_ = subscriptionsStorage.PutAddress(ctx, "0x123")
+	_ = subscriptionsStorage.PutAddress(ctx, "0x456")
+	_ = subscriptionsStorage.DeleteAddress(ctx, "0x456")
+	err := blockStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+		return txStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+			err := txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc1"}})
+			if err != nil {
+				return err
+			}
+
+			return blockStorage.SaveBlockID(ctx, 10)
+		})
+	})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	closeFileStorages(t, blockStorage, txStorage, subscriptionsStorage)
+
+	// Act
+	blockStorage, txStorage, subscriptionsStorage = openFileStorages(t, dir)
+	defer closeFileStorages(t, blockStorage, txStorage, subscriptionsStorage)
+
+	// Assert
+	if blockIDOf(t, ctx, blockStorage) != 10 {
+		t.Errorf("block id should be %d, but is %d", 10, blockIDOf(t, ctx, blockStorage))
+	}
+	if !isSubscribed(t, ctx, subscriptionsStorage, "0x123") {
+		t.Error("address should exist")
+	}
+	if isSubscribed(t, ctx, subscriptionsStorage, "0x456") {
+		t.Error("deleted address should not exist")
+	}
+	transactions, err := txStorage.GetTransactionsByAddress(ctx, "0x123")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(transactions) != 1 || transactions[0].Hash != "0xabc1" {
+		t.Errorf("transactions should be restored, but are %v", transactions)
+	}
+}
+
+func Test_FileStorage_RollbackIsNotPersisted(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	dir := t.TempDir()
+	errFailed := errors.New("failed")
+
+	txStorage, err := txparser.NewFileTransactionsStorage(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Act
+	err = txStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+		_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc1"}})
+		return errFailed
+	})
+	_ = txStorage.Close()
+
+	// Assert
+	if !errors.Is(err, errFailed) {
+		t.Errorf("error should be %v, but is %v", errFailed, err)
+	}
+	txStorage, err = txparser.NewFileTransactionsStorage(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer txStorage.Close()
+	transactions, _ := txStorage.GetTransactionsByAddress(ctx, "0x123")
+	if len(transactions) != 0 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 0, len(transactions))
+	}
+}
+
+func Test_FileStorage_TornWriteRecovery(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	txStorage, err := txparser.NewFileTransactionsStorage(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc1"}})
+	_ = txStorage.Close()
+
+	// Simulate a crash in the middle of a record write
+	f, err := os.OpenFile(filepath.Join(dir, "transactions.wal"), os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_, _ = f.Write([]byte{0, 0, 1, 0, 0xde, 0xad})
+	_ = f.Close()
+
+	// Act
+	txStorage, err = txparser.NewFileTransactionsStorage(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc2"}})
+	_ = txStorage.Close()
+
+	// Assert
+	txStorage, err = txparser.NewFileTransactionsStorage(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer txStorage.Close()
+	transactions, _ := txStorage.GetTransactionsByAddress(ctx, "0x123")
+	if len(transactions) != 2 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 2, len(transactions))
+	}
+}
+
+func Test_FileStorage_CorruptedRecord(t *testing.T) {
+	tests := []struct {
+		name    string
+		record  int
+		wantErr error
+		want    int
+	}{
+		{name: "last record is dropped", record: 1, want: 1},
+		{name: "record followed by others fails", record: 0, wantErr: txparser.ErrCorruptedWAL},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			dir := t.TempDir()
+			path := filepath.Join(dir, "transactions.wal")
+
+			txStorage, err := txparser.NewFileTransactionsStorage(dir)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			var offsets []int64
+			for _, hash := range []string{"0xabc1", "0xabc2"} {
+				info, _ := os.Stat(path)
+				offsets = append(offsets, info.Size())
+				_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: hash}})
+			}
+			_ = txStorage.Close()
+
+			// Damage the first payload byte, past the length, the checksum and the seq
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			data[offsets[test.record]+16] ^= 0xff
+			err = os.WriteFile(path, data, 0o600)
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+
+			// Act
+			txStorage, err = txparser.NewFileTransactionsStorage(dir)
+
+			// Assert
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("error should be %v, but is %v", test.wantErr, err)
+			}
+			if err != nil {
+				if info, _ := os.Stat(path); info.Size() != int64(len(data)) {
+					t.Errorf("log size should be %d, but is %d", len(data), info.Size())
+				}
+				return
+			}
+			defer txStorage.Close()
+			transactions, _ := txStorage.GetTransactionsByAddress(ctx, "0x123")
+			if len(transactions) != test.want {
+				t.Errorf("transactions slice should have %d item(s), but has %d", test.want, len(transactions))
+			}
+		})
+	}
+}
+
+func Test_FileStorage_Compaction(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	txStorage, err := txparser.NewFileTransactionsStorage(dir, txparser.WithCompactionThreshold(3))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Act
+	for _, hash := range []string{"0xabc1", "0xabc2", "0xabc3", "0xabc4"} {
+		err = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: hash}})
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	_ = txStorage.Close()
+
+	// Assert
+	if _, err := os.Stat(filepath.Join(dir, "transactions.snapshot")); err != nil {
+		t.Error("snapshot should be written:", err)
+	}
+	txStorage, err = txparser.NewFileTransactionsStorage(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer txStorage.Close()
+	transactions, _ := txStorage.GetTransactionsByAddress(ctx, "0x123")
+	if len(transactions) != 4 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 4, len(transactions))
+	}
+}
+
+func Test_FileTenantSubscriptionsStorage_Reopen(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	storage, err := txparser.NewFileTenantSubscriptionsStorage(dir, txparser.WithCompactionThreshold(2))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	for _, address := range []string{"0x123", "0x456", "0x789"} {
+		err = storage.PutTenantAddress(ctx, "tenant-a", address, 0)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+	_ = storage.Close()
+
+	// Act
+	storage, err = txparser.NewFileTenantSubscriptionsStorage(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer storage.Close()
+	addresses, _ := storage.GetTenantAddresses(ctx, "tenant-a")
+	quotaErr := storage.PutTenantAddress(ctx, "tenant-a", "0xabc", 3)
+
+	// Assert
+	if !areSlicesEqual([]string{"0x123", "0x456", "0x789"}, addresses) {
+		t.Errorf("addresses should be %v, but are %v", []string{"0x123", "0x456", "0x789"}, addresses)
+	}
+	if !errors.Is(quotaErr, txparser.ErrSubscriptionQuotaExceeded) {
+		t.Errorf("error should be %v, but is %v", txparser.ErrSubscriptionQuotaExceeded, quotaErr)
+	}
+}
+
+func Test_FileSubscriptionsStorage_ReopenKeepsManaged(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	storage, err := txparser.NewFileSubscriptionsStorage(dir, txparser.WithCompactionThreshold(2))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_ = storage.PutManagedAddress(ctx, "0x123")
+	_ = storage.PutAddress(ctx, "0x456")
+	_ = storage.PutManagedAddress(ctx, "0x789")
+	_ = storage.PutAddress(ctx, "0x789")
+	_ = storage.PutManagedAddress(ctx, "0xabc")
+	_ = storage.Close()
+
+	// Act
+	storage, err = txparser.NewFileSubscriptionsStorage(dir)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer storage.Close()
+	addresses, _ := storage.GetAddresses(ctx)
+	managed, _ := storage.GetManagedAddresses(ctx)
+
+	// Assert
+	if !areSlicesEqual([]string{"0x123", "0x456", "0x789", "0xabc"}, addresses) {
+		t.Errorf("addresses should be %v, but are %v", []string{"0x123", "0x456", "0x789", "0xabc"}, addresses)
+	}
+	if !areSlicesEqual([]string{"0x123", "0xabc"}, managed) {
+		t.Errorf("managed addresses should be %v, but are %v", []string{"0x123", "0xabc"}, managed)
+	}
+}
+
+func openFileStorages(t *testing.T, dir string) (
+	*txparser.FileBlockStorage,
+	*txparser.FileTransactionsStorage,
+	*txparser.FileSubscriptionsStorage,
+) {
+	t.Helper()
+
+	blockStorage, err := txparser.NewFileBlockStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txStorage, err := txparser.NewFileTransactionsStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriptionsStorage, err := txparser.NewFileSubscriptionsStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return blockStorage, txStorage, subscriptionsStorage
+}
+
+func closeFileStorages(t *testing.T, storages ...interface{ Close() error }) {
+	t.Helper()
+
+	for _, s := range storages {
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"txparser"
+)
+
+var errSubscribeFailed = errors.New("failed to subscribe")
+
+type tenantKey struct{}
+
+// WithAuth requires an API key on every endpoint. Addresses subscribed with a key
+// belong to its tenant, and only they are readable with keys of the tenant.
+func WithAuth(apiKeys txparser.APIKeyStorage, subscriptions txparser.TenantSubscriptionsStorage) Option {
+	return func(s *Server) {
+		s.apiKeys = apiKeys
+		s.tenantSubscriptions = subscriptions
+	}
+}
+
+// TenantFromContext returns the tenant of an authenticated request.
+func TenantFromContext(ctx context.Context) (*txparser.Tenant, bool) {
+	tenant, ok := ctx.Value(tenantKey{}).(*txparser.Tenant)
+
+	return tenant, ok
+}
+
+// authenticate resolves the tenant of the API key passed as a bearer token, the X-API-Key header
+// or, for browser event streams and WebSockets unable to set headers, the apiKey parameter.
+// Failed authentications are rate limited by IP addresses with the limit of the route.
+func (s *Server) authenticate(route string, next http.Handler) http.Handler {
+	if s.apiKeys == nil {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowAuthentication(w, r, route) {
+			return
+		}
+
+		key := apiKeyOf(r)
+		if key == "" {
+			s.countFailedAuthentication(r, route)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="txparser"`)
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "api key is required")
+			return
+		}
+
+		tenant, err := s.apiKeys.GetTenantByAPIKey(r.Context(), txparser.HashAPIKey(key))
+		if errors.Is(err, txparser.ErrUnknownAPIKey) {
+			s.countFailedAuthentication(r, route)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="txparser", error="invalid_token"`)
+			writeError(w, http.StatusUnauthorized, codeUnauthorized, "invalid api key")
+			return
+		}
+		if err != nil {
+			s.logError(r.Context(), "authenticate", err)
+			writeError(w, http.StatusInternalServerError, codeInternal, "failed to authenticate")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), tenantKey{}, tenant)))
+	})
+}
+
+func apiKeyOf(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	return r.URL.Query().Get("apiKey")
+}
+
+// subscribe subscribes the parser and the tenant of the request to the address.
+// It returns txparser.ErrSubscriptionQuotaExceeded once the tenant quota is used up.
+func (s *Server) subscribe(ctx context.Context, address string) error {
+	if tenant, ok := TenantFromContext(ctx); ok {
+		err := s.tenantSubscriptions.PutTenantAddress(ctx, tenant.ID, address, tenant.MaxSubscriptions)
+		if err != nil {
+			return err
+		}
+	}
+
+	if !s.parser.Subscribe(address) {
+		return errSubscribeFailed
+	}
+
+	return nil
+}
+
+// canRead reports whether the request may read transactions of the address.
+func (s *Server) canRead(ctx context.Context, address string) (bool, error) {
+	tenant, ok := TenantFromContext(ctx)
+	if !ok {
+		return true, nil
+	}
+
+	return s.tenantSubscriptions.IsTenantAddressExists(ctx, tenant.ID, address)
+}
+
+// writeSubscribeError writes the error of subscribe.
+func (s *Server) writeSubscribeError(w http.ResponseWriter, r *http.Request, address string, err error) {
+	if errors.Is(err, txparser.ErrSubscriptionQuotaExceeded) {
+		writeError(w, http.StatusForbidden, codeQuotaExceeded, err.Error())
+		return
+	}
+
+	if !errors.Is(err, errSubscribeFailed) {
+		s.logError(r.Context(), "subscribe", err, "address", address)
+	}
+	writeError(w, http.StatusInternalServerError, codeInternal, "failed to subscribe")
+}
@@ -0,0 +1,178 @@
+package httpapi_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"txparser"
+	"txparser/httpapi"
+)
+
+const (
+	acmeKey   = "acme-key"
+	globexKey = "globex-key"
+)
+
+func Test_Server_AuthRequiresAPIKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		setKey     func(r *http.Request)
+		wantStatus int
+	}{
+		{
+			name:       "missing key",
+			setKey:     func(r *http.Request) {},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "unknown key",
+			setKey: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer unknown")
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "bearer token",
+			setKey: func(r *http.Request) {
+				r.Header.Set("Authorization", "Bearer "+acmeKey)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "header",
+			setKey: func(r *http.Request) {
+				r.Header.Set("X-API-Key", acmeKey)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "parameter",
+			setKey: func(r *http.Request) {
+				r.URL.RawQuery = "apiKey=" + acmeKey
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			server := newAuthTestServer(t)
+			request := httptest.NewRequest(http.MethodGet, "/currentBlock", nil)
+			test.setKey(request)
+			response := httptest.NewRecorder()
+
+			// Act
+			server.Handler().ServeHTTP(response, request)
+
+			// Assert
+			assertStatus(t, response, test.wantStatus)
+			if test.wantStatus == http.StatusUnauthorized {
+				assertErrorCode(t, response, "unauthorized")
+				if response.Header().Get("WWW-Authenticate") == "" {
+					t.Error("WWW-Authenticate header should be set")
+				}
+			}
+		})
+	}
+}
+
+func Test_Server_AuthIsolatesTenants(t *testing.T) {
+	// Arrange
+	server := newAuthTestServer(t)
+
+	// Act
+	subscribed := serveWithKey(server, http.MethodPost, "/subscribe", `{"address": "`+address+`"}`, acmeKey)
+	own := serveWithKey(server, http.MethodGet, "/transactions?address="+address, "", acmeKey)
+	foreign := serveWithKey(server, http.MethodGet, "/transactions?address="+address, "", globexKey)
+	foreignEvents := serveWithKey(server, http.MethodGet, "/events?address="+address, "", globexKey)
+
+	// Assert
+	assertStatus(t, subscribed, http.StatusOK)
+	assertStatus(t, own, http.StatusOK)
+	var page txparser.TransactionsPage
+	decode(t, own, &page)
+	if len(page.Transactions) != 3 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 3, len(page.Transactions))
+	}
+	assertStatus(t, foreign, http.StatusNotFound)
+	assertErrorCode(t, foreign, "not_found")
+	assertStatus(t, foreignEvents, http.StatusNotFound)
+	assertErrorCode(t, foreignEvents, "not_found")
+}
+
+func Test_Server_AuthSubscriptionQuota(t *testing.T) {
+	// Arrange
+	server := newAuthTestServer(t)
+	_ = serveWithKey(server, http.MethodPost, "/subscribe", `{"address": "`+address+`"}`, acmeKey)
+
+	// Act
+	resubscribed := serveWithKey(server, http.MethodPost, "/subscribe", `{"address": "`+address+`"}`, acmeKey)
+	exceeded := serveWithKey(server, http.MethodPost, "/subscribe", `{"address": "`+otherAddress+`"}`, acmeKey)
+	otherTenant := serveWithKey(server, http.MethodPost, "/subscribe", `{"address": "`+otherAddress+`"}`, globexKey)
+
+	// Assert
+	assertStatus(t, resubscribed, http.StatusOK)
+	assertStatus(t, exceeded, http.StatusForbidden)
+	assertErrorCode(t, exceeded, "quota_exceeded")
+	assertStatus(t, otherTenant, http.StatusOK)
+}
+
+func Test_Server_AuthLogsStorageError(t *testing.T) {
+	// Arrange
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, nil))
+	server, _ := newTestServer(t,
+		httpapi.WithAuth(failingAPIKeyStorage{}, txparser.NewInmemoryTenantSubscriptionsStorage()),
+		httpapi.WithLogger(logger),
+	)
+
+	// Act
+	response := serveWithKey(server, http.MethodGet, "/currentBlock", "", acmeKey)
+
+	// Assert
+	assertStatus(t, response, http.StatusInternalServerError)
+	want := `level=ERROR msg=authenticate error="storage is down"`
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("log should contain %q, but is %q", want, out.String())
+	}
+}
+
+// failingAPIKeyStorage fails to look up keys.
+type failingAPIKeyStorage struct {
+	txparser.APIKeyStorage
+}
+
+func (failingAPIKeyStorage) GetTenantByAPIKey(_ context.Context, _ string) (*txparser.Tenant, error) {
+	return nil, errors.New("storage is down")
+}
+
+// newAuthTestServer returns a server of tenants acme, limited to one subscription, and globex.
+func newAuthTestServer(t *testing.T, opts ...httpapi.Option) *httpapi.Server {
+	t.Helper()
+
+	ctx := context.Background()
+	apiKeys := txparser.NewInmemoryAPIKeyStorage()
+	_ = apiKeys.PutAPIKey(ctx, txparser.HashAPIKey(acmeKey), txparser.Tenant{ID: "acme", MaxSubscriptions: 1})
+	_ = apiKeys.PutAPIKey(ctx, txparser.HashAPIKey(globexKey), txparser.Tenant{ID: "globex"})
+
+	opts = append(opts, httpapi.WithAuth(apiKeys, txparser.NewInmemoryTenantSubscriptionsStorage()))
+	server, _ := newTestServer(t, opts...)
+
+	return server
+}
+
+func serveWithKey(server *httpapi.Server, method, target, body, key string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer "+key)
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	return response
+}
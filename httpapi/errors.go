@@ -0,0 +1,44 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	codeInvalidRequest   = "invalid_request"
+	codeInvalidAddress   = "invalid_address"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeInternal         = "internal_error"
+	codeUnauthorized     = "unauthorized"
+	codeQuotaExceeded    = "quota_exceeded"
+	codeRateLimited      = "rate_limited"
+)
+
+// ErrorResponse is the envelope of every error returned by the API.
+type ErrorResponse struct {
+	Error Error `json:"error"`
+}
+
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	// Values are encodable, so encoding fails only if the client is gone
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{
+		Error: Error{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
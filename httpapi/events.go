@@ -0,0 +1,155 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"txparser"
+)
+
+const (
+	maxStreamAddresses = 100
+
+	// streamBuffer is the number of events a stream may fall behind the parser before it is dropped.
+	streamBuffer = 1024
+
+	// streamRetry is the reconnection delay advised to clients, in milliseconds.
+	streamRetry = 3000
+)
+
+// handleEvents streams transactions of the addresses as Server-Sent Events.
+// Addresses are passed as repeated or comma separated address parameters.
+// A reconnecting client resumes after the Last-Event-ID header or lastEventId parameter.
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	addresses, err := parseAddresses(r.URL.Query()["address"])
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeFor(err), err.Error())
+		return
+	}
+
+	for _, address := range addresses {
+		allowed, err := s.canRead(r.Context(), address)
+		if err != nil {
+			s.logError(r.Context(), "check subscription", err, "address", address)
+			writeError(w, http.StatusInternalServerError, codeInternal, "failed to check subscription")
+			return
+		}
+		if !allowed {
+			writeError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("address %s is not subscribed", address))
+			return
+		}
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, codeInternal, "streaming is not supported")
+		return
+	}
+
+	// The stream is closed once the client disconnects or the server shuts down
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.closing:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	events, err := s.parser.Stream(ctx, addresses, lastEventID, streamBuffer)
+	if errors.Is(err, txparser.ErrInvalidEventID) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, fmt.Sprintf("%v %q", err, lastEventID))
+		return
+	}
+	if err != nil {
+		s.logError(r.Context(), "stream events", err, "last_event_id", lastEventID)
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to stream events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = fmt.Fprintf(w, "retry: %d\n\n", streamRetry)
+	if err != nil {
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := s.clock.NewTimer(s.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C():
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+			heartbeat.Reset(s.heartbeatInterval)
+		case event, ok := <-events:
+			if !ok {
+				// The stream fell behind, the client reconnects and resumes
+				return
+			}
+			err = writeEvent(w, event)
+		}
+
+		if err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event txparser.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %s\nevent: transaction\ndata: %s\n\n", event.ID, data)
+
+	return err
+}
+
+func parseAddresses(values []string) ([]string, error) {
+	var addresses []string
+	seen := make(map[string]struct{})
+
+	for _, value := range values {
+		for _, raw := range strings.Split(value, ",") {
+			address, err := normalizeAddress(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, err
+			}
+
+			if _, ok := seen[address]; ok {
+				continue
+			}
+			seen[address] = struct{}{}
+			addresses = append(addresses, address)
+		}
+	}
+
+	if len(addresses) == 0 {
+		return nil, fmt.Errorf("%w: at least one address is required", errInvalidAddress)
+	}
+	if len(addresses) > maxStreamAddresses {
+		return nil, fmt.Errorf("too many addresses, at most %d are allowed", maxStreamAddresses)
+	}
+
+	return addresses, nil
+}
@@ -0,0 +1,210 @@
+package httpapi_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/httpapi"
+	"txparser/txparsertest"
+)
+
+func Test_Server_Events(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &stubClient{
+		head: 10,
+		blocks: map[int][]txparser.Transaction{
+			11: {
+				{BlockNumber: "0xb", TransactionIndex: "0x0", Hash: "0xb0", From: otherAddress, To: otherAddress},
+				{BlockNumber: "0xb", TransactionIndex: "0x1", Hash: "0xb1", From: otherAddress, To: address},
+			},
+		},
+	}
+	clock := txparsertest.NewFakeClock(time.Now())
+	server, _, parser := newTestServerWithClient(t, client, httpapi.WithClock(clock), httpapi.WithHeartbeatInterval(time.Minute))
+	go func() {
+		_ = parser.RunWorker(ctx, 10*time.Millisecond)
+	}()
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	request, _ := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/events?address="+address, nil)
+	request.Header.Set("Last-Event-ID", "1-0")
+
+	// Act
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer response.Body.Close()
+	stream := newEventReader(response)
+	replayed := []sseEvent{stream.next(t), stream.next(t)}
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	stream.heartbeat(t)
+	client.setHead(11)
+	live := stream.next(t)
+
+	// Assert
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("status should be %d, but is %d", http.StatusOK, response.StatusCode)
+	}
+	if response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Errorf("content type should be %q, but is %q", "text/event-stream", response.Header.Get("Content-Type"))
+	}
+	for i, want := range []struct {
+		id   string
+		hash string
+	}{
+		{id: "2-0", hash: "0xa2"},
+		{id: "3-0", hash: "0xa3"},
+		{id: "11-1", hash: "0xb1"},
+	} {
+		event := append(replayed, live)[i]
+		if event.id != want.id || event.name != "transaction" || event.data.Transaction.Hash != want.hash {
+			t.Errorf("event %d should be %s with %s, but is %+v", i, want.id, want.hash, event)
+		}
+		if event.data.Address != address {
+			t.Errorf("event %d address should be %s, but is %s", i, address, event.data.Address)
+		}
+	}
+}
+
+func Test_Server_EventsInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		lastEventID string
+		wantCode    string
+	}{
+		{name: "missing address", query: "", wantCode: "invalid_address"},
+		{name: "invalid address", query: "?address=" + address + ",0x123", wantCode: "invalid_address"},
+		{name: "invalid last event id", query: "?address=" + address, lastEventID: "abc", wantCode: "invalid_request"},
+		{name: "invalid last event id parameter", query: "?address=" + address + "&lastEventId=1", wantCode: "invalid_request"},
+		{name: "too many addresses", query: "?address=" + manyAddresses(101), wantCode: "invalid_request"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			server, _ := newTestServer(t)
+			request := httptest.NewRequest(http.MethodGet, "/events"+test.query, nil)
+			request.Header.Set("Last-Event-ID", test.lastEventID)
+			response := httptest.NewRecorder()
+
+			// Act
+			server.Handler().ServeHTTP(response, request)
+
+			// Assert
+			assertStatus(t, response, http.StatusBadRequest)
+			assertErrorCode(t, response, test.wantCode)
+		})
+	}
+}
+
+func Test_Server_ServeClosesEvents(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	server, _, _ := newTestServerWithClient(t, &stubClient{head: 10}, httpapi.WithShutdownTimeout(time.Minute))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, listener, time.Hour)
+	}()
+	response, err := http.Get("http://" + listener.Addr().String() + "/events?address=" + address)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	defer response.Body.Close()
+
+	// Act
+	cancel()
+
+	// Assert
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("server should close event streams on shutdown")
+	}
+}
+
+func manyAddresses(n int) string {
+	addresses := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		addresses = append(addresses, fmt.Sprintf("0x%040x", i))
+	}
+
+	return strings.Join(addresses, ",")
+}
+
+type sseEvent struct {
+	id   string
+	name string
+	data txparser.Event
+}
+
+type eventReader struct {
+	scanner *bufio.Scanner
+}
+
+func newEventReader(response *http.Response) *eventReader {
+	return &eventReader{scanner: bufio.NewScanner(response.Body)}
+}
+
+// heartbeat reads the stream until a heartbeat.
+func (r *eventReader) heartbeat(t *testing.T) {
+	t.Helper()
+
+	for r.scanner.Scan() {
+		if r.scanner.Text() == ": heartbeat" {
+			return
+		}
+	}
+
+	t.Fatalf("stream ended: %v", r.scanner.Err())
+}
+
+// next reads the next event skipping heartbeats and other fields.
+func (r *eventReader) next(t *testing.T) sseEvent {
+	t.Helper()
+
+	var event sseEvent
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		switch {
+		case line == "" && event.id != "":
+			return event
+		case strings.HasPrefix(line, "id: "):
+			event.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	t.Fatalf("stream ended: %v", r.scanner.Err())
+
+	return event
+}
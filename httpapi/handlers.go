@@ -0,0 +1,183 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"txparser"
+)
+
+const maxBodySize = 1 << 20
+
+var addressRegexp = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+
+type SubscribeRequest struct {
+	Address string `json:"address"`
+}
+
+// routes registers the API endpoints, every route accepts only its own method.
+// Endpoints are described by OpenAPI, keep it in sync.
+func (s *Server) routes() {
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, codeNotFound, "endpoint not found")
+	})
+	s.handle("/currentBlock", http.MethodGet, s.handleCurrentBlock)
+	s.handle("/subscribe", http.MethodPost, s.handleSubscribe)
+	s.handle("/transactions", http.MethodGet, s.handleTransactions)
+	s.handle("/events", http.MethodGet, s.handleEvents)
+	s.handle("/ws", http.MethodGet, s.handleWebSocket)
+	s.handle("/rpc", http.MethodPost, s.handleJSONRPC)
+	s.mux.Handle(OpenAPIPath, allowMethod(http.MethodGet, s.handleOpenAPI()))
+	s.mux.Handle(HealthPath, allowMethod(http.MethodGet, s.handleHealth))
+	s.mux.Handle(ReadinessPath, allowMethod(http.MethodGet, s.handleReadiness))
+	if s.metrics != nil {
+		s.mux.Handle(MetricsPath, allowMethod(http.MethodGet, s.metrics.ServeHTTP))
+	}
+}
+
+// handle registers the route authenticating, then rate limiting requests.
+// Failed authentications are rate limited by IP addresses before API keys are looked up.
+func (s *Server) handle(route, method string, handler http.HandlerFunc) {
+	s.mux.Handle(route, s.authenticate(route, s.limit(route, allowMethod(method, handler))))
+}
+
+func allowMethod(method string, handler http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method && !(method == http.MethodGet && r.Method == http.MethodHead) {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method not allowed, use "+method)
+			return
+		}
+
+		handler(w, r)
+	})
+}
+
+func (s *Server) handleCurrentBlock(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.parser.GetCurrentBlock())
+}
+
+func (s *Server) handleSubscribe(w http.ResponseWriter, r *http.Request) {
+	var in SubscribeRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&in)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "invalid request body: "+err.Error())
+		return
+	}
+
+	address, err := normalizeAddress(in.Address)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidAddress, err.Error())
+		return
+	}
+
+	err = s.subscribe(r.Context(), address)
+	if err != nil {
+		s.writeSubscribeError(w, r, address, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, true)
+}
+
+func (s *Server) handleTransactions(w http.ResponseWriter, r *http.Request) {
+	query, err := parseTransactionsQuery(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, codeFor(err), err.Error())
+		return
+	}
+
+	allowed, err := s.canRead(r.Context(), query.Address)
+	if err != nil {
+		s.logError(r.Context(), "check subscription", err, "address", query.Address)
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to check subscription")
+		return
+	}
+	if !allowed {
+		writeError(w, http.StatusNotFound, codeNotFound, "address is not subscribed")
+		return
+	}
+
+	page, err := s.parser.QueryTransactions(r.Context(), query)
+	if errors.Is(err, txparser.ErrInvalidQuery) || errors.Is(err, txparser.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, err.Error())
+		return
+	}
+	if err != nil {
+		s.logError(r.Context(), "query transactions", err, "address", query.Address)
+		writeError(w, http.StatusInternalServerError, codeInternal, "failed to get transactions")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, page)
+}
+
+var errInvalidAddress = errors.New("invalid address")
+
+// normalizeAddress validates the address and converts it to the lower case used by nodes.
+func normalizeAddress(address string) (string, error) {
+	if !addressRegexp.MatchString(address) {
+		return "", fmt.Errorf("%w %q, expected 0x followed by 40 hex digits", errInvalidAddress, address)
+	}
+
+	return strings.ToLower(address), nil
+}
+
+func codeFor(err error) string {
+	if errors.Is(err, errInvalidAddress) {
+		return codeInvalidAddress
+	}
+
+	return codeInvalidRequest
+}
+
+// parseTransactionsQuery reads the transactions query from URL parameters:
+// address, cursor, limit, fromBlock, toBlock, direction, minValue and order.
+func parseTransactionsQuery(values url.Values) (txparser.TransactionsQuery, error) {
+	address, err := normalizeAddress(values.Get("address"))
+	if err != nil {
+		return txparser.TransactionsQuery{}, err
+	}
+
+	query := txparser.TransactionsQuery{
+		Address:   address,
+		Cursor:    values.Get("cursor"),
+		Direction: txparser.Direction(values.Get("direction")),
+		Order:     txparser.SortOrder(values.Get("order")),
+	}
+
+	for name, target := range map[string]*int{
+		"limit":     &query.Limit,
+		"fromBlock": &query.FromBlock,
+		"toBlock":   &query.ToBlock,
+	} {
+		if values.Get(name) == "" {
+			continue
+		}
+
+		*target, err = strconv.Atoi(values.Get(name))
+		if err != nil || *target < 0 {
+			return txparser.TransactionsQuery{}, fmt.Errorf("invalid %s %q", name, values.Get(name))
+		}
+	}
+
+	if v := values.Get("minValue"); v != "" {
+		minValue, ok := new(big.Int).SetString(v, 0)
+		if !ok || minValue.Sign() < 0 {
+			return txparser.TransactionsQuery{}, fmt.Errorf("invalid minValue %q", v)
+		}
+		query.MinValue = minValue
+	}
+
+	return query, nil
+}
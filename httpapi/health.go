@@ -0,0 +1,105 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"txparser"
+)
+
+// Paths of the health endpoints, the endpoints are public.
+const (
+	HealthPath    = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+const (
+	defaultMaxTickAge = time.Minute
+	defaultMaxLag     = 100
+)
+
+// HealthThresholds define when the parser is unhealthy or not ready.
+type HealthThresholds struct {
+	// MaxTickAge is how long the running worker may make no progress before /healthz fails,
+	// it should exceed the poll period.
+	MaxTickAge time.Duration
+
+	// MaxLag is how many blocks the worker may fall behind the head of the node before /readyz fails.
+	MaxLag int
+}
+
+// WithHealthThresholds overrides thresholds of the health endpoints, a minute and 100 blocks by default.
+// Zero values keep the defaults.
+func WithHealthThresholds(thresholds HealthThresholds) Option {
+	return func(s *Server) {
+		if thresholds.MaxTickAge > 0 {
+			s.health.MaxTickAge = thresholds.MaxTickAge
+		}
+		if thresholds.MaxLag > 0 {
+			s.health.MaxLag = thresholds.MaxLag
+		}
+	}
+}
+
+// HealthResponse is the body of the health endpoints, Status is "ok" or "fail".
+type HealthResponse struct {
+	Status  string          `json:"status"`
+	Reasons []string        `json:"reasons,omitempty"`
+	Parser  txparser.Status `json:"parser"`
+}
+
+// handleHealth responds 503 if the worker is not running or has made no progress for MaxTickAge,
+// a paused worker is healthy.
+func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
+	status := s.parser.Status()
+	writeHealth(w, status, s.liveness(status, s.clock.Now()))
+}
+
+// handleReadiness responds 503 if the parser is unhealthy, paused, has not finished a pass yet
+// or lags behind the head of the node by more than MaxLag blocks.
+func (s *Server) handleReadiness(w http.ResponseWriter, _ *http.Request) {
+	status := s.parser.Status()
+	reasons := s.liveness(status, s.clock.Now())
+	if status.State == txparser.WorkerPaused {
+		reasons = append(reasons, "worker is paused")
+	}
+	if status.LastTickAt.IsZero() {
+		reasons = append(reasons, "worker has not parsed blocks yet")
+	}
+	if status.Lag > s.health.MaxLag {
+		reasons = append(reasons, fmt.Sprintf("lag of %d blocks exceeds %d", status.Lag, s.health.MaxLag))
+	}
+
+	writeHealth(w, status, reasons)
+}
+
+// liveness returns reasons of the parser being unhealthy.
+func (s *Server) liveness(status txparser.Status, now time.Time) []string {
+	if status.State == txparser.WorkerPaused {
+		return nil
+	}
+	if status.State != txparser.WorkerRunning {
+		return []string{fmt.Sprintf("worker is %s", status.State)}
+	}
+
+	// A worker is given MaxTickAge from the start to make progress
+	lastProgress := status.LastTickAt
+	if lastProgress.IsZero() {
+		lastProgress = status.StartedAt
+	}
+	if age := now.Sub(lastProgress); age > s.health.MaxTickAge {
+		return []string{fmt.Sprintf("worker has made no progress for %s", age.Round(time.Second))}
+	}
+
+	return nil
+}
+
+func writeHealth(w http.ResponseWriter, status txparser.Status, reasons []string) {
+	if len(reasons) > 0 {
+		writeJSON(w, http.StatusServiceUnavailable, HealthResponse{Status: "fail", Reasons: reasons, Parser: status})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, HealthResponse{Status: "ok", Parser: status})
+}
@@ -0,0 +1,125 @@
+package httpapi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/httpapi"
+	"txparser/txparsertest"
+)
+
+func Test_Server_Health(t *testing.T) {
+	tests := []struct {
+		name          string
+		client        txparser.Client
+		thresholds    httpapi.HealthThresholds
+		noWorker      bool
+		pause         bool
+		wantHealth    int
+		wantReadiness int
+		wantReasons   []string
+	}{
+		{
+			name:          "caught up",
+			client:        &stubClient{head: 10},
+			wantHealth:    http.StatusOK,
+			wantReadiness: http.StatusOK,
+		},
+		{
+			name:          "lagging",
+			client:        brokenBlocksClient{head: 200},
+			wantHealth:    http.StatusOK,
+			wantReadiness: http.StatusServiceUnavailable,
+			wantReasons:   []string{"worker has not parsed blocks yet", "lag of 190 blocks exceeds 100"},
+		},
+		{
+			name:          "stuck",
+			client:        brokenBlocksClient{head: 11},
+			thresholds:    httpapi.HealthThresholds{MaxTickAge: time.Millisecond, MaxLag: 5},
+			wantHealth:    http.StatusServiceUnavailable,
+			wantReadiness: http.StatusServiceUnavailable,
+		},
+		{
+			name:          "paused",
+			client:        &stubClient{head: 10},
+			pause:         true,
+			wantHealth:    http.StatusOK,
+			wantReadiness: http.StatusServiceUnavailable,
+			wantReasons:   []string{"worker is paused"},
+		},
+		{
+			name:          "idle",
+			client:        &stubClient{head: 10},
+			noWorker:      true,
+			wantHealth:    http.StatusServiceUnavailable,
+			wantReadiness: http.StatusServiceUnavailable,
+			wantReasons:   []string{"worker is idle", "worker has not parsed blocks yet"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			clock := txparsertest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+			server, _, parser := newTestServerWithClock(
+				t,
+				test.client,
+				clock,
+				httpapi.WithHealthThresholds(test.thresholds),
+			)
+			if !test.noWorker {
+				runWorker(t, parser, clock)
+				clock.Advance(2 * time.Millisecond) // exceeds the shortest MaxTickAge
+			}
+			if test.pause {
+				parser.Pause()
+			}
+
+			// Act
+			health := serve(server, http.MethodGet, httpapi.HealthPath, "")
+			readiness := serve(server, http.MethodGet, httpapi.ReadinessPath, "")
+
+			// Assert
+			assertStatus(t, health, test.wantHealth)
+			assertStatus(t, readiness, test.wantReadiness)
+			var body httpapi.HealthResponse
+			decode(t, readiness, &body)
+			if test.wantReasons != nil && !reflect.DeepEqual(body.Reasons, test.wantReasons) {
+				t.Errorf("reasons should be %q, but are %q", test.wantReasons, body.Reasons)
+			}
+			if body.Parser.Lag != body.Parser.Head-body.Parser.LastBlock {
+				t.Errorf("lag should be %d, but is %d", body.Parser.Head-body.Parser.LastBlock, body.Parser.Lag)
+			}
+		})
+	}
+}
+
+// runWorker runs the worker of the parser until it waits for the clock after the first pass.
+func runWorker(t *testing.T, parser *txparser.TXParser, clock *txparsertest.FakeClock) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go func() {
+		_ = parser.RunWorker(ctx, time.Hour)
+	}()
+	clock.BlockUntil(1)
+}
+
+// brokenBlocksClient fails to return blocks.
+type brokenBlocksClient struct {
+	head int
+}
+
+func (c brokenBlocksClient) CurrentBlockNumber(_ context.Context) (int, error) {
+	return c.head, nil
+}
+
+func (c brokenBlocksClient) GetBlockByNumber(_ context.Context, _ int) (*txparser.Block, error) {
+	return nil, errors.New("block not found")
+}
@@ -0,0 +1,277 @@
+package httpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+
+	"txparser"
+)
+
+const maxBatchSize = 100
+
+// Application JSON-RPC error codes, in the range reserved for servers.
+const (
+	JSONRPCNotFound      = -32001
+	JSONRPCQuotaExceeded = -32002
+)
+
+// RPCTransactionsOptions is the optional second parameter of txparser_getTransactions.
+type RPCTransactionsOptions struct {
+	Cursor    string `json:"cursor,omitempty"`
+	Limit     int    `json:"limit,omitempty"`
+	FromBlock int    `json:"fromBlock,omitempty"`
+	ToBlock   int    `json:"toBlock,omitempty"`
+	Direction string `json:"direction,omitempty"`
+	MinValue  string `json:"minValue,omitempty"`
+	Order     string `json:"order,omitempty"`
+}
+
+type rpcMethod func(ctx context.Context, params []any) (any, *txparser.JSONRPCError)
+
+func (s *Server) rpcMethods() map[string]rpcMethod {
+	return map[string]rpcMethod{
+		"txparser_getCurrentBlock": s.rpcGetCurrentBlock,
+		"txparser_subscribe":       s.rpcSubscribe,
+		"txparser_getTransactions": s.rpcGetTransactions,
+	}
+}
+
+// handleJSONRPC serves JSON-RPC 2.0 calls and batches of them. Params are positional.
+// Notifications, calls without an ID, are executed without a response.
+func (s *Server) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		response := rpcErrorResponse(nil, txparser.JSONRPCInvalidRequest, "failed to read request: "+err.Error())
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		response, ok := s.rpcCall(r.Context(), body)
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+
+	var batch []json.RawMessage
+	err = json.Unmarshal(body, &batch)
+	if err != nil {
+		writeJSON(w, http.StatusOK, rpcErrorResponse(nil, txparser.JSONRPCParseError, "parse error: "+err.Error()))
+		return
+	}
+	if len(batch) == 0 {
+		writeJSON(w, http.StatusOK, rpcErrorResponse(nil, txparser.JSONRPCInvalidRequest, "empty batch"))
+		return
+	}
+	if len(batch) > maxBatchSize {
+		writeJSON(w, http.StatusOK, rpcErrorResponse(
+			nil,
+			txparser.JSONRPCInvalidRequest,
+			fmt.Sprintf("batch is too large, at most %d calls are allowed", maxBatchSize),
+		))
+		return
+	}
+
+	responses := make([]txparser.JSONRPCResponse, 0, len(batch))
+	for _, raw := range batch {
+		response, ok := s.rpcCall(r.Context(), raw)
+		if ok {
+			responses = append(responses, response)
+		}
+	}
+
+	if len(responses) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// rpcCall executes a single call, it returns false for notifications.
+func (s *Server) rpcCall(ctx context.Context, raw json.RawMessage) (txparser.JSONRPCResponse, bool) {
+	if !json.Valid(raw) {
+		return rpcErrorResponse(nil, txparser.JSONRPCParseError, "parse error"), true
+	}
+
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(raw, &fields)
+	if err != nil {
+		return rpcErrorResponse(nil, txparser.JSONRPCInvalidRequest, "call should be an object"), true
+	}
+
+	var call txparser.JSONRPCCall
+	err = json.Unmarshal(raw, &call)
+	if err != nil || !isValidRPCID(call.ID) {
+		return rpcErrorResponse(nil, txparser.JSONRPCInvalidRequest, "invalid request"), true
+	}
+
+	_, hasID := fields["id"]
+	if call.Jsonrpc != "2.0" || call.Method == "" {
+		message := `invalid request, jsonrpc should be "2.0" and method is required`
+		return rpcErrorResponse(call.ID, txparser.JSONRPCInvalidRequest, message), true
+	}
+
+	method, ok := s.rpcMethods()[call.Method]
+	if !ok {
+		return rpcErrorResponse(call.ID, txparser.JSONRPCMethodNotFound, "method not found: "+call.Method), hasID
+	}
+
+	result, rpcErr := method(ctx, call.Params)
+	if rpcErr != nil {
+		return txparser.JSONRPCResponse{Jsonrpc: "2.0", Error: rpcErr, ID: call.ID}, hasID
+	}
+
+	return txparser.JSONRPCResponse{Jsonrpc: "2.0", Result: result, ID: call.ID}, hasID
+}
+
+// isValidRPCID reports whether the ID is a string, a number or null.
+func isValidRPCID(id any) bool {
+	switch id.(type) {
+	case nil, string, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func rpcErrorResponse(id any, code int, message string) txparser.JSONRPCResponse {
+	return txparser.JSONRPCResponse{
+		Jsonrpc: "2.0",
+		Error:   &txparser.JSONRPCError{Code: code, Message: message},
+		ID:      id,
+	}
+}
+
+func rpcError(code int, message string) *txparser.JSONRPCError {
+	return &txparser.JSONRPCError{Code: code, Message: message}
+}
+
+// rpcGetCurrentBlock returns the last parsed block number as a hex quantity, like eth_blockNumber.
+func (s *Server) rpcGetCurrentBlock(_ context.Context, params []any) (any, *txparser.JSONRPCError) {
+	if len(params) != 0 {
+		return nil, rpcError(txparser.JSONRPCInvalidParams, "no params expected")
+	}
+
+	return fmt.Sprintf("0x%x", s.parser.GetCurrentBlock()), nil
+}
+
+// rpcSubscribe takes the address and returns true.
+func (s *Server) rpcSubscribe(ctx context.Context, params []any) (any, *txparser.JSONRPCError) {
+	if len(params) != 1 {
+		return nil, rpcError(txparser.JSONRPCInvalidParams, "expected params [address]")
+	}
+
+	address, rpcErr := rpcAddress(params[0])
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	err := s.subscribe(ctx, address)
+	if errors.Is(err, txparser.ErrSubscriptionQuotaExceeded) {
+		return nil, rpcError(JSONRPCQuotaExceeded, err.Error())
+	}
+	if err != nil {
+		if !errors.Is(err, errSubscribeFailed) {
+			s.logError(ctx, "subscribe", err, "address", address)
+		}
+		return nil, rpcError(txparser.JSONRPCInternalError, "failed to subscribe")
+	}
+
+	return true, nil
+}
+
+// rpcGetTransactions takes the address and optionally RPCTransactionsOptions and returns a page of transactions.
+func (s *Server) rpcGetTransactions(ctx context.Context, params []any) (any, *txparser.JSONRPCError) {
+	if len(params) != 1 && len(params) != 2 {
+		return nil, rpcError(txparser.JSONRPCInvalidParams, "expected params [address, options]")
+	}
+
+	address, rpcErr := rpcAddress(params[0])
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var options RPCTransactionsOptions
+	if len(params) == 2 && params[1] != nil {
+		err := remarshal(params[1], &options)
+		if err != nil {
+			return nil, rpcError(txparser.JSONRPCInvalidParams, "invalid options: "+err.Error())
+		}
+	}
+
+	query := txparser.TransactionsQuery{
+		Address:   address,
+		Cursor:    options.Cursor,
+		Limit:     options.Limit,
+		FromBlock: options.FromBlock,
+		ToBlock:   options.ToBlock,
+		Direction: txparser.Direction(options.Direction),
+		Order:     txparser.SortOrder(options.Order),
+	}
+	if options.MinValue != "" {
+		minValue, ok := new(big.Int).SetString(options.MinValue, 0)
+		if !ok || minValue.Sign() < 0 {
+			return nil, rpcError(txparser.JSONRPCInvalidParams, fmt.Sprintf("invalid minValue %q", options.MinValue))
+		}
+		query.MinValue = minValue
+	}
+
+	allowed, err := s.canRead(ctx, address)
+	if err != nil {
+		s.logError(ctx, "check subscription", err, "address", address)
+		return nil, rpcError(txparser.JSONRPCInternalError, "failed to check subscription")
+	}
+	if !allowed {
+		return nil, rpcError(JSONRPCNotFound, "address is not subscribed")
+	}
+
+	page, err := s.parser.QueryTransactions(ctx, query)
+	if errors.Is(err, txparser.ErrInvalidQuery) || errors.Is(err, txparser.ErrInvalidCursor) {
+		return nil, rpcError(txparser.JSONRPCInvalidParams, err.Error())
+	}
+	if err != nil {
+		s.logError(ctx, "query transactions", err, "address", query.Address)
+		return nil, rpcError(txparser.JSONRPCInternalError, "failed to get transactions")
+	}
+
+	return page, nil
+}
+
+func rpcAddress(param any) (string, *txparser.JSONRPCError) {
+	raw, ok := param.(string)
+	if !ok {
+		return "", rpcError(txparser.JSONRPCInvalidParams, "address should be a string")
+	}
+
+	address, err := normalizeAddress(raw)
+	if err != nil {
+		return "", rpcError(txparser.JSONRPCInvalidParams, err.Error())
+	}
+
+	return address, nil
+}
+
+// remarshal decodes a generic JSON value into v rejecting unknown fields.
+func remarshal(value any, v any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	return decoder.Decode(v)
+}
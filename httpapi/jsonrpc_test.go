@@ -0,0 +1,288 @@
+package httpapi_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"txparser"
+	"txparser/httpapi"
+)
+
+type rpcResponse struct {
+	Jsonrpc string                 `json:"jsonrpc"`
+	Result  json.RawMessage        `json:"result"`
+	Error   *txparser.JSONRPCError `json:"error"`
+	ID      any                    `json:"id"`
+}
+
+func Test_Server_JSONRPC(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantResult string
+		wantCode   int
+	}{
+		{
+			name:       "current block",
+			body:       `{"jsonrpc": "2.0", "method": "txparser_getCurrentBlock", "params": [], "id": 1}`,
+			wantResult: `"0xa"`,
+		},
+		{
+			name:     "subscribe invalid address",
+			body:     `{"jsonrpc": "2.0", "method": "txparser_subscribe", "params": ["0x123"], "id": 1}`,
+			wantCode: txparser.JSONRPCInvalidParams,
+		},
+		{
+			name:       "subscribe",
+			body:       `{"jsonrpc": "2.0", "method": "txparser_subscribe", "params": ["0x` + strings.ToUpper(otherAddress[2:]) + `"], "id": 1}`,
+			wantResult: `true`,
+		},
+		{
+			name:     "parse error",
+			body:     `{"jsonrpc": "2.0", "method"`,
+			wantCode: txparser.JSONRPCParseError,
+		},
+		{
+			name:     "not an object",
+			body:     `"txparser_getCurrentBlock"`,
+			wantCode: txparser.JSONRPCInvalidRequest,
+		},
+		{
+			name:     "invalid version",
+			body:     `{"jsonrpc": "1.0", "method": "txparser_getCurrentBlock", "id": 1}`,
+			wantCode: txparser.JSONRPCInvalidRequest,
+		},
+		{
+			name:     "invalid id",
+			body:     `{"jsonrpc": "2.0", "method": "txparser_getCurrentBlock", "id": {}}`,
+			wantCode: txparser.JSONRPCInvalidRequest,
+		},
+		{
+			name:     "unknown method",
+			body:     `{"jsonrpc": "2.0", "method": "eth_blockNumber", "id": 1}`,
+			wantCode: txparser.JSONRPCMethodNotFound,
+		},
+		{
+			name:     "missing params",
+			body:     `{"jsonrpc": "2.0", "method": "txparser_getTransactions", "params": [], "id": 1}`,
+			wantCode: txparser.JSONRPCInvalidParams,
+		},
+		{
+			name:     "invalid options",
+			body:     `{"jsonrpc": "2.0", "method": "txparser_getTransactions", "params": ["` + address + `", {"limit": -1}], "id": 1}`,
+			wantCode: txparser.JSONRPCInvalidParams,
+		},
+		{
+			name:     "unknown option",
+			body:     `{"jsonrpc": "2.0", "method": "txparser_getTransactions", "params": ["` + address + `", {"foo": 1}], "id": 1}`,
+			wantCode: txparser.JSONRPCInvalidParams,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			server, _ := newTestServer(t)
+
+			// Act
+			response := serve(server, http.MethodPost, "/rpc", test.body)
+
+			// Assert
+			assertStatus(t, response, http.StatusOK)
+			var r rpcResponse
+			decode(t, response, &r)
+			if r.Jsonrpc != "2.0" {
+				t.Errorf("jsonrpc should be %q, but is %q", "2.0", r.Jsonrpc)
+			}
+			if test.wantCode != 0 {
+				if r.Error == nil || r.Error.Code != test.wantCode {
+					t.Errorf("error code should be %d, but response is %s", test.wantCode, response.Body.String())
+				}
+				return
+			}
+			if r.Error != nil {
+				t.Errorf("error should be nil, but is %v", r.Error)
+			}
+			if string(r.Result) != test.wantResult {
+				t.Errorf("result should be %s, but is %s", test.wantResult, r.Result)
+			}
+			if r.ID != float64(1) {
+				t.Errorf("id should be %v, but is %v", 1, r.ID)
+			}
+		})
+	}
+}
+
+func Test_Server_JSONRPCGetTransactions(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+	body := `{"jsonrpc": "2.0", "method": "txparser_getTransactions", "params": ["` + address +
+		`", {"limit": 2, "minValue": "0x2", "order": "desc"}], "id": "page"}`
+
+	// Act
+	response := serve(server, http.MethodPost, "/rpc", body)
+
+	// Assert
+	assertStatus(t, response, http.StatusOK)
+	var r rpcResponse
+	decode(t, response, &r)
+	if r.Error != nil {
+		t.Error(r.Error)
+		t.FailNow()
+	}
+	if r.ID != "page" {
+		t.Errorf("id should be %q, but is %v", "page", r.ID)
+	}
+	var page txparser.TransactionsPage
+	err := json.Unmarshal(r.Result, &page)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(page.Transactions) != 2 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 2, len(page.Transactions))
+		t.FailNow()
+	}
+	if page.Transactions[0].Hash != "0xa3" || page.Transactions[1].Hash != "0xa2" {
+		t.Errorf("transactions should be %v, but are %v", []string{"0xa3", "0xa2"}, page.Transactions)
+	}
+}
+
+func Test_Server_JSONRPCBatch(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+	body := `[
+		{"jsonrpc": "2.0", "method": "txparser_getCurrentBlock", "id": 1},
+		{"jsonrpc": "2.0", "method": "txparser_subscribe", "params": ["` + otherAddress + `"]},
+		{"jsonrpc": "2.0", "method": "txparser_unknown", "id": 2},
+		1
+	]`
+
+	// Act
+	response := serve(server, http.MethodPost, "/rpc", body)
+
+	// Assert
+	assertStatus(t, response, http.StatusOK)
+	var responses []rpcResponse
+	decode(t, response, &responses)
+	if len(responses) != 3 {
+		t.Errorf("responses slice should have %d item(s), but has %d", 3, len(responses))
+		t.FailNow()
+	}
+	if string(responses[0].Result) != `"0xa"` || responses[0].ID != float64(1) {
+		t.Errorf("first response should be the current block, but is %+v", responses[0])
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != txparser.JSONRPCMethodNotFound || responses[1].ID != float64(2) {
+		t.Errorf("second response should be the method not found error, but is %+v", responses[1])
+	}
+	if responses[2].Error == nil || responses[2].Error.Code != txparser.JSONRPCInvalidRequest || responses[2].ID != nil {
+		t.Errorf("third response should be the invalid request error, but is %+v", responses[2])
+	}
+
+	// Assert notification is executed
+	page := serve(server, http.MethodGet, "/transactions?address="+otherAddress, "")
+	assertStatus(t, page, http.StatusOK)
+}
+
+func Test_Server_JSONRPCInvalidBatch(t *testing.T) {
+	calls := make([]string, 101)
+	for i := range calls {
+		calls[i] = fmt.Sprintf(`{"jsonrpc": "2.0", "method": "txparser_getCurrentBlock", "id": %d}`, i)
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{
+			name:     "empty batch",
+			body:     `[]`,
+			wantCode: txparser.JSONRPCInvalidRequest,
+		},
+		{
+			name:     "too large batch",
+			body:     "[" + strings.Join(calls, ",") + "]",
+			wantCode: txparser.JSONRPCInvalidRequest,
+		},
+		{
+			name:     "invalid json",
+			body:     `[{"jsonrpc": "2.0"`,
+			wantCode: txparser.JSONRPCParseError,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			server, _ := newTestServer(t)
+
+			// Act
+			response := serve(server, http.MethodPost, "/rpc", test.body)
+
+			// Assert
+			assertStatus(t, response, http.StatusOK)
+			var r rpcResponse
+			decode(t, response, &r)
+			if r.Error == nil || r.Error.Code != test.wantCode {
+				t.Errorf("error code should be %d, but response is %s", test.wantCode, response.Body.String())
+			}
+		})
+	}
+}
+
+func Test_Server_JSONRPCNotifications(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+	body := `[
+		{"jsonrpc": "2.0", "method": "txparser_getCurrentBlock"},
+		{"jsonrpc": "2.0", "method": "txparser_unknown"}
+	]`
+
+	// Act
+	batch := serve(server, http.MethodPost, "/rpc", body)
+	single := serve(server, http.MethodPost, "/rpc", `{"jsonrpc": "2.0", "method": "txparser_getCurrentBlock"}`)
+
+	// Assert
+	assertStatus(t, batch, http.StatusNoContent)
+	assertStatus(t, single, http.StatusNoContent)
+	if batch.Body.Len() != 0 || single.Body.Len() != 0 {
+		t.Error("notifications should not have a response body")
+	}
+}
+
+func Test_Server_JSONRPCTenants(t *testing.T) {
+	// Arrange
+	server := newAuthTestServer(t)
+	call := func(method, address string) string {
+		return `{"jsonrpc": "2.0", "method": "` + method + `", "params": ["` + address + `"], "id": 1}`
+	}
+	_ = serveWithKey(server, http.MethodPost, "/rpc", call("txparser_subscribe", address), acmeKey)
+
+	// Act
+	own := serveWithKey(server, http.MethodPost, "/rpc", call("txparser_getTransactions", address), acmeKey)
+	foreign := serveWithKey(server, http.MethodPost, "/rpc", call("txparser_getTransactions", address), globexKey)
+	exceeded := serveWithKey(server, http.MethodPost, "/rpc", call("txparser_subscribe", otherAddress), acmeKey)
+	unauthorized := serve(server, http.MethodPost, "/rpc", call("txparser_getCurrentBlock", ""))
+
+	// Assert
+	var r rpcResponse
+	decode(t, own, &r)
+	if r.Error != nil {
+		t.Errorf("error should be nil, but is %v", r.Error)
+	}
+	r = rpcResponse{}
+	decode(t, foreign, &r)
+	if r.Error == nil || r.Error.Code != httpapi.JSONRPCNotFound {
+		t.Errorf("error code should be %d, but response is %s", httpapi.JSONRPCNotFound, foreign.Body.String())
+	}
+	r = rpcResponse{}
+	decode(t, exceeded, &r)
+	if r.Error == nil || r.Error.Code != httpapi.JSONRPCQuotaExceeded {
+		t.Errorf("error code should be %d, but response is %s", httpapi.JSONRPCQuotaExceeded, exceeded.Body.String())
+	}
+	assertStatus(t, unauthorized, http.StatusUnauthorized)
+}
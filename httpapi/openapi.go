@@ -0,0 +1,368 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"txparser"
+)
+
+// OpenAPIPath is where the server serves its OpenAPI 3 document, the document is public.
+const OpenAPIPath = "/openapi.json"
+
+const schemasRef = "#/components/schemas/"
+
+// openAPIBuilder collects component schemas of the types referenced by the document.
+type openAPIBuilder struct {
+	schemas map[string]any
+}
+
+// OpenAPI returns the OpenAPI 3 document of the API, schemas are derived from the Go types.
+func (s *Server) OpenAPI() map[string]any {
+	b := &openAPIBuilder{schemas: make(map[string]any)}
+
+	errorResponses := func(statuses ...int) map[string]any {
+		responses := map[string]any{
+			"405": b.jsonResponse("Method not allowed", ErrorResponse{}),
+		}
+		if s.limiter.defaultLimit.enabled() || len(s.limiter.routes) > 0 {
+			responses["429"] = b.jsonResponse("Rate limit exceeded", ErrorResponse{})
+		}
+		if s.apiKeys != nil {
+			responses["401"] = b.jsonResponse("Missing or unknown API key", ErrorResponse{})
+		}
+		for _, status := range statuses {
+			responses[statusKey(status)] = b.jsonResponse(http.StatusText(status), ErrorResponse{})
+		}
+
+		return responses
+	}
+	with := func(responses map[string]any, status int, response any) map[string]any {
+		responses[statusKey(status)] = response
+		return responses
+	}
+
+	// Health endpoints are public, they are not authenticated nor rate limited
+	health := func(operationID, summary string) map[string]any {
+		return map[string]any{
+			"get": map[string]any{
+				"operationId": operationID,
+				"summary":     summary,
+				"security":    []any{},
+				"responses": map[string]any{
+					"200": b.jsonResponse("Parser status", HealthResponse{}),
+					"503": b.jsonResponse("Parser status with reasons of the failure", HealthResponse{}),
+					"405": b.jsonResponse("Method not allowed", ErrorResponse{}),
+				},
+			},
+		}
+	}
+
+	addressParameter := parameter("address", "query", "Address, 0x followed by 40 hex digits", true, addressSchema())
+
+	paths := map[string]any{
+		"/currentBlock": map[string]any{
+			"get": map[string]any{
+				"operationId": "getCurrentBlock",
+				"summary":     "Returns the last parsed block number",
+				"responses":   with(errorResponses(), http.StatusOK, b.jsonResponse("Block number", 0)),
+			},
+		},
+		"/subscribe": map[string]any{
+			"post": map[string]any{
+				"operationId": "subscribe",
+				"summary":     "Subscribes to transactions of an address",
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{"application/json": map[string]any{
+						"schema": b.schemaOf(reflect.TypeOf(SubscribeRequest{})),
+					}},
+				},
+				"responses": with(
+					errorResponses(http.StatusBadRequest, http.StatusForbidden, http.StatusInternalServerError),
+					http.StatusOK,
+					b.jsonResponse("Subscribed", true),
+				),
+			},
+		},
+		"/transactions": map[string]any{
+			"get": map[string]any{
+				"operationId": "getTransactions",
+				"summary":     "Returns a page of transactions of a subscribed address",
+				"parameters": []any{
+					addressParameter,
+					parameter("cursor", "query", "nextCursor of the previous page", false, map[string]any{"type": "string"}),
+					parameter("limit", "query", "Page size", false, map[string]any{
+						"type": "integer", "minimum": 1, "maximum": txparser.MaxQueryLimit,
+					}),
+					parameter("fromBlock", "query", "First block, inclusive", false, map[string]any{"type": "integer", "minimum": 0}),
+					parameter("toBlock", "query", "Last block, inclusive", false, map[string]any{"type": "integer", "minimum": 0}),
+					parameter("direction", "query", "Direction relative to the address", false, map[string]any{
+						"type": "string",
+						"enum": []any{txparser.DirectionIncoming, txparser.DirectionOutgoing, txparser.DirectionSelf},
+					}),
+					parameter("minValue", "query", "Minimum value in wei, decimal or 0x hex", false, map[string]any{"type": "string"}),
+					parameter("order", "query", "Sort order", false, map[string]any{
+						"type": "string",
+						"enum": []any{txparser.SortAscending, txparser.SortDescending},
+					}),
+				},
+				"responses": with(
+					errorResponses(http.StatusBadRequest, http.StatusNotFound, http.StatusInternalServerError),
+					http.StatusOK,
+					b.jsonResponse("Page of transactions", txparser.TransactionsPage{}),
+				),
+			},
+		},
+		"/events": map[string]any{
+			"get": map[string]any{
+				"operationId": "streamEvents",
+				"summary":     "Streams transactions of addresses as Server-Sent Events",
+				"description": "Every event data is a JSON encoded Event, event IDs resume the stream after reconnects.",
+				"parameters": []any{
+					parameter("address", "query", "Addresses, repeated or comma separated", true, map[string]any{
+						"type": "array", "items": addressSchema(), "maxItems": maxStreamAddresses,
+					}),
+					parameter("lastEventId", "query", "ID of the last received event", false, map[string]any{"type": "string"}),
+					parameter("Last-Event-ID", "header", "ID of the last received event", false, map[string]any{"type": "string"}),
+				},
+				"responses": with(
+					errorResponses(http.StatusBadRequest, http.StatusNotFound),
+					http.StatusOK,
+					map[string]any{
+						"description": "Event stream",
+						"content": map[string]any{
+							"text/event-stream": map[string]any{"schema": b.schemaOf(reflect.TypeOf(txparser.Event{}))},
+						},
+					},
+				),
+			},
+		},
+		"/ws": map[string]any{
+			"get": map[string]any{
+				"operationId": "pushEvents",
+				"summary":     "Pushes events over WebSocket",
+				"description": "Clients send PushRequest messages and receive PushResponse and Event messages.",
+				"responses": with(
+					errorResponses(http.StatusBadRequest),
+					http.StatusSwitchingProtocols,
+					map[string]any{"description": "WebSocket connection"},
+				),
+			},
+		},
+		HealthPath:    health("getHealth", "Reports whether the parser worker is running and making progress"),
+		ReadinessPath: health("getReadiness", "Reports whether the parser worker keeps up with the head of the node"),
+		"/rpc": map[string]any{
+			"post": map[string]any{
+				"operationId": "jsonRPC",
+				"summary":     "Serves txparser_getCurrentBlock, txparser_subscribe and txparser_getTransactions over JSON-RPC 2.0",
+				"requestBody": map[string]any{
+					"required": true,
+					"content": map[string]any{"application/json": map[string]any{
+						"schema": b.oneOrBatch(reflect.TypeOf(txparser.JSONRPCCall{})),
+					}},
+				},
+				"responses": with(
+					with(errorResponses(), http.StatusOK, map[string]any{
+						"description": "Responses to calls",
+						"content": map[string]any{"application/json": map[string]any{
+							"schema": b.oneOrBatch(reflect.TypeOf(txparser.JSONRPCResponse{})),
+						}},
+					}),
+					http.StatusNoContent,
+					map[string]any{"description": "Only notifications were received"},
+				),
+			},
+		},
+	}
+
+	// Metrics are public like health endpoints
+	if s.metrics != nil {
+		paths[MetricsPath] = map[string]any{
+			"get": map[string]any{
+				"operationId": "getMetrics",
+				"summary":     "Returns metrics in the Prometheus text format",
+				"security":    []any{},
+				"responses": map[string]any{
+					"200": map[string]any{
+						"description": "Metrics",
+						"content": map[string]any{"text/plain": map[string]any{
+							"schema": map[string]any{"type": "string"},
+						}},
+					},
+					"405": b.jsonResponse("Method not allowed", ErrorResponse{}),
+				},
+			},
+		}
+	}
+
+	// Messages of the WebSocket API are not referenced by paths.
+	b.schemaOf(reflect.TypeOf(PushRequest{}))
+	b.schemaOf(reflect.TypeOf(PushResponse{}))
+	b.schemaOf(reflect.TypeOf(txparser.Block{}))
+	b.schemaOf(reflect.TypeOf(RPCTransactionsOptions{}))
+
+	components := map[string]any{"schemas": b.schemas}
+	document := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "TX Parser API",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": components,
+	}
+
+	if s.apiKeys != nil {
+		components["securitySchemes"] = map[string]any{
+			"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+			"header": map[string]any{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+			"query":  map[string]any{"type": "apiKey", "in": "query", "name": "apiKey"},
+		}
+		document["security"] = []any{
+			map[string]any{"bearer": []any{}},
+			map[string]any{"header": []any{}},
+			map[string]any{"query": []any{}},
+		}
+	}
+
+	return document
+}
+
+// handleOpenAPI returns a handler serving the document encoded once.
+func (s *Server) handleOpenAPI() http.HandlerFunc {
+	document, err := json.Marshal(s.OpenAPI())
+	if err != nil {
+		// The document consists of maps, slices and strings only.
+		panic(err)
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		// The client is gone if the write fails
+		_, _ = w.Write(document)
+	}
+}
+
+func (b *openAPIBuilder) jsonResponse(description string, example any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": b.schemaOf(reflect.TypeOf(example))},
+		},
+	}
+}
+
+func (b *openAPIBuilder) oneOrBatch(t reflect.Type) map[string]any {
+	schema := b.schemaOf(t)
+
+	return map[string]any{
+		"oneOf": []any{
+			schema,
+			map[string]any{"type": "array", "items": schema, "minItems": 1, "maxItems": maxBatchSize},
+		},
+	}
+}
+
+// schemaOf returns the schema of values of the type encoded by encoding/json.
+// Structs are added to components and referenced.
+func (b *openAPIBuilder) schemaOf(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return b.schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.Struct:
+		return b.structSchema(t)
+	default:
+		// Interfaces hold any value.
+		return map[string]any{}
+	}
+}
+
+func (b *openAPIBuilder) structSchema(t reflect.Type) map[string]any {
+	ref := map[string]any{"$ref": schemasRef + t.Name()}
+	if _, ok := b.schemas[t.Name()]; ok {
+		return ref
+	}
+	// Reserve the name before fields for recursive types.
+	b.schemas[t.Name()] = nil
+
+	properties := make(map[string]any)
+	required := make([]any, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema := b.schemaOf(field.Type)
+		if field.Type.Kind() == reflect.Slice && !strings.Contains(options, "omitempty") {
+			// Nil slices are encoded as null.
+			schema["nullable"] = true
+		}
+		properties[name] = schema
+
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	b.schemas[t.Name()] = schema
+
+	return ref
+}
+
+func parameter(name, in, description string, required bool, schema map[string]any) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          in,
+		"description": description,
+		"required":    required,
+		"schema":      schema,
+	}
+}
+
+func addressSchema() map[string]any {
+	return map[string]any{"type": "string", "pattern": addressRegexp.String()}
+}
+
+func statusKey(status int) string {
+	return strconv.Itoa(status)
+}
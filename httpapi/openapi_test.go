@@ -0,0 +1,371 @@
+package httpapi_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"txparser"
+	"txparser/httpapi"
+)
+
+func Test_Server_OpenAPIDocument(t *testing.T) {
+	// Arrange
+	server := newAuthTestServer(t, httpapi.WithMetrics(txparser.NewMetrics()))
+
+	// Act
+	response := serve(server, http.MethodGet, httpapi.OpenAPIPath, "")
+
+	// Assert
+	assertStatus(t, response, http.StatusOK)
+	if response.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("content type should be %q, but is %q", "application/json", response.Header().Get("Content-Type"))
+	}
+	var document map[string]any
+	decode(t, response, &document)
+	if document["openapi"] != "3.0.3" {
+		t.Errorf("openapi version should be %q, but is %v", "3.0.3", document["openapi"])
+	}
+	paths := make([]string, 0)
+	for path := range document["paths"].(map[string]any) {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	wantPaths := []string{
+		"/currentBlock", "/events", "/healthz", "/metrics", "/readyz", "/rpc", "/subscribe", "/transactions", "/ws",
+	}
+	if strings.Join(paths, " ") != strings.Join(wantPaths, " ") {
+		t.Errorf("paths should be %v, but are %v", wantPaths, paths)
+	}
+	metrics, _ := document["paths"].(map[string]any)["/metrics"].(map[string]any)["get"].(map[string]any)
+	if security, ok := metrics["security"].([]any); !ok || len(security) != 0 {
+		t.Errorf("metrics should be public, but security is %v", metrics["security"])
+	}
+	success, _ := metrics["responses"].(map[string]any)["200"].(map[string]any)
+	if _, isText := success["content"].(map[string]any)["text/plain"]; !isText {
+		t.Errorf("metrics should be text/plain, but are %v", success["content"])
+	}
+	for _, ref := range refsOf(document) {
+		if resolveRef(document, ref) == nil {
+			t.Errorf("reference %q should be resolved", ref)
+		}
+	}
+	for _, name := range []string{"Transaction", "Block", "SubscribeRequest", "Event", "PushRequest"} {
+		if resolveRef(document, "#/components/schemas/"+name) == nil {
+			t.Errorf("schema %q should be defined", name)
+		}
+	}
+	if document["security"] == nil {
+		t.Error("security should be set when authentication is enabled")
+	}
+}
+
+func Test_Server_OpenAPIDescribesResponses(t *testing.T) {
+	// Arrange
+	server := newAuthTestServer(t, httpapi.WithRouteRateLimit("/currentBlock", httpapi.RateLimit{Rate: 0.001, Burst: 1}))
+	document := openAPIDocument(t, server)
+	rpc := func(method string, params string) string {
+		return `{"jsonrpc": "2.0", "method": "` + method + `", "params": ` + params + `, "id": 1}`
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		key        string
+		wantStatus int
+	}{
+		{
+			name:       "current block",
+			method:     http.MethodGet,
+			target:     "/currentBlock",
+			key:        acmeKey,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "rate limited",
+			method:     http.MethodGet,
+			target:     "/currentBlock",
+			key:        acmeKey,
+			wantStatus: http.StatusTooManyRequests,
+		},
+		{
+			name:       "unauthorized",
+			method:     http.MethodGet,
+			target:     "/currentBlock",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "method not allowed",
+			method:     http.MethodGet,
+			target:     "/subscribe",
+			key:        acmeKey,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "subscribe",
+			method:     http.MethodPost,
+			target:     "/subscribe",
+			body:       `{"address": "` + address + `"}`,
+			key:        acmeKey,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "subscribe invalid address",
+			method:     http.MethodPost,
+			target:     "/subscribe",
+			body:       `{"address": "0x123"}`,
+			key:        acmeKey,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "subscribe quota exceeded",
+			method:     http.MethodPost,
+			target:     "/subscribe",
+			body:       `{"address": "` + otherAddress + `"}`,
+			key:        acmeKey,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "transactions",
+			method:     http.MethodGet,
+			target:     "/transactions?address=" + address + "&limit=2",
+			key:        acmeKey,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "transactions invalid limit",
+			method:     http.MethodGet,
+			target:     "/transactions?address=" + address + "&limit=x",
+			key:        acmeKey,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "transactions not subscribed",
+			method:     http.MethodGet,
+			target:     "/transactions?address=" + address,
+			key:        globexKey,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "no transactions",
+			method:     http.MethodPost,
+			target:     "/rpc",
+			body:       "[" + rpc("txparser_subscribe", `["`+otherAddress+`"]`) + "," + rpc("txparser_getTransactions", `["`+otherAddress+`"]`) + "]",
+			key:        globexKey,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "rpc",
+			method:     http.MethodPost,
+			target:     "/rpc",
+			body:       rpc("txparser_getTransactions", `["`+address+`", {"limit": 1}]`),
+			key:        acmeKey,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "rpc error",
+			method:     http.MethodPost,
+			target:     "/rpc",
+			body:       rpc("txparser_unknown", `[]`),
+			key:        acmeKey,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "health of idle worker",
+			method:     http.MethodGet,
+			target:     httpapi.HealthPath,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			var response *httptest.ResponseRecorder
+			if test.key == "" {
+				response = serve(server, test.method, test.target, test.body)
+			} else {
+				response = serveWithKey(server, test.method, test.target, test.body, test.key)
+			}
+
+			// Assert
+			assertStatus(t, response, test.wantStatus)
+			route, _, _ := strings.Cut(test.target, "?")
+			schema := responseSchema(document, route, test.method, response.Code)
+			if schema == nil {
+				t.Errorf("response %d of %s %s should be documented", response.Code, test.method, route)
+				t.FailNow()
+			}
+			var body any
+			decode(t, response, &body)
+			for _, err := range validateSchema(document, schema, body, "$") {
+				t.Errorf("response should match the schema: %s", err)
+			}
+		})
+	}
+}
+
+func openAPIDocument(t *testing.T, server *httpapi.Server) map[string]any {
+	t.Helper()
+
+	var document map[string]any
+	decode(t, serve(server, http.MethodGet, httpapi.OpenAPIPath, ""), &document)
+
+	return document
+}
+
+// responseSchema returns the schema of the response, responses to methods not allowed
+// are looked up in the operation of the route.
+func responseSchema(document map[string]any, route, method string, status int) any {
+	operations, _ := document["paths"].(map[string]any)[route].(map[string]any)
+	operation := operations[strings.ToLower(method)]
+	for _, allowed := range operations {
+		if operation == nil && status == http.StatusMethodNotAllowed {
+			operation = allowed
+		}
+	}
+
+	node := operation
+	for _, key := range []string{"responses", fmt.Sprint(status), "content", "application/json", "schema"} {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = object[key]
+	}
+
+	return node
+}
+
+func refsOf(node any) []string {
+	var refs []string
+	switch v := node.(type) {
+	case map[string]any:
+		for key, value := range v {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				refs = append(refs, ref)
+				continue
+			}
+			refs = append(refs, refsOf(value)...)
+		}
+	case []any:
+		for _, value := range v {
+			refs = append(refs, refsOf(value)...)
+		}
+	}
+
+	return refs
+}
+
+func resolveRef(document map[string]any, ref string) map[string]any {
+	var node any = document
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = object[key]
+	}
+
+	object, _ := node.(map[string]any)
+
+	return object
+}
+
+// validateSchema validates the value against the subset of the OpenAPI schema used by the API.
+func validateSchema(document map[string]any, rawSchema, value any, path string) []string {
+	schema, _ := rawSchema.(map[string]any)
+	if ref, ok := schema["$ref"].(string); ok {
+		return validateSchema(document, resolveRef(document, ref), value, path)
+	}
+
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		matches := 0
+		for _, option := range oneOf {
+			if len(validateSchema(document, option, value, path)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []string{fmt.Sprintf("%s should match exactly one schema, but matches %d", path, matches)}
+		}
+		return nil
+	}
+
+	if value == nil {
+		if schema["nullable"] == true || schema["type"] == nil {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s should not be null", path)}
+	}
+
+	var errs []string
+	switch schema["type"] {
+	case nil:
+		return nil
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s should be an object", path)}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := object[name.(string)]; !ok {
+				errs = append(errs, fmt.Sprintf("%s.%s is required", path, name))
+			}
+		}
+		for name, property := range object {
+			propertySchema, ok := properties[name]
+			if !ok {
+				if schema["additionalProperties"] == false {
+					errs = append(errs, fmt.Sprintf("%s.%s is not defined", path, name))
+				}
+				continue
+			}
+			errs = append(errs, validateSchema(document, propertySchema, property, path+"."+name)...)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s should be an array", path)}
+		}
+		if minItems, ok := schema["minItems"].(float64); ok && len(items) < int(minItems) {
+			errs = append(errs, fmt.Sprintf("%s should have at least %v item(s)", path, minItems))
+		}
+		for i, item := range items {
+			errs = append(errs, validateSchema(document, schema["items"], item, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s should be a string", path)}
+		}
+		if pattern, ok := schema["pattern"].(string); ok && !regexp.MustCompile(pattern).MatchString(s) {
+			errs = append(errs, fmt.Sprintf("%s should match %s", path, pattern))
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return []string{fmt.Sprintf("%s should be an integer", path)}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s should be a number", path)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s should be a boolean", path)}
+		}
+	default:
+		return []string{fmt.Sprintf("%s has unknown type %v", path, schema["type"])}
+	}
+
+	return errs
+}
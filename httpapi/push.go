@@ -0,0 +1,282 @@
+package httpapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"txparser"
+)
+
+const (
+	// pushQueueSize is the number of messages a WebSocket client may fall behind before it is disconnected.
+	pushQueueSize = 256
+
+	pushListenerBuffer = 1024
+)
+
+const (
+	PushSubscribe   = "subscribe"
+	PushUnsubscribe = "unsubscribe"
+
+	PushSubscribed   = "subscribed"
+	PushUnsubscribed = "unsubscribed"
+	PushError        = "error"
+)
+
+// PushRequest is a message of a WebSocket client, Type is PushSubscribe or PushUnsubscribe.
+type PushRequest struct {
+	Type      string   `json:"type"`
+	Addresses []string `json:"addresses"`
+}
+
+// PushResponse answers a PushRequest. Events are sent to clients as txparser.Event.
+type PushResponse struct {
+	Type      string   `json:"type"`
+	Addresses []string `json:"addresses,omitempty"`
+	Error     *Error   `json:"error,omitempty"`
+}
+
+// handleWebSocket pushes events to WebSocket clients: block events of every saved block
+// and transaction events of the addresses the client subscribed to with PushRequest messages.
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgradeWebSocket(w, r, 2*s.heartbeatInterval)
+	if err != nil {
+		return
+	}
+
+	client := &pushClient{
+		ctx:       r.Context(),
+		server:    s,
+		ws:        ws,
+		queue:     make(chan []byte, pushQueueSize),
+		done:      make(chan struct{}),
+		addresses: make(map[string]struct{}),
+	}
+	client.run()
+}
+
+type pushClient struct {
+	// ctx is the context of the upgraded request, it carries the tenant
+	ctx    context.Context
+	server *Server
+	ws     *wsConn
+
+	// queue bounds messages waiting to be written
+	queue chan []byte
+
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu        sync.RWMutex
+	addresses map[string]struct{}
+}
+
+func (c *pushClient) run() {
+	events, stop := c.server.parser.Listen(pushListenerBuffer)
+	defer stop()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		c.writeLoop()
+	}()
+	go func() {
+		defer wg.Done()
+		c.forward(events)
+	}()
+
+	c.readLoop()
+	wg.Wait()
+}
+
+// close sends the close frame once and closes the connection.
+func (c *pushClient) close(code uint16, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		_ = c.ws.writeClose(code, reason)
+		_ = c.ws.Close()
+	})
+}
+
+func (c *pushClient) readLoop() {
+	for {
+		opcode, message, err := c.ws.readMessage()
+
+		var protocolErr *wsError
+		switch {
+		case errors.As(err, &protocolErr):
+			c.close(protocolErr.code, protocolErr.reason)
+			return
+		case err != nil:
+			c.close(closeNormal, "")
+			return
+		case opcode != opText:
+			c.close(closeUnsupportedData, "only text messages are supported")
+			return
+		}
+
+		c.handle(message)
+	}
+}
+
+func (c *pushClient) handle(message []byte) {
+	var request PushRequest
+	err := json.Unmarshal(message, &request)
+	if err != nil {
+		c.reply(pushError(codeInvalidRequest, "invalid message: "+err.Error()))
+		return
+	}
+
+	addresses := make([]string, 0, len(request.Addresses))
+	for _, raw := range request.Addresses {
+		address, err := normalizeAddress(raw)
+		if err != nil {
+			c.reply(pushError(codeInvalidAddress, err.Error()))
+			return
+		}
+		addresses = append(addresses, address)
+	}
+
+	switch request.Type {
+	case PushSubscribe:
+		c.subscribe(addresses)
+	case PushUnsubscribe:
+		c.mu.Lock()
+		for _, address := range addresses {
+			delete(c.addresses, address)
+		}
+		c.mu.Unlock()
+
+		c.reply(PushResponse{Type: PushUnsubscribed, Addresses: addresses})
+	default:
+		c.reply(pushError(codeInvalidRequest, "unknown message type "+request.Type))
+	}
+}
+
+// subscribe is called by the read loop only, so the addresses do not change between the checks.
+func (c *pushClient) subscribe(addresses []string) {
+	c.mu.RLock()
+	count := len(c.addresses)
+	for _, address := range addresses {
+		if _, ok := c.addresses[address]; !ok {
+			count++
+		}
+	}
+	c.mu.RUnlock()
+
+	if count > maxStreamAddresses {
+		c.reply(pushError(codeInvalidRequest, "too many addresses"))
+		return
+	}
+
+	for _, address := range addresses {
+		err := c.server.subscribe(c.ctx, address)
+		if errors.Is(err, txparser.ErrSubscriptionQuotaExceeded) {
+			c.reply(pushError(codeQuotaExceeded, err.Error()))
+			return
+		}
+		if err != nil {
+			if !errors.Is(err, errSubscribeFailed) {
+				c.server.logError(c.ctx, "subscribe", err, "address", address)
+			}
+			c.reply(pushError(codeInternal, "failed to subscribe"))
+			return
+		}
+
+		c.mu.Lock()
+		c.addresses[address] = struct{}{}
+		c.mu.Unlock()
+	}
+
+	c.reply(PushResponse{Type: PushSubscribed, Addresses: addresses})
+}
+
+func (c *pushClient) reply(response PushResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		c.server.logError(c.ctx, "encode push response", err, "type", response.Type)
+		return
+	}
+
+	c.enqueue(data)
+}
+
+func pushError(code, message string) PushResponse {
+	return PushResponse{Type: PushError, Error: &Error{Code: code, Message: message}}
+}
+
+// forward queues block events and transaction events of the subscribed addresses.
+func (c *pushClient) forward(events <-chan txparser.Event) {
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-c.server.closing:
+			c.close(closeGoingAway, "server is shutting down")
+			return
+		case event, ok := <-events:
+			if !ok {
+				c.close(closePolicyViolation, "client is too slow")
+				return
+			}
+
+			if event.Type == txparser.EventTransaction && !c.isSubscribed(event.Address) {
+				continue
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				c.server.logError(c.ctx, "encode event", err, "block", event.BlockNumber)
+				continue
+			}
+			c.enqueue(data)
+		}
+	}
+}
+
+func (c *pushClient) isSubscribed(address string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	_, ok := c.addresses[address]
+
+	return ok
+}
+
+// enqueue queues the message without blocking, a client with a full queue is disconnected.
+func (c *pushClient) enqueue(message []byte) {
+	select {
+	case c.queue <- message:
+	default:
+		c.close(closePolicyViolation, "client is too slow")
+	}
+}
+
+// writeLoop writes queued messages and pings the client every heartbeat interval.
+func (c *pushClient) writeLoop() {
+	ping := c.server.clock.NewTimer(c.server.heartbeatInterval)
+	defer ping.Stop()
+
+	for {
+		var err error
+
+		select {
+		case <-c.done:
+			return
+		case message := <-c.queue:
+			err = c.ws.writeFrame(opText, message)
+		case <-ping.C():
+			err = c.ws.writeFrame(opPing, nil)
+			ping.Reset(c.server.heartbeatInterval)
+		}
+
+		if err != nil {
+			c.close(closeNormal, "")
+			return
+		}
+	}
+}
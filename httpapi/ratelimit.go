@@ -0,0 +1,218 @@
+package httpapi
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"txparser"
+)
+
+// bucketsSweepPeriod is how often buckets refilled to the full burst are forgotten.
+const bucketsSweepPeriod = time.Minute
+
+// RateLimit is a token bucket: Burst requests are allowed at once and
+// the bucket is refilled at Rate requests per second. The zero value disables limiting.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// WithRateLimit limits requests to every route of every client, see WithRouteRateLimit.
+// Clients are told apart by API keys if WithAuth is used, otherwise by IP addresses.
+func WithRateLimit(limit RateLimit) Option {
+	return func(s *Server) {
+		s.limiter.defaultLimit = limit
+	}
+}
+
+// WithRouteRateLimit overrides the limit of the route, such as "/transactions".
+// The zero limit disables limiting of the route.
+func WithRouteRateLimit(route string, limit RateLimit) Option {
+	return func(s *Server) {
+		s.limiter.routes[route] = limit
+	}
+}
+
+type rateLimiter struct {
+	defaultLimit RateLimit
+	routes       map[string]RateLimit
+
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limit   RateLimit
+	tokens  float64
+	updated time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		routes:  make(map[string]RateLimit),
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (l *rateLimiter) limitOf(route string) RateLimit {
+	if limit, ok := l.routes[route]; ok {
+		return limit
+	}
+
+	return l.defaultLimit
+}
+
+// take takes cost tokens from the bucket of the client on the route if it has a token
+// and returns the bucket state. The zero cost only checks the bucket.
+func (l *rateLimiter) take(route, client string, limit RateLimit, cost float64) (b bucket, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	key := route + " " + client
+	current, exists := l.buckets[key]
+	if !exists {
+		current = &bucket{limit: limit, tokens: float64(limit.Burst), updated: now}
+		l.buckets[key] = current
+	}
+	current.refill(now)
+
+	if current.tokens < 1 {
+		return *current, false
+	}
+
+	current.tokens -= cost
+
+	return *current, true
+}
+
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updated).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+elapsed*b.limit.Rate)
+		b.updated = now
+	}
+}
+
+// sweep forgets full buckets, they are equal to new ones. The caller holds mu.
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < bucketsSweepPeriod {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// untilTokens returns the time until the bucket has n tokens.
+func (b bucket) untilTokens(n float64) time.Duration {
+	if b.tokens >= n {
+		return 0
+	}
+
+	return time.Duration((n - b.tokens) / b.limit.Rate * float64(time.Second))
+}
+
+// limit rejects requests of clients exceeding the rate limit of the route with 429 Too Many Requests.
+// Responses carry the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers,
+// the latter is the number of seconds until the bucket is full.
+func (s *Server) limit(route string, next http.Handler) http.Handler {
+	limit := s.limiter.limitOf(route)
+	if !limit.enabled() {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := s.limiter.take(route, clientOf(r), limit, 1)
+		if !writeRateLimit(w, limit, b, ok) {
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowAuthentication rejects requests from IP addresses which exceeded the rate limit of the route
+// with failed authentications, before their API keys are looked up. It reports whether the request may proceed.
+func (s *Server) allowAuthentication(w http.ResponseWriter, r *http.Request, route string) bool {
+	limit := s.limiter.limitOf(route)
+	if !limit.enabled() {
+		return true
+	}
+
+	b, ok := s.limiter.take(route, authFailuresOf(r), limit, 0)
+	if ok {
+		return true
+	}
+
+	return writeRateLimit(w, limit, b, ok)
+}
+
+// countFailedAuthentication takes a token from the bucket of failed authentications of the IP address.
+// Only failures take tokens, so clients sharing an IP address are limited by their API keys.
+func (s *Server) countFailedAuthentication(r *http.Request, route string) {
+	limit := s.limiter.limitOf(route)
+	if limit.enabled() {
+		s.limiter.take(route, authFailuresOf(r), limit, 1)
+	}
+}
+
+// writeRateLimit sets the rate limit headers and rejects the request unless ok, it returns ok.
+func writeRateLimit(w http.ResponseWriter, limit RateLimit, b bucket, ok bool) bool {
+	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(b.tokens)))
+	w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(b.untilTokens(float64(limit.Burst)))))
+
+	if !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(b.untilTokens(1))))
+		writeError(w, http.StatusTooManyRequests, codeRateLimited, "rate limit exceeded")
+	}
+
+	return ok
+}
+
+// clientOf identifies the client by the API key of an authenticated request or by the IP address.
+func clientOf(r *http.Request) string {
+	if _, ok := TenantFromContext(r.Context()); ok {
+		return "key:" + txparser.HashAPIKey(apiKeyOf(r))
+	}
+
+	return "ip:" + ipOf(r)
+}
+
+// authFailuresOf identifies the bucket of failed authentications of the IP address of the request.
+func authFailuresOf(r *http.Request) string {
+	return "auth:" + ipOf(r)
+}
+
+func ipOf(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
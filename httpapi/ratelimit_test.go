@@ -0,0 +1,177 @@
+package httpapi_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/httpapi"
+	"txparser/txparsertest"
+)
+
+// slowRefill is refilled by a token in about 17 minutes, so tests never see a refill.
+var slowRefill = httpapi.RateLimit{Rate: 0.001, Burst: 2}
+
+func Test_Server_RateLimit(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t, httpapi.WithRateLimit(slowRefill))
+
+	// Act
+	first := serveFrom(server, "/currentBlock", "10.0.0.1:1000", "")
+	second := serveFrom(server, "/currentBlock", "10.0.0.1:1001", "")
+	limited := serveFrom(server, "/currentBlock", "10.0.0.1:1002", "")
+	otherClient := serveFrom(server, "/currentBlock", "10.0.0.2:1000", "")
+	otherRoute := serveFrom(server, "/transactions?address="+address, "10.0.0.1:1000", "")
+
+	// Assert
+	assertStatus(t, first, http.StatusOK)
+	assertRateLimitHeaders(t, first, 2, 1)
+	assertStatus(t, second, http.StatusOK)
+	assertRateLimitHeaders(t, second, 2, 0)
+	assertStatus(t, limited, http.StatusTooManyRequests)
+	assertErrorCode(t, limited, "rate_limited")
+	assertRateLimitHeaders(t, limited, 2, 0)
+	retryAfter, err := strconv.Atoi(limited.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 {
+		t.Errorf("Retry-After should be a positive number of seconds, but is %q", limited.Header().Get("Retry-After"))
+	}
+	assertStatus(t, otherClient, http.StatusOK)
+	assertStatus(t, otherRoute, http.StatusOK)
+}
+
+func Test_Server_RouteRateLimit(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t,
+		httpapi.WithRateLimit(slowRefill),
+		httpapi.WithRouteRateLimit("/currentBlock", httpapi.RateLimit{}),
+		httpapi.WithRouteRateLimit("/transactions", httpapi.RateLimit{Rate: 0.001, Burst: 1}),
+	)
+
+	for i := 0; i < 3; i++ {
+		// Act
+		response := serveFrom(server, "/currentBlock", "10.0.0.1:1000", "")
+
+		// Assert
+		assertStatus(t, response, http.StatusOK)
+		if response.Header().Get("X-RateLimit-Limit") != "" {
+			t.Error("unlimited route should not have rate limit headers")
+		}
+	}
+
+	// Act
+	first := serveFrom(server, "/transactions?address="+address, "10.0.0.1:1000", "")
+	limited := serveFrom(server, "/transactions?address="+address, "10.0.0.1:1000", "")
+
+	// Assert
+	assertStatus(t, first, http.StatusOK)
+	assertStatus(t, limited, http.StatusTooManyRequests)
+}
+
+func Test_Server_RateLimitRefill(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	server, _ := newTestServer(t,
+		httpapi.WithClock(clock),
+		httpapi.WithRateLimit(httpapi.RateLimit{Rate: 1, Burst: 1}),
+	)
+	_ = serveFrom(server, "/currentBlock", "10.0.0.1:1000", "")
+
+	// Act
+	limited := serveFrom(server, "/currentBlock", "10.0.0.1:1000", "")
+	clock.Advance(999 * time.Millisecond)
+	stillLimited := serveFrom(server, "/currentBlock", "10.0.0.1:1000", "")
+	clock.Advance(time.Millisecond)
+	refilled := serveFrom(server, "/currentBlock", "10.0.0.1:1000", "")
+
+	// Assert
+	assertStatus(t, limited, http.StatusTooManyRequests)
+	assertStatus(t, stillLimited, http.StatusTooManyRequests)
+	assertStatus(t, refilled, http.StatusOK)
+}
+
+func Test_Server_RateLimitByAPIKey(t *testing.T) {
+	// Arrange
+	apiKeys := txparser.NewInmemoryAPIKeyStorage()
+	_ = apiKeys.PutAPIKey(context.Background(), txparser.HashAPIKey(acmeKey), txparser.Tenant{ID: "acme"})
+	_ = apiKeys.PutAPIKey(context.Background(), txparser.HashAPIKey(globexKey), txparser.Tenant{ID: "globex"})
+	server, _ := newTestServer(t,
+		httpapi.WithAuth(apiKeys, txparser.NewInmemoryTenantSubscriptionsStorage()),
+		httpapi.WithRateLimit(httpapi.RateLimit{Rate: 0.001, Burst: 1}),
+	)
+
+	// Act
+	first := serveFrom(server, "/currentBlock", "10.0.0.1:1000", acmeKey)
+	limited := serveFrom(server, "/currentBlock", "10.0.0.1:1000", acmeKey)
+	otherKey := serveFrom(server, "/currentBlock", "10.0.0.1:1000", globexKey)
+
+	// Assert
+	assertStatus(t, first, http.StatusOK)
+	assertStatus(t, limited, http.StatusTooManyRequests)
+	assertStatus(t, otherKey, http.StatusOK)
+}
+
+func Test_Server_RateLimitFailedAuthentication(t *testing.T) {
+	// Arrange
+	apiKeys := &countingAPIKeyStorage{APIKeyStorage: txparser.NewInmemoryAPIKeyStorage()}
+	_ = apiKeys.PutAPIKey(context.Background(), txparser.HashAPIKey(acmeKey), txparser.Tenant{ID: "acme"})
+	server, _ := newTestServer(t,
+		httpapi.WithAuth(apiKeys, txparser.NewInmemoryTenantSubscriptionsStorage()),
+		httpapi.WithRateLimit(slowRefill),
+	)
+
+	// Act
+	missing := serveFrom(server, "/currentBlock", "10.0.0.1:1000", "")
+	invalid := serveFrom(server, "/currentBlock", "10.0.0.1:1000", "guess-1")
+	limited := serveFrom(server, "/currentBlock", "10.0.0.1:1000", "guess-2")
+	otherIP := serveFrom(server, "/currentBlock", "10.0.0.2:1000", acmeKey)
+
+	// Assert
+	assertStatus(t, missing, http.StatusUnauthorized)
+	assertStatus(t, invalid, http.StatusUnauthorized)
+	assertStatus(t, limited, http.StatusTooManyRequests)
+	assertStatus(t, otherIP, http.StatusOK)
+	if apiKeys.lookups != 2 {
+		t.Errorf("api keys should be looked up %d times, but are %d times", 2, apiKeys.lookups)
+	}
+}
+
+// countingAPIKeyStorage counts lookups of API keys.
+type countingAPIKeyStorage struct {
+	txparser.APIKeyStorage
+	lookups int
+}
+
+func (s *countingAPIKeyStorage) GetTenantByAPIKey(ctx context.Context, keyHash string) (*txparser.Tenant, error) {
+	s.lookups++
+	return s.APIKeyStorage.GetTenantByAPIKey(ctx, keyHash)
+}
+
+func serveFrom(server *httpapi.Server, target, remoteAddr, key string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	request.RemoteAddr = remoteAddr
+	if key != "" {
+		request.Header.Set("Authorization", "Bearer "+key)
+	}
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	return response
+}
+
+func assertRateLimitHeaders(t *testing.T, response *httptest.ResponseRecorder, limit, remaining int) {
+	t.Helper()
+
+	if response.Header().Get("X-RateLimit-Limit") != strconv.Itoa(limit) {
+		t.Errorf("X-RateLimit-Limit should be %d, but is %q", limit, response.Header().Get("X-RateLimit-Limit"))
+	}
+	if response.Header().Get("X-RateLimit-Remaining") != strconv.Itoa(remaining) {
+		t.Errorf("X-RateLimit-Remaining should be %d, but is %q", remaining, response.Header().Get("X-RateLimit-Remaining"))
+	}
+	if _, err := strconv.Atoi(response.Header().Get("X-RateLimit-Reset")); err != nil {
+		t.Errorf("X-RateLimit-Reset should be a number of seconds, but is %q", response.Header().Get("X-RateLimit-Reset"))
+	}
+}
@@ -0,0 +1,209 @@
+// Package httpapi serves the parser over HTTP.
+package httpapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"txparser"
+)
+
+const (
+	defaultAddr            = ":8080"
+	defaultShutdownTimeout = 10 * time.Second
+	defaultHeartbeat       = 15 * time.Second
+	readHeaderTimeout      = 10 * time.Second
+)
+
+// MetricsPath is where the server serves metrics enabled by WithMetrics.
+const MetricsPath = "/metrics"
+
+type Server struct {
+	parser *txparser.TXParser
+
+	addr            string
+	shutdownTimeout time.Duration
+
+	heartbeatInterval time.Duration
+
+	apiKeys             txparser.APIKeyStorage
+	tenantSubscriptions txparser.TenantSubscriptionsStorage
+
+	limiter *rateLimiter
+
+	metrics *txparser.Metrics
+
+	health HealthThresholds
+
+	clock txparser.Clock
+
+	logger *slog.Logger
+
+	mux *http.ServeMux
+
+	// closing is closed on shutdown to end event streams
+	closing     chan struct{}
+	closingOnce sync.Once
+}
+
+type Option func(*Server)
+
+// WithAddr sets the TCP address to listen on, ":8080" by default.
+func WithAddr(addr string) Option {
+	return func(s *Server) {
+		s.addr = addr
+	}
+}
+
+// WithShutdownTimeout sets how long in-flight requests are waited for on shutdown.
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = timeout
+	}
+}
+
+// WithHeartbeatInterval sets how often event streams send a heartbeat and WebSocket
+// clients are pinged, 15 seconds by default. WebSocket clients silent for two intervals are disconnected.
+func WithHeartbeatInterval(interval time.Duration) Option {
+	return func(s *Server) {
+		s.heartbeatInterval = interval
+	}
+}
+
+// WithMetrics serves the metrics in the Prometheus text format at MetricsPath, the endpoint is public.
+func WithMetrics(metrics *txparser.Metrics) Option {
+	return func(s *Server) {
+		s.metrics = metrics
+	}
+}
+
+// WithClock sets the clock of rate limits and health checks, it should be the clock of the parser.
+func WithClock(clock txparser.Clock) Option {
+	return func(s *Server) {
+		s.clock = clock
+		s.limiter.now = clock.Now
+	}
+}
+
+// WithLogger sets the logger of failed requests, records are dropped by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
+}
+
+func NewServer(parser *txparser.TXParser, opts ...Option) *Server {
+	s := &Server{
+		parser:            parser,
+		addr:              defaultAddr,
+		shutdownTimeout:   defaultShutdownTimeout,
+		heartbeatInterval: defaultHeartbeat,
+		mux:               http.NewServeMux(),
+		closing:           make(chan struct{}),
+		limiter:           newRateLimiter(),
+		health:            HealthThresholds{MaxTickAge: defaultMaxTickAge, MaxLag: defaultMaxLag},
+		clock:             txparser.SystemClock(),
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.routes()
+
+	return s
+}
+
+// Handler returns the handler of the API endpoints.
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Run listens on the server address and serves the API while the parser worker
+// parses blocks every pollPeriod, see Serve.
+func (s *Server) Run(ctx context.Context, pollPeriod time.Duration) error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, listener, pollPeriod)
+}
+
+// Serve serves the API on the listener and runs the parser worker until ctx is done
+// or the process receives SIGTERM or SIGINT. On shutdown in-flight requests are
+// finished first and event streams are closed, then the worker is stopped and waited for.
+// A server serves only once.
+func (s *Server) Serve(ctx context.Context, listener net.Listener, pollPeriod time.Duration) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	workerCtx, stopWorker := context.WithCancel(ctx)
+	defer stopWorker()
+
+	workerDone := make(chan error, 1)
+	go func() {
+		workerDone <- s.parser.RunWorker(workerCtx, pollPeriod)
+	}()
+
+	httpServer := &http.Server{
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+	// Shutdown does not wait for event streams to end on their own
+	httpServer.RegisterOnShutdown(s.closeStreams)
+
+	serveDone := make(chan error, 1)
+	go func() {
+		serveDone <- httpServer.Serve(listener)
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+	case err = <-serveDone:
+	case err = <-workerDone:
+		// The worker returns before ctx is done only if it failed to start or was stopped
+		workerDone <- err
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	shutdownErr := httpServer.Shutdown(shutdownCtx)
+	if errors.Is(shutdownErr, http.ErrServerClosed) {
+		shutdownErr = nil
+	}
+
+	stopWorker()
+	workerErr := <-workerDone
+
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	return errors.Join(err, shutdownErr, workerErr)
+}
+
+func (s *Server) closeStreams() {
+	s.closingOnce.Do(func() {
+		close(s.closing)
+	})
+}
+
+// logError logs an error of a request, msg names the failed operation.
+func (s *Server) logError(ctx context.Context, msg string, err error, args ...any) {
+	if s.logger == nil {
+		return
+	}
+
+	s.logger.ErrorContext(ctx, msg, append(args, "error", err)...)
+}
@@ -0,0 +1,469 @@
+package httpapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/httpapi"
+	"txparser/txparsertest"
+)
+
+const (
+	address      = "0x0d1d4e623d10f9fba5db95830f7d3839406c6af2"
+	otherAddress = "0x00000000000000000000000000000000000000aa"
+)
+
+func Test_Server_CurrentBlock(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+
+	// Act
+	response := serve(server, http.MethodGet, "/currentBlock", "")
+
+	// Assert
+	assertStatus(t, response, http.StatusOK)
+	if strings.TrimSpace(response.Body.String()) != "10" {
+		t.Errorf("current block should be %s, but is %s", "10", response.Body.String())
+	}
+	if response.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("content type should be %q, but is %q", "application/json", response.Header().Get("Content-Type"))
+	}
+}
+
+func Test_Server_Subscribe(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "valid address",
+			method:     http.MethodPost,
+			body:       `{"address": "0x0d1d4e623D10F9FBA5Db95830F7d3839406C6AF2"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid address",
+			method:     http.MethodPost,
+			body:       `{"address": "0x123"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_address",
+		},
+		{
+			name:       "invalid json",
+			method:     http.MethodPost,
+			body:       `{"address": `,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_request",
+		},
+		{
+			name:       "unknown field",
+			method:     http.MethodPost,
+			body:       `{"addr": "0x0d1d4e623D10F9FBA5Db95830F7d3839406C6AF2"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_request",
+		},
+		{
+			name:       "wrong method",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+			wantCode:   "method_not_allowed",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			server, _ := newTestServer(t)
+
+			// Act
+			response := serve(server, test.method, "/subscribe", test.body)
+
+			// Assert
+			assertStatus(t, response, test.wantStatus)
+			if test.wantCode != "" {
+				assertErrorCode(t, response, test.wantCode)
+				return
+			}
+			if strings.TrimSpace(response.Body.String()) != "true" {
+				t.Errorf("response should be %s, but is %s", "true", response.Body.String())
+			}
+		})
+	}
+}
+
+func Test_Server_SubscribeNormalizesAddress(t *testing.T) {
+	// Arrange
+	server, subscriptionsStorage := newTestServer(t)
+
+	// Act
+	response := serve(server, http.MethodPost, "/subscribe", `{"address": "0x00000000000000000000000000000000000000AA"}`)
+
+	// Assert
+	assertStatus(t, response, http.StatusOK)
+	if exists, _ := subscriptionsStorage.IsAddressExists(context.Background(), otherAddress); !exists {
+		t.Error("lower case address should be subscribed")
+	}
+}
+
+func Test_Server_Transactions(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+
+	// Act
+	response := serve(server, http.MethodGet, "/transactions?address="+address+"&limit=2", "")
+
+	// Assert
+	assertStatus(t, response, http.StatusOK)
+	var page txparser.TransactionsPage
+	decode(t, response, &page)
+	if len(page.Transactions) != 2 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 2, len(page.Transactions))
+		t.FailNow()
+	}
+	if page.Transactions[0].Hash != "0xa1" || page.Transactions[1].Hash != "0xa2" {
+		t.Errorf("unexpected transactions %v", page.Transactions)
+	}
+	if page.NextCursor == "" {
+		t.Error("next cursor should be set")
+		t.FailNow()
+	}
+
+	// Act #2
+	response = serve(server, http.MethodGet, "/transactions?address="+address+"&limit=2&cursor="+page.NextCursor, "")
+
+	// Assert
+	assertStatus(t, response, http.StatusOK)
+	page = txparser.TransactionsPage{}
+	decode(t, response, &page)
+	if len(page.Transactions) != 1 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 1, len(page.Transactions))
+		t.FailNow()
+	}
+	if page.Transactions[0].Hash != "0xa3" {
+		t.Errorf("unexpected transactions %v", page.Transactions)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("next cursor should be empty, but is %q", page.NextCursor)
+	}
+}
+
+func Test_Server_TransactionsFilters(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantHashes []string
+	}{
+		{
+			name:       "block range",
+			query:      "&fromBlock=2&toBlock=2",
+			wantHashes: []string{"0xa2"},
+		},
+		{
+			name:       "direction",
+			query:      "&direction=incoming",
+			wantHashes: []string{"0xa2"},
+		},
+		{
+			name:       "min value",
+			query:      "&minValue=0x10",
+			wantHashes: []string{"0xa3"},
+		},
+		{
+			name:       "descending order",
+			query:      "&order=desc",
+			wantHashes: []string{"0xa3", "0xa2", "0xa1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			server, _ := newTestServer(t)
+
+			// Act
+			response := serve(server, http.MethodGet, "/transactions?address="+address+test.query, "")
+
+			// Assert
+			assertStatus(t, response, http.StatusOK)
+			var page txparser.TransactionsPage
+			decode(t, response, &page)
+			hashes := make([]string, 0, len(page.Transactions))
+			for _, tx := range page.Transactions {
+				hashes = append(hashes, tx.Hash)
+			}
+			if fmt.Sprint(hashes) != fmt.Sprint(test.wantHashes) {
+				t.Errorf("hashes should be %v, but are %v", test.wantHashes, hashes)
+			}
+		})
+	}
+}
+
+func Test_Server_TransactionsInvalidRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantCode string
+	}{
+		{name: "missing address", query: "", wantCode: "invalid_address"},
+		{name: "invalid address", query: "?address=0x123", wantCode: "invalid_address"},
+		{name: "invalid limit", query: "?address=" + address + "&limit=abc", wantCode: "invalid_request"},
+		{name: "negative block", query: "?address=" + address + "&fromBlock=-1", wantCode: "invalid_request"},
+		{name: "too large limit", query: "?address=" + address + "&limit=100000", wantCode: "invalid_request"},
+		{name: "invalid cursor", query: "?address=" + address + "&cursor=abc", wantCode: "invalid_request"},
+		{name: "invalid direction", query: "?address=" + address + "&direction=up", wantCode: "invalid_request"},
+		{name: "invalid min value", query: "?address=" + address + "&minValue=-1", wantCode: "invalid_request"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			server, _ := newTestServer(t)
+
+			// Act
+			response := serve(server, http.MethodGet, "/transactions"+test.query, "")
+
+			// Assert
+			assertStatus(t, response, http.StatusBadRequest)
+			assertErrorCode(t, response, test.wantCode)
+		})
+	}
+}
+
+func Test_Server_NotFound(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+
+	// Act
+	response := serve(server, http.MethodGet, "/unknown", "")
+
+	// Assert
+	assertStatus(t, response, http.StatusNotFound)
+	assertErrorCode(t, response, "not_found")
+}
+
+func Test_Server_MethodNotAllowed(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+
+	// Act
+	response := serve(server, http.MethodPost, "/currentBlock", "")
+
+	// Assert
+	assertStatus(t, response, http.StatusMethodNotAllowed)
+	assertErrorCode(t, response, "method_not_allowed")
+	if response.Header().Get("Allow") != http.MethodGet {
+		t.Errorf("allow header should be %q, but is %q", http.MethodGet, response.Header().Get("Allow"))
+	}
+}
+
+func Test_Server_Metrics(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t, httpapi.WithMetrics(txparser.NewMetrics()))
+	withoutMetrics, _ := newTestServer(t)
+
+	// Act
+	response := serve(server, http.MethodGet, httpapi.MetricsPath, "")
+	disabled := serve(withoutMetrics, http.MethodGet, httpapi.MetricsPath, "")
+
+	// Assert
+	assertStatus(t, response, http.StatusOK)
+	if !strings.Contains(response.Body.String(), "# TYPE txparser_head_lag_blocks gauge\n") {
+		t.Errorf("body should have the head lag metric, but is %q", response.Body.String())
+	}
+	assertStatus(t, disabled, http.StatusNotFound)
+}
+
+func Test_Server_ServeShutsDownWithWorker(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := txparsertest.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	client := &stubClient{head: 10}
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		client,
+		txparser.WithClock(clock),
+	)
+	server := httpapi.NewServer(parser, httpapi.WithShutdownTimeout(time.Second), httpapi.WithClock(clock))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, listener, time.Second)
+	}()
+	clock.BlockUntil(1) // the worker waits for the next pass
+
+	// Act
+	response, err := http.Get("http://" + listener.Addr().String() + "/currentBlock")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_ = response.Body.Close()
+	cancel()
+
+	// Assert
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("status should be %d, but is %d", http.StatusOK, response.StatusCode)
+	}
+	err = <-done
+	if err != nil {
+		t.Error(err)
+	}
+	select {
+	case <-parser.Done():
+	default:
+		t.Error("worker should be stopped")
+	}
+	clock.BlockUntil(0) // the stopped worker waits for no pass
+	calls := client.calls()
+	clock.Advance(time.Second)
+	if client.calls() != calls {
+		t.Errorf("worker should make %d call(s), but makes %d", calls, client.calls())
+	}
+	_, err = http.Get("http://" + listener.Addr().String() + "/currentBlock")
+	if err == nil {
+		t.Error("server should not accept connections")
+	}
+}
+
+// newTestServer returns a server with transactions of address in blocks 1-3.
+func newTestServer(t *testing.T, opts ...httpapi.Option) (*httpapi.Server, txparser.SubscriptionsStorage) {
+	t.Helper()
+
+	server, subscriptionsStorage, _ := newTestServerWithClient(t, &stubClient{head: 10}, opts...)
+
+	return server, subscriptionsStorage
+}
+
+// newTestServerWithClient returns a server with transactions of address in blocks 1-3
+// and the parser of it, address is subscribed and blocks up to 10 are parsed.
+func newTestServerWithClient(
+	t *testing.T,
+	client txparser.Client,
+	opts ...httpapi.Option,
+) (*httpapi.Server, txparser.SubscriptionsStorage, *txparser.TXParser) {
+	t.Helper()
+
+	return newTestServerWithClock(t, client, txparser.SystemClock(), opts...)
+}
+
+// newTestServerWithClock is newTestServerWithClient with the clock of the parser and the server.
+func newTestServerWithClock(
+	t *testing.T,
+	client txparser.Client,
+	clock txparser.Clock,
+	opts ...httpapi.Option,
+) (*httpapi.Server, txparser.SubscriptionsStorage, *txparser.TXParser) {
+	t.Helper()
+
+	ctx := context.Background()
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	txStorage := txparser.NewInmemoryTransactionsStorage()
+	subscriptionsStorage := txparser.NewInmemorySubscriptionsStorage()
+	_ = blockStorage.SaveBlockID(ctx, 10)
+	_ = subscriptionsStorage.PutAddress(ctx, address)
+	err := txStorage.SaveTransactions(ctx, address, []txparser.Transaction{
+		{BlockNumber: "0x1", Hash: "0xa1", From: address, To: otherAddress, Value: "0x1"},
+		{BlockNumber: "0x2", Hash: "0xa2", From: otherAddress, To: address, Value: "0x2"},
+		{BlockNumber: "0x3", Hash: "0xa3", From: address, To: otherAddress, Value: "0x20"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parser := txparser.NewTXParser(blockStorage, txStorage, subscriptionsStorage, client, txparser.WithClock(clock))
+	opts = append([]httpapi.Option{httpapi.WithClock(clock)}, opts...)
+
+	return httpapi.NewServer(parser, opts...), subscriptionsStorage, parser
+}
+
+func serve(server *httpapi.Server, method, target, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	response := httptest.NewRecorder()
+	server.Handler().ServeHTTP(response, request)
+
+	return response
+}
+
+func decode(t *testing.T, response *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	err := json.Unmarshal(response.Body.Bytes(), v)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+}
+
+func assertStatus(t *testing.T, response *httptest.ResponseRecorder, want int) {
+	t.Helper()
+
+	if response.Code != want {
+		t.Errorf("status should be %d, but is %d: %s", want, response.Code, response.Body.String())
+		t.FailNow()
+	}
+}
+
+func assertErrorCode(t *testing.T, response *httptest.ResponseRecorder, want string) {
+	t.Helper()
+
+	var envelope httpapi.ErrorResponse
+	decode(t, response, &envelope)
+	if envelope.Error.Code != want {
+		t.Errorf("error code should be %q, but is %q", want, envelope.Error.Code)
+	}
+	if envelope.Error.Message == "" {
+		t.Error("error message should be set")
+	}
+}
+
+type stubClient struct {
+	blocks map[int][]txparser.Transaction
+
+	mu       sync.Mutex
+	head     int
+	numCalls int
+}
+
+func (c *stubClient) CurrentBlockNumber(_ context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.numCalls++
+
+	return c.head, nil
+}
+
+func (c *stubClient) GetBlockByNumber(_ context.Context, number int) (*txparser.Block, error) {
+	return &txparser.Block{Transactions: c.blocks[number]}, nil
+}
+
+func (c *stubClient) setHead(head int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.head = head
+}
+
+func (c *stubClient) calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.numCalls
+}
@@ -0,0 +1,319 @@
+package httpapi
+
+import (
+	"bufio"
+	"crypto/sha1" //nolint:gosec // SHA-1 is required by the WebSocket handshake
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// WebSocket protocol, RFC 6455.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+const (
+	closeNormal          = 1000
+	closeGoingAway       = 1001
+	closeProtocolError   = 1002
+	closeUnsupportedData = 1003
+	closeInvalidPayload  = 1007
+	closePolicyViolation = 1008
+	closeMessageTooBig   = 1009
+)
+
+const (
+	maxMessageSize = 64 << 10
+	writeWait      = 10 * time.Second
+)
+
+var errWebSocketClosed = errors.New("websocket closed")
+
+// wsError is a protocol error closing the connection with the code.
+type wsError struct {
+	code   uint16
+	reason string
+}
+
+func (e *wsError) Error() string {
+	return fmt.Sprintf("websocket error %d: %s", e.code, e.reason)
+}
+
+type wsConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+
+	// readTimeout is the longest time without frames from the client
+	readTimeout time.Duration
+
+	writeMu sync.Mutex
+	// closeSent is set once the close frame is written, no frames follow it
+	closeSent bool
+}
+
+// upgradeWebSocket validates the opening handshake, takes over the connection
+// and switches protocols. On failure the error response is already written.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request, readTimeout time.Duration) (*wsConn, error) {
+	if !headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") {
+		w.Header().Set("Upgrade", "websocket")
+		writeError(w, http.StatusUpgradeRequired, codeInvalidRequest, "websocket upgrade is required")
+		return nil, errors.New("not a websocket handshake")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeError(w, http.StatusUpgradeRequired, codeInvalidRequest, "unsupported websocket version, use 13")
+		return nil, errors.New("unsupported websocket version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != 16 {
+		writeError(w, http.StatusBadRequest, codeInvalidRequest, "invalid Sec-WebSocket-Key")
+		return nil, errors.New("invalid websocket key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, codeInternal, "websocket is not supported")
+		return nil, errors.New("response writer does not support hijacking")
+	}
+
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = fmt.Fprintf(rw.Writer,
+		"HTTP/1.1 101 Switching Protocols\r\n"+
+			"Upgrade: websocket\r\n"+
+			"Connection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: %s\r\n\r\n",
+		websocketAccept(key),
+	)
+	if err == nil {
+		err = rw.Writer.Flush()
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return &wsConn{
+		conn:        conn,
+		reader:      rw.Reader,
+		readTimeout: readTimeout,
+	}, nil
+}
+
+func websocketAccept(key string) string {
+	hash := sha1.Sum([]byte(key + websocketGUID)) //nolint:gosec // required by the protocol
+
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func headerContainsToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// readMessage returns the next text or binary message. It answers pings
+// and the close handshake, in the latter case it returns errWebSocketClosed.
+func (c *wsConn) readMessage() (byte, []byte, error) {
+	var (
+		opcode  byte
+		message []byte
+	)
+
+	for {
+		err := c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		if err != nil {
+			return 0, nil, err
+		}
+
+		fin, frameOpcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch frameOpcode {
+		case opPing:
+			err = c.writeFrame(opPong, payload)
+			if err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := uint16(closeNormal)
+			if len(payload) >= 2 {
+				code = binary.BigEndian.Uint16(payload)
+			}
+			_ = c.writeClose(code, "")
+			return 0, nil, errWebSocketClosed
+		case opText, opBinary:
+			if message != nil {
+				return 0, nil, &wsError{code: closeProtocolError, reason: "expected continuation frame"}
+			}
+			opcode = frameOpcode
+			message = payload
+		case opContinuation:
+			if message == nil {
+				return 0, nil, &wsError{code: closeProtocolError, reason: "unexpected continuation frame"}
+			}
+			if len(message)+len(payload) > maxMessageSize {
+				return 0, nil, &wsError{code: closeMessageTooBig, reason: "message is too big"}
+			}
+			message = append(message, payload...)
+		default:
+			return 0, nil, &wsError{code: closeProtocolError, reason: "unknown opcode"}
+		}
+
+		if !fin {
+			continue
+		}
+
+		if opcode == opText && !utf8.Valid(message) {
+			return 0, nil, &wsError{code: closeInvalidPayload, reason: "invalid utf-8"}
+		}
+
+		return opcode, message, nil
+	}
+}
+
+func (c *wsConn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	_, err := io.ReadFull(c.reader, header[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		return false, 0, nil, &wsError{code: closeProtocolError, reason: "reserved bits are set"}
+	}
+	if header[1]&0x80 == 0 {
+		return false, 0, nil, &wsError{code: closeProtocolError, reason: "client frames must be masked"}
+	}
+
+	length := uint64(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	isControl := opcode&0x8 != 0
+	if isControl && (length > 125 || !fin) {
+		return false, 0, nil, &wsError{code: closeProtocolError, reason: "invalid control frame"}
+	}
+	if length > maxMessageSize {
+		return false, 0, nil, &wsError{code: closeMessageTooBig, reason: "message is too big"}
+	}
+
+	var mask [4]byte
+	_, err = io.ReadFull(c.reader, mask[:])
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		return false, 0, nil, err
+	}
+
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+
+	return fin, opcode, payload, nil
+}
+
+// writeFrame writes an unfragmented unmasked frame, it is safe for concurrent use.
+// Once the close frame is written another one is skipped and other frames fail.
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|opcode)
+
+	switch length := len(payload); {
+	case length <= 125:
+		frame = append(frame, byte(length))
+	case length <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(length))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(length))
+	}
+	frame = append(frame, payload...)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.closeSent {
+		if opcode == opClose {
+			return nil
+		}
+		return errWebSocketClosed
+	}
+	c.closeSent = opcode == opClose
+
+	err := c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	if err != nil {
+		return err
+	}
+
+	_, err = c.conn.Write(frame)
+
+	return err
+}
+
+func (c *wsConn) writeClose(code uint16, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	payload = append(payload, reason...)
+
+	return c.writeFrame(opClose, payload)
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
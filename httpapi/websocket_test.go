@@ -0,0 +1,389 @@
+package httpapi_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/httpapi"
+	"txparser/txparsertest"
+)
+
+func Test_Server_WebSocketPushesEvents(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &stubClient{
+		head: 10,
+		blocks: map[int][]txparser.Transaction{
+			11: {
+				{BlockNumber: "0xb", TransactionIndex: "0x0", Hash: "0xb0", From: otherAddress, To: otherAddress},
+				{BlockNumber: "0xb", TransactionIndex: "0x1", Hash: "0xb1", From: otherAddress, To: address},
+			},
+		},
+	}
+	server, subscriptionsStorage, parser := newTestServerWithClient(t, client)
+	go func() {
+		_ = parser.RunWorker(ctx, 10*time.Millisecond)
+	}()
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	ws := dialWebSocket(t, httpServer)
+	defer ws.close()
+
+	// Act
+	ws.writeJSON(t, httpapi.PushRequest{Type: httpapi.PushSubscribe, Addresses: []string{"0x123"}})
+	invalid := ws.readResponse(t)
+	ws.writeJSON(t, httpapi.PushRequest{Type: httpapi.PushSubscribe, Addresses: []string{address}})
+	subscribed := ws.readResponse(t)
+	client.setHead(11)
+	transactionEvent := ws.readEvent(t)
+	blockEvent := ws.readEvent(t)
+
+	// Assert
+	if invalid.Type != httpapi.PushError || invalid.Error == nil || invalid.Error.Code != "invalid_address" {
+		t.Errorf("response should be invalid address error, but is %+v", invalid)
+	}
+	if subscribed.Type != httpapi.PushSubscribed || len(subscribed.Addresses) != 1 || subscribed.Addresses[0] != address {
+		t.Errorf("response should be subscribed to %s, but is %+v", address, subscribed)
+	}
+	if exists, _ := subscriptionsStorage.IsAddressExists(ctx, address); !exists {
+		t.Error("address should be subscribed in the parser")
+	}
+	if transactionEvent.Type != txparser.EventTransaction || transactionEvent.Transaction.Hash != "0xb1" {
+		t.Errorf("event should be transaction 0xb1, but is %+v", transactionEvent)
+	}
+	if blockEvent.Type != txparser.EventBlock || blockEvent.BlockNumber != 11 {
+		t.Errorf("event should be block 11, but is %+v", blockEvent)
+	}
+}
+
+func Test_Server_WebSocketUnsubscribe(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	ws := dialWebSocket(t, httpServer)
+	defer ws.close()
+	ws.writeJSON(t, httpapi.PushRequest{Type: httpapi.PushSubscribe, Addresses: []string{address}})
+	_ = ws.readResponse(t)
+
+	// Act
+	ws.writeJSON(t, httpapi.PushRequest{Type: httpapi.PushUnsubscribe, Addresses: []string{address}})
+	unsubscribed := ws.readResponse(t)
+	ws.writeText(t, `{"type": "unknown"}`)
+	unknown := ws.readResponse(t)
+	ws.writeText(t, `{"type": `)
+	malformed := ws.readResponse(t)
+
+	// Assert
+	if unsubscribed.Type != httpapi.PushUnsubscribed || len(unsubscribed.Addresses) != 1 {
+		t.Errorf("response should be unsubscribed, but is %+v", unsubscribed)
+	}
+	for _, response := range []httpapi.PushResponse{unknown, malformed} {
+		if response.Type != httpapi.PushError || response.Error == nil || response.Error.Code != "invalid_request" {
+			t.Errorf("response should be invalid request error, but is %+v", response)
+		}
+	}
+}
+
+func Test_Server_WebSocketPingPong(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(time.Now())
+	server, _, _ := newTestServerWithClient(
+		t,
+		&stubClient{head: 10},
+		httpapi.WithClock(clock),
+		httpapi.WithHeartbeatInterval(time.Minute),
+	)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	ws := dialWebSocket(t, httpServer)
+	defer ws.close()
+
+	// Act
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+	opcode, _ := ws.readFrame(t)
+	ws.writeFrame(t, 0xA, nil)
+	ws.writeFrame(t, 0x9, []byte("hello"))
+
+	// Assert
+	if opcode != 0x9 {
+		t.Errorf("frame should be ping, but opcode is %x", opcode)
+	}
+	for {
+		opcode, payload := ws.readFrame(t)
+		if opcode == 0x9 {
+			continue
+		}
+		if opcode != 0xA || string(payload) != "hello" {
+			t.Errorf("frame should be pong with the ping payload, but opcode is %x and payload is %q", opcode, payload)
+		}
+		break
+	}
+}
+
+func Test_Server_WebSocketProtocolError(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	ws := dialWebSocket(t, httpServer)
+	defer ws.close()
+
+	// Act: client frames must be masked
+	_, err := ws.conn.Write([]byte{0x81, 0x02, '{', '}'})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Assert
+	opcode, payload := ws.readFrame(t)
+	if opcode != 0x8 || len(payload) < 2 || binary.BigEndian.Uint16(payload) != 1002 {
+		t.Errorf("frame should be close with code 1002, but opcode is %x and payload is %q", opcode, payload)
+	}
+}
+
+func Test_Server_WebSocketCloseHandshake(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+	ws := dialWebSocket(t, httpServer)
+	defer ws.close()
+
+	// Act
+	ws.writeFrame(t, 0x8, binary.BigEndian.AppendUint16(nil, 1000))
+	opcode, payload := ws.readFrame(t)
+	rest, err := io.ReadAll(ws.reader)
+
+	// Assert
+	if opcode != 0x8 || len(payload) < 2 || binary.BigEndian.Uint16(payload) != 1000 {
+		t.Errorf("frame should be close with code 1000, but opcode is %x and payload is %q", opcode, payload)
+	}
+	if err != nil {
+		t.Error(err)
+	}
+	if len(rest) != 0 {
+		t.Errorf("connection should be closed after one close frame, but has %q", rest)
+	}
+}
+
+func Test_Server_WebSocketRequiresUpgrade(t *testing.T) {
+	// Arrange
+	server, _ := newTestServer(t)
+
+	// Act
+	response := serve(server, http.MethodGet, "/ws", "")
+
+	// Assert
+	assertStatus(t, response, http.StatusUpgradeRequired)
+	assertErrorCode(t, response, "invalid_request")
+}
+
+func Test_Server_ServeClosesWebSockets(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	server, _, _ := newTestServerWithClient(t, &stubClient{head: 10})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Serve(ctx, listener, time.Hour)
+	}()
+	ws := dialWebSocketAddr(t, listener.Addr().String())
+	defer ws.close()
+
+	// Act
+	cancel()
+
+	// Assert
+	opcode, payload := ws.readFrame(t)
+	if opcode != 0x8 || len(payload) < 2 || binary.BigEndian.Uint16(payload) != 1001 {
+		t.Errorf("frame should be close with code 1001, but opcode is %x and payload is %q", opcode, payload)
+	}
+	select {
+	case err = <-done:
+		if err != nil {
+			t.Error(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("server should shut down")
+	}
+}
+
+// wsClient is a minimal WebSocket client.
+type wsClient struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, server *httptest.Server) *wsClient {
+	t.Helper()
+
+	return dialWebSocketAddr(t, server.Listener.Addr().String())
+}
+
+func dialWebSocketAddr(t *testing.T, addr string) *wsClient {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	_, err = io.WriteString(conn, "GET /ws HTTP/1.1\r\n"+
+		"Host: "+addr+"\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status should be %d, but is %d", http.StatusSwitchingProtocols, response.StatusCode)
+	}
+	// The accept value of the key from RFC 6455
+	if response.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("invalid Sec-WebSocket-Accept %q", response.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	return &wsClient{conn: conn, reader: reader}
+}
+
+func (c *wsClient) close() {
+	_ = c.conn.Close()
+}
+
+func (c *wsClient) writeFrame(t *testing.T, opcode byte, payload []byte) {
+	t.Helper()
+
+	mask := [4]byte{1, 2, 3, 4}
+	frame := []byte{0x80 | opcode}
+	switch {
+	case len(payload) <= 125:
+		frame = append(frame, 0x80|byte(len(payload)))
+	default:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	_, err := c.conn.Write(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (c *wsClient) writeText(t *testing.T, text string) {
+	t.Helper()
+
+	c.writeFrame(t, 0x1, []byte(text))
+}
+
+func (c *wsClient) writeJSON(t *testing.T, v interface{}) {
+	t.Helper()
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.writeFrame(t, 0x1, data)
+}
+
+func (c *wsClient) readFrame(t *testing.T) (byte, []byte) {
+	t.Helper()
+
+	var header [2]byte
+	_, err := io.ReadFull(c.reader, header[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	length := int(header[1] & 0x7F)
+	switch length {
+	case 126:
+		var extended [2]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		length = int(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		length = int(binary.BigEndian.Uint64(extended[:]))
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := make([]byte, length)
+	_, err = io.ReadFull(c.reader, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return header[0] & 0x0F, payload
+}
+
+// readText reads the next text message skipping pings.
+func (c *wsClient) readText(t *testing.T) []byte {
+	t.Helper()
+
+	for {
+		opcode, payload := c.readFrame(t)
+		switch opcode {
+		case 0x9:
+			continue
+		case 0x1:
+			return payload
+		default:
+			t.Fatalf("frame should be text, but opcode is %x", opcode)
+		}
+	}
+}
+
+func (c *wsClient) readResponse(t *testing.T) httpapi.PushResponse {
+	t.Helper()
+
+	var response httpapi.PushResponse
+	err := json.Unmarshal(c.readText(t), &response)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return response
+}
+
+// readEvent reads the next event.
+func (c *wsClient) readEvent(t *testing.T) txparser.Event {
+	t.Helper()
+
+	var event txparser.Event
+	err := json.Unmarshal(c.readText(t), &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return event
+}
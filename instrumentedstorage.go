@@ -0,0 +1,196 @@
+package txparser
+
+import (
+	"context"
+	"time"
+)
+
+// Storage labels of storage metrics.
+const (
+	blocksStorageLabel        = "blocks"
+	transactionsStorageLabel  = "transactions"
+	subscriptionsStorageLabel = "subscriptions"
+)
+
+// InstrumentBlockStorage returns the storage collecting latencies and errors of its operations.
+func (m *Metrics) InstrumentBlockStorage(storage BlockStorage) BlockStorage {
+	return &instrumentedBlockStorage{storage: storage, metrics: m}
+}
+
+// InstrumentTransactionStorage returns the storage collecting latencies and errors of its operations.
+func (m *Metrics) InstrumentTransactionStorage(storage TransactionStorage) TransactionStorage {
+	return &instrumentedTransactionStorage{storage: storage, metrics: m}
+}
+
+// InstrumentSubscriptionsStorage returns the storage collecting latencies and errors of its operations.
+func (m *Metrics) InstrumentSubscriptionsStorage(storage SubscriptionsStorage) SubscriptionsStorage {
+	return &instrumentedSubscriptionsStorage{storage: storage, metrics: m}
+}
+
+type instrumentedBlockStorage struct {
+	storage BlockStorage
+	metrics *Metrics
+}
+
+func (s *instrumentedBlockStorage) WithDBTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	err := s.storage.WithDBTransaction(ctx, fn)
+	s.metrics.observeStorage(blocksStorageLabel, "WithDBTransaction", start, err)
+
+	return err
+}
+
+func (s *instrumentedBlockStorage) SaveBlockID(ctx context.Context, blockID int) error {
+	start := time.Now()
+	err := s.storage.SaveBlockID(ctx, blockID)
+	s.metrics.observeStorage(blocksStorageLabel, "SaveBlockID", start, err)
+
+	return err
+}
+
+func (s *instrumentedBlockStorage) GetBlockID(ctx context.Context) (int, error) {
+	start := time.Now()
+	blockID, err := s.storage.GetBlockID(ctx)
+	s.metrics.observeStorage(blocksStorageLabel, "GetBlockID", start, err)
+
+	return blockID, err
+}
+
+type instrumentedTransactionStorage struct {
+	storage TransactionStorage
+	metrics *Metrics
+}
+
+func (s *instrumentedTransactionStorage) WithDBTransaction(
+	ctx context.Context,
+	fn func(ctx context.Context) error,
+) error {
+	start := time.Now()
+	err := s.storage.WithDBTransaction(ctx, fn)
+	s.metrics.observeStorage(transactionsStorageLabel, "WithDBTransaction", start, err)
+
+	return err
+}
+
+func (s *instrumentedTransactionStorage) GetTransactionsByAddress(
+	ctx context.Context,
+	address string,
+) ([]Transaction, error) {
+	start := time.Now()
+	transactions, err := s.storage.GetTransactionsByAddress(ctx, address)
+	s.metrics.observeStorage(transactionsStorageLabel, "GetTransactionsByAddress", start, err)
+
+	return transactions, err
+}
+
+func (s *instrumentedTransactionStorage) QueryTransactions(
+	ctx context.Context,
+	query TransactionsQuery,
+) (*TransactionsPage, error) {
+	start := time.Now()
+	page, err := s.storage.QueryTransactions(ctx, query)
+	s.metrics.observeStorage(transactionsStorageLabel, "QueryTransactions", start, err)
+
+	return page, err
+}
+
+func (s *instrumentedTransactionStorage) SaveTransactions(
+	ctx context.Context,
+	address string,
+	transactions []Transaction,
+) error {
+	start := time.Now()
+	err := s.storage.SaveTransactions(ctx, address, transactions)
+	s.metrics.observeStorage(transactionsStorageLabel, "SaveTransactions", start, err)
+
+	return err
+}
+
+func (s *instrumentedTransactionStorage) DeleteTransactionsByAddress(ctx context.Context, address string) error {
+	start := time.Now()
+	err := s.storage.DeleteTransactionsByAddress(ctx, address)
+	s.metrics.observeStorage(transactionsStorageLabel, "DeleteTransactionsByAddress", start, err)
+
+	return err
+}
+
+func (s *instrumentedTransactionStorage) DeleteTransactionsByBlockRange(
+	ctx context.Context,
+	address string,
+	fromBlock, toBlock int,
+) (int, error) {
+	start := time.Now()
+	deleted, err := s.storage.DeleteTransactionsByBlockRange(ctx, address, fromBlock, toBlock)
+	s.metrics.observeStorage(transactionsStorageLabel, "DeleteTransactionsByBlockRange", start, err)
+
+	return deleted, err
+}
+
+func (s *instrumentedTransactionStorage) Addresses(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	addresses, err := s.storage.Addresses(ctx)
+	s.metrics.observeStorage(transactionsStorageLabel, "Addresses", start, err)
+
+	return addresses, err
+}
+
+type instrumentedSubscriptionsStorage struct {
+	storage SubscriptionsStorage
+	metrics *Metrics
+}
+
+func (s *instrumentedSubscriptionsStorage) PutAddress(ctx context.Context, address string) error {
+	start := time.Now()
+	err := s.storage.PutAddress(ctx, address)
+	s.metrics.observeStorage(subscriptionsStorageLabel, "PutAddress", start, err)
+
+	return err
+}
+
+func (s *instrumentedSubscriptionsStorage) DeleteAddress(ctx context.Context, address string) error {
+	start := time.Now()
+	err := s.storage.DeleteAddress(ctx, address)
+	s.metrics.observeStorage(subscriptionsStorageLabel, "DeleteAddress", start, err)
+
+	return err
+}
+
+func (s *instrumentedSubscriptionsStorage) IsAddressExists(ctx context.Context, address string) (bool, error) {
+	start := time.Now()
+	exists, err := s.storage.IsAddressExists(ctx, address)
+	s.metrics.observeStorage(subscriptionsStorageLabel, "IsAddressExists", start, err)
+
+	return exists, err
+}
+
+func (s *instrumentedSubscriptionsStorage) GetAddresses(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	addresses, err := s.storage.GetAddresses(ctx)
+	s.metrics.observeStorage(subscriptionsStorageLabel, "GetAddresses", start, err)
+
+	return addresses, err
+}
+
+func (s *instrumentedSubscriptionsStorage) PutManagedAddress(ctx context.Context, address string) error {
+	start := time.Now()
+	err := s.storage.PutManagedAddress(ctx, address)
+	s.metrics.observeStorage(subscriptionsStorageLabel, "PutManagedAddress", start, err)
+
+	return err
+}
+
+func (s *instrumentedSubscriptionsStorage) DeleteManagedAddress(ctx context.Context, address string) error {
+	start := time.Now()
+	err := s.storage.DeleteManagedAddress(ctx, address)
+	s.metrics.observeStorage(subscriptionsStorageLabel, "DeleteManagedAddress", start, err)
+
+	return err
+}
+
+func (s *instrumentedSubscriptionsStorage) GetManagedAddresses(ctx context.Context) ([]string, error) {
+	start := time.Now()
+	addresses, err := s.storage.GetManagedAddresses(ctx)
+	s.metrics.observeStorage(subscriptionsStorageLabel, "GetManagedAddresses", start, err)
+
+	return addresses, err
+}
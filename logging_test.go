@@ -0,0 +1,158 @@
+package txparser_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/txparsertest"
+)
+
+func Test_Logger_BlockParsed(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	var out lockedBuffer
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	parser := txparser.NewTXParser(
+		blockStorage,
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		&blocksClient{head: 2},
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+		txparser.WithLogger(slog.New(slog.NewJSONHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		txparser.WithClock(clock),
+	)
+
+	// Act
+	runUntilBlock(t, parser, clock, blockStorage, 2)
+	records := out.records(t)
+
+	// Assert
+	var blocks []float64
+	for _, record := range records {
+		if record["msg"] == "block parsed" && record["level"] == "DEBUG" {
+			blocks = append(blocks, record["block"].(float64))
+		}
+	}
+	if !reflect.DeepEqual(blocks, []float64{1, 2}) {
+		t.Errorf("blocks of records should be %v, but are %v", []float64{1, 2}, blocks)
+	}
+	if records[0]["msg"] != "worker started" || records[len(records)-1]["msg"] != "worker stopped" {
+		t.Errorf("records should start and stop the worker, but are %v", records)
+	}
+}
+
+func Test_Logger_ParseError(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	var out lockedBuffer
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		missingBlocksClient{head: 1},
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+		txparser.WithLogger(slog.New(slog.NewJSONHandler(&out, nil))),
+		txparser.WithClock(clock),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	go func() {
+		_ = parser.RunWorker(ctx, time.Second)
+	}()
+	clock.BlockUntil(1) // the retry of the failed pass
+
+	// Assert
+	var record map[string]any
+	for _, r := range out.records(t) {
+		if r["msg"] == "parse block" {
+			record = r
+			break
+		}
+	}
+	if record == nil {
+		t.Error("parse error should be logged")
+		t.FailNow()
+	}
+	want := map[string]any{"level": "ERROR", "block": float64(1), "head": float64(1), "error": "block not found"}
+	for key, value := range want {
+		if record[key] != value {
+			t.Errorf("record %s should be %v, but is %v", key, value, record[key])
+		}
+	}
+}
+
+func Test_Logger_QuietByDefault(t *testing.T) {
+	// Arrange
+	var out bytes.Buffer
+	previous := log.Writer()
+	log.SetOutput(&out)
+	defer log.SetOutput(previous)
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		failingSubscriptionsStorage{},
+		missingBlocksClient{head: 1},
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// Act
+	err := parser.RunWorker(ctx, time.Millisecond)
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("parser should not log by default, but logged %q", out.String())
+	}
+}
+
+// lockedBuffer is a buffer written by the worker while the test reads it.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// records decodes JSON records of the buffer.
+func (b *lockedBuffer) records(t *testing.T) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		var record map[string]any
+		err := json.Unmarshal([]byte(line), &record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
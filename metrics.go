@@ -0,0 +1,305 @@
+package txparser
+
+import (
+	"context"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultLatencyBuckets are upper bounds in seconds of latency histograms.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// RPC error types of the txparser_rpc_errors_total metric.
+const (
+	RPCErrorTransport = "transport"
+	RPCErrorStatus    = "status"
+	RPCErrorDecode    = "decode"
+	RPCErrorRPC       = "rpc"
+)
+
+// Metrics collects metrics of the parser, the client and storages and serves them
+// in the Prometheus text exposition format. It is safe for concurrent use.
+type Metrics struct {
+	blocksProcessed    *metricFamily
+	blockDuration      *metricFamily
+	transactionsStored *metricFamily
+	parseErrors        *metricFamily
+	headBlock          *metricFamily
+	lastParsedBlock    *metricFamily
+	headLag            *metricFamily
+	subscriptions      *metricFamily
+
+	rpcRequests *metricFamily
+	rpcDuration *metricFamily
+	rpcErrors   *metricFamily
+
+	storageDuration *metricFamily
+	storageErrors   *metricFamily
+
+	families []*metricFamily
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{}
+
+	m.blocksProcessed = m.register("txparser_blocks_processed_total", counterMetric,
+		"Blocks parsed by the worker.")
+	m.blockDuration = m.register("txparser_block_duration_seconds", histogramMetric,
+		"Duration of fetching and saving a block by the worker.")
+	m.transactionsStored = m.register("txparser_transactions_stored_total", counterMetric,
+		"Transactions saved for subscribed addresses, once per address.")
+	m.parseErrors = m.register("txparser_parse_errors_total", counterMetric,
+		"Failed passes of the worker.")
+	m.headBlock = m.register("txparser_head_block", gaugeMetric,
+		"Number of the latest block of the node.")
+	m.lastParsedBlock = m.register("txparser_last_parsed_block", gaugeMetric,
+		"Number of the last block parsed by the worker.")
+	m.headLag = m.register("txparser_head_lag_blocks", gaugeMetric,
+		"Blocks between the head of the node and the last parsed block.")
+	m.subscriptions = m.register("txparser_subscriptions", gaugeMetric,
+		"Subscribed addresses.")
+
+	m.rpcRequests = m.register("txparser_rpc_requests_total", counterMetric,
+		"JSON-RPC requests to the node.", "method")
+	m.rpcDuration = m.register("txparser_rpc_request_duration_seconds", histogramMetric,
+		"Duration of JSON-RPC requests to the node.", "method")
+	m.rpcErrors = m.register("txparser_rpc_errors_total", counterMetric,
+		"Failed JSON-RPC requests to the node by type: transport, status, decode or rpc.", "method", "type")
+
+	m.storageDuration = m.register("txparser_storage_operation_duration_seconds", histogramMetric,
+		"Duration of storage operations.", "storage", "operation")
+	m.storageErrors = m.register("txparser_storage_errors_total", counterMetric,
+		"Failed storage operations.", "storage", "operation")
+
+	return m
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.Write(r.Context(), w)
+}
+
+// Write writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) Write(ctx context.Context, w io.Writer) error {
+	var b strings.Builder
+	for _, family := range m.families {
+		family.write(ctx, &b)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func (m *Metrics) observeBlock(head, blockID int, duration time.Duration, stored int) {
+	m.blocksProcessed.add(1)
+	m.blockDuration.observe(duration.Seconds())
+	m.transactionsStored.add(float64(stored))
+	m.lastParsedBlock.set(float64(blockID))
+	m.headLag.set(float64(head - blockID))
+}
+
+func (m *Metrics) observeHead(head, lastParsedBlock int) {
+	m.headBlock.set(float64(head))
+	m.lastParsedBlock.set(float64(lastParsedBlock))
+	m.headLag.set(float64(head - lastParsedBlock))
+}
+
+func (m *Metrics) observeRPC(method string, start time.Time, errType string) {
+	m.rpcRequests.add(1, method)
+	m.rpcDuration.observe(time.Since(start).Seconds(), method)
+	if errType != "" {
+		m.rpcErrors.add(1, method, errType)
+	}
+}
+
+func (m *Metrics) observeStorage(storage, operation string, start time.Time, err error) {
+	m.storageDuration.observe(time.Since(start).Seconds(), storage, operation)
+	if err != nil {
+		m.storageErrors.add(1, storage, operation)
+	}
+}
+
+func (m *Metrics) register(name string, kind metricKind, help string, labels ...string) *metricFamily {
+	family := &metricFamily{
+		name:   name,
+		kind:   kind,
+		help:   help,
+		labels: labels,
+		series: make(map[string]*metricSeries),
+	}
+	if kind == histogramMetric {
+		family.buckets = DefaultLatencyBuckets
+	}
+	m.families = append(m.families, family)
+
+	return family
+}
+
+type metricKind string
+
+const (
+	counterMetric   metricKind = "counter"
+	gaugeMetric     metricKind = "gauge"
+	histogramMetric metricKind = "histogram"
+)
+
+type metricFamily struct {
+	name    string
+	kind    metricKind
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*metricSeries
+
+	// collect returns the value of a gauge without labels on every write if set
+	collect func(ctx context.Context) (float64, error)
+}
+
+type metricSeries struct {
+	labelValues []string
+
+	value float64
+
+	// Histogram observations by bucket, not cumulative
+	bucketCounts []uint64
+	sum          float64
+	count        uint64
+}
+
+func (f *metricFamily) add(delta float64, labelValues ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.get(labelValues).value += delta
+}
+
+func (f *metricFamily) set(value float64, labelValues ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.get(labelValues).value = value
+}
+
+func (f *metricFamily) observe(value float64, labelValues ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.get(labelValues)
+	if s.bucketCounts == nil {
+		s.bucketCounts = make([]uint64, len(f.buckets))
+	}
+	for i, bound := range f.buckets {
+		if value <= bound {
+			s.bucketCounts[i]++
+			break
+		}
+	}
+	s.sum += value
+	s.count++
+}
+
+func (f *metricFamily) setCollect(collect func(ctx context.Context) (float64, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.collect = collect
+}
+
+func (f *metricFamily) get(labelValues []string) *metricSeries {
+	key := strings.Join(labelValues, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: labelValues}
+		f.series[key] = s
+	}
+
+	return s
+}
+
+func (f *metricFamily) write(ctx context.Context, w *strings.Builder) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.collect != nil {
+		value, err := f.collect(ctx)
+		if err == nil {
+			f.get(nil).value = value
+		}
+	}
+
+	// Metrics without labels are written before the first observation
+	if len(f.labels) == 0 && f.kind != histogramMetric {
+		f.get(nil)
+	}
+
+	w.WriteString("# HELP " + f.name + " " + f.help + "\n")
+	w.WriteString("# TYPE " + f.name + " " + string(f.kind) + "\n")
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != histogramMetric {
+			writeSample(w, f.name, f.labels, s.labelValues, "", s.value)
+			continue
+		}
+
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.bucketCounts[i]
+			writeSample(w, f.name+"_bucket", f.labels, s.labelValues, formatFloat(bound), float64(cumulative))
+		}
+		writeSample(w, f.name+"_bucket", f.labels, s.labelValues, "+Inf", float64(s.count))
+		writeSample(w, f.name+"_sum", f.labels, s.labelValues, "", s.sum)
+		writeSample(w, f.name+"_count", f.labels, s.labelValues, "", float64(s.count))
+	}
+}
+
+// writeSample writes a sample line, le is the bucket bound of histograms.
+func writeSample(w *strings.Builder, name string, labels, labelValues []string, le string, value float64) {
+	w.WriteString(name)
+
+	pairs := make([]string, 0, len(labels)+1)
+	for i, label := range labels {
+		pairs = append(pairs, label+"="+quoteLabelValue(labelValues[i]))
+	}
+	if le != "" {
+		pairs = append(pairs, "le="+quoteLabelValue(le))
+	}
+	if len(pairs) > 0 {
+		w.WriteString("{" + strings.Join(pairs, ",") + "}")
+	}
+
+	w.WriteString(" " + formatFloat(value) + "\n")
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabelValue(value string) string {
+	return `"` + labelValueReplacer.Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,236 @@
+package txparser_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/txparsertest"
+)
+
+func Test_Metrics_Parser(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	ctx := context.Background()
+	metrics := txparser.NewMetrics()
+	blockStorage := metrics.InstrumentBlockStorage(txparser.NewInmemoryBlockStorage())
+	subscriptionsStorage := metrics.InstrumentSubscriptionsStorage(txparser.NewInmemorySubscriptionsStorage())
+	_ = subscriptionsStorage.PutAddress(ctx, "0x123")
+	parser := txparser.NewTXParser(
+		blockStorage,
+		metrics.InstrumentTransactionStorage(txparser.NewInmemoryTransactionsStorage()),
+		subscriptionsStorage,
+		&blocksClient{
+			head: 3,
+			blocks: map[int][]txparser.Transaction{
+				2: {{BlockNumber: "0x2", Hash: "0xabc20", From: "0x123", To: "0x321"}},
+				3: {
+					{BlockNumber: "0x3", Hash: "0xabc30", From: "0x456", To: "0x123"},
+					{BlockNumber: "0x3", Hash: "0xabc31", From: "0x456", To: "0x321"},
+				},
+			},
+		},
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+		txparser.WithMetrics(metrics),
+		txparser.WithClock(clock),
+	)
+
+	// Act
+	runUntilBlock(t, parser, clock, blockStorage, 3)
+	exposition := writeMetrics(t, metrics)
+
+	// Assert
+	for _, line := range []string{
+		"# TYPE txparser_blocks_processed_total counter",
+		"txparser_blocks_processed_total 3",
+		"txparser_block_duration_seconds_count 3",
+		"txparser_transactions_stored_total 2",
+		"txparser_parse_errors_total 0",
+		"txparser_head_block 3",
+		"txparser_last_parsed_block 3",
+		"txparser_head_lag_blocks 0",
+		"txparser_subscriptions 1",
+		`txparser_storage_operation_duration_seconds_count{storage="blocks",operation="SaveBlockID"} 4`,
+		`txparser_storage_operation_duration_seconds_count{storage="transactions",operation="SaveTransactions"} 2`,
+		`txparser_storage_operation_duration_seconds_count{storage="subscriptions",operation="IsAddressExists"} 6`,
+	} {
+		if !containsLine(exposition, line) {
+			t.Errorf("metrics should have line %q, but are:\n%s", line, exposition)
+		}
+	}
+}
+
+func Test_Metrics_Client(t *testing.T) {
+	// Arrange
+	responses := []func(w http.ResponseWriter){
+		func(w http.ResponseWriter) {
+			w.WriteHeader(http.StatusBadGateway)
+		},
+		func(w http.ResponseWriter) {
+			_, _ = w.Write([]byte("{"))
+		},
+	}
+	calls := 0
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls < len(responses) {
+			responses[calls](w)
+			calls++
+			return
+		}
+
+		var call txparser.JSONRPCCall
+		_ = json.NewDecoder(r.Body).Decode(&call)
+		_ = json.NewEncoder(w).Encode(txparser.JSONRPCResponse{
+			Jsonrpc: "2.0",
+			Error:   &txparser.JSONRPCError{Code: -32000, Message: "header not found"},
+			ID:      call.ID,
+		})
+	}))
+	defer node.Close()
+	metrics := txparser.NewMetrics()
+	client := txparser.NewJSONRPCClient(node.Client(), node.URL, txparser.WithClientMetrics(metrics))
+
+	// Act
+	var errs []error
+	for i := 0; i < 2; i++ {
+		_, err := client.CurrentBlockNumber(context.Background())
+		errs = append(errs, err)
+	}
+	_, err := client.GetBlockByNumber(context.Background(), 1)
+	errs = append(errs, err)
+	exposition := writeMetrics(t, metrics)
+
+	// Assert
+	for i, err := range errs {
+		if err == nil {
+			t.Errorf("request %d should fail", i)
+		}
+	}
+	var rpcErr *txparser.JSONRPCError
+	if !errors.As(errs[2], &rpcErr) {
+		t.Errorf("error should be %T, but is %v", rpcErr, errs[2])
+	}
+	for _, line := range []string{
+		`txparser_rpc_requests_total{method="eth_blockNumber"} 2`,
+		`txparser_rpc_requests_total{method="eth_getBlockByNumber"} 1`,
+		`txparser_rpc_request_duration_seconds_bucket{method="eth_blockNumber",le="+Inf"} 2`,
+		`txparser_rpc_request_duration_seconds_count{method="eth_getBlockByNumber"} 1`,
+		`txparser_rpc_errors_total{method="eth_blockNumber",type="decode"} 1`,
+		`txparser_rpc_errors_total{method="eth_blockNumber",type="status"} 1`,
+		`txparser_rpc_errors_total{method="eth_getBlockByNumber",type="rpc"} 1`,
+	} {
+		if !containsLine(exposition, line) {
+			t.Errorf("metrics should have line %q, but are:\n%s", line, exposition)
+		}
+	}
+}
+
+func Test_Metrics_ParseErrors(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	errs := make(chan *txparser.WorkerError, 1)
+	metrics := txparser.NewMetrics()
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	parser := txparser.NewTXParser(
+		blockStorage,
+		txparser.NewInmemoryTransactionsStorage(),
+		failingSubscriptionsStorage{},
+		missingBlocksClient{head: 1},
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+		txparser.WithMetrics(metrics),
+		txparser.WithClock(clock),
+		txparser.WithErrorHandler(func(err *txparser.WorkerError) {
+			errs <- err
+		}),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	go func() {
+		_ = parser.RunWorker(ctx, time.Second)
+	}()
+	clock.BlockUntil(1)
+	clock.Advance((<-errs).RetryIn)
+	clock.BlockUntil(1)
+	<-errs
+	exposition := writeMetrics(t, metrics)
+
+	// Assert
+	if !containsLine(exposition, "txparser_parse_errors_total 2") {
+		t.Errorf("metrics should count parse errors, but are:\n%s", exposition)
+	}
+	if blockIDOf(t, ctx, blockStorage) != 0 {
+		t.Errorf("block id should be %d, but is %d", 0, blockIDOf(t, ctx, blockStorage))
+	}
+	if !containsLine(exposition, "txparser_subscriptions 0") {
+		t.Errorf("metrics should have subscriptions gauge, but are:\n%s", exposition)
+	}
+}
+
+func Test_Metrics_ServeHTTP(t *testing.T) {
+	// Arrange
+	metrics := txparser.NewMetrics()
+	recorder := httptest.NewRecorder()
+
+	// Act
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	// Assert
+	if got := recorder.Header().Get("Content-Type"); got != "text/plain; version=0.0.4; charset=utf-8" {
+		t.Errorf("content type should be the text exposition format, but is %q", got)
+	}
+	if !strings.HasPrefix(recorder.Body.String(), "# HELP txparser_blocks_processed_total ") {
+		t.Errorf("body should start with help of the first metric, but is %q", recorder.Body.String())
+	}
+}
+
+// failingSubscriptionsStorage fails to list subscriptions.
+type failingSubscriptionsStorage struct {
+	txparser.SubscriptionsStorage
+}
+
+func (failingSubscriptionsStorage) GetAddresses(_ context.Context) ([]string, error) {
+	return nil, errors.New("storage is down")
+}
+
+// missingBlocksClient fails to return blocks.
+type missingBlocksClient struct {
+	head int
+}
+
+func (c missingBlocksClient) CurrentBlockNumber(_ context.Context) (int, error) {
+	return c.head, nil
+}
+
+func (c missingBlocksClient) GetBlockByNumber(_ context.Context, number int) (*txparser.Block, error) {
+	return nil, errors.New("block not found")
+}
+
+func writeMetrics(t *testing.T, metrics *txparser.Metrics) string {
+	t.Helper()
+
+	var b strings.Builder
+	err := metrics.Write(context.Background(), &b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b.String()
+}
+
+func containsLine(text, line string) bool {
+	for _, l := range strings.Split(text, "\n") {
+		if l == line {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,252 @@
+package txparser
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultQueryLimit = 100
+	MaxQueryLimit     = 1000
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidQuery  = errors.New("invalid query")
+)
+
+type Direction string
+
+const (
+	DirectionAny      Direction = ""
+	DirectionIncoming Direction = "incoming"
+	DirectionOutgoing Direction = "outgoing"
+	DirectionSelf     Direction = "self"
+)
+
+type SortOrder string
+
+const (
+	SortAscending  SortOrder = "asc"
+	SortDescending SortOrder = "desc"
+)
+
+// TransactionsQuery selects transactions of an address ordered by their position in the chain.
+type TransactionsQuery struct {
+	Address string
+
+	// Cursor continues the query after the last transaction of a previous page.
+	Cursor string
+	Limit  int
+
+	// FromBlock and ToBlock bound the block range inclusively, zero means no bound.
+	FromBlock int
+	ToBlock   int
+
+	Direction Direction
+
+	// MinValue skips transactions with a smaller value in wei.
+	MinValue *big.Int
+
+	Order SortOrder
+}
+
+type TransactionsPage struct {
+	Transactions []Transaction `json:"transactions"`
+
+	// NextCursor is empty on the last page.
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// normalize validates the query and fills in the defaults.
+func (q TransactionsQuery) normalize() (TransactionsQuery, error) {
+	if q.Address == "" {
+		return q, fmt.Errorf("%w: address is required", ErrInvalidQuery)
+	}
+
+	switch {
+	case q.Limit == 0:
+		q.Limit = DefaultQueryLimit
+	case q.Limit < 0 || q.Limit > MaxQueryLimit:
+		return q, fmt.Errorf("%w: limit should be between 1 and %d", ErrInvalidQuery, MaxQueryLimit)
+	}
+
+	if q.FromBlock < 0 || q.ToBlock < 0 || (q.ToBlock > 0 && q.FromBlock > q.ToBlock) {
+		return q, fmt.Errorf("%w: invalid block range", ErrInvalidQuery)
+	}
+
+	switch q.Direction {
+	case DirectionAny, DirectionIncoming, DirectionOutgoing, DirectionSelf:
+	default:
+		return q, fmt.Errorf("%w: unknown direction %q", ErrInvalidQuery, q.Direction)
+	}
+
+	switch q.Order {
+	case "":
+		q.Order = SortAscending
+	case SortAscending, SortDescending:
+	default:
+		return q, fmt.Errorf("%w: unknown sort order %q", ErrInvalidQuery, q.Order)
+	}
+
+	if q.Cursor != "" {
+		_, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return q, err
+		}
+	}
+
+	return q, nil
+}
+
+// matches checks the filters of the query which are not based on the transaction position.
+func (q TransactionsQuery) matches(tx Transaction) bool {
+	from := strings.EqualFold(tx.From, q.Address)
+	to := strings.EqualFold(tx.To, q.Address)
+
+	switch q.Direction {
+	case DirectionIncoming:
+		if !to || from {
+			return false
+		}
+	case DirectionOutgoing:
+		if !from || to {
+			return false
+		}
+	case DirectionSelf:
+		if !from || !to {
+			return false
+		}
+	}
+
+	if q.MinValue != nil && valueOf(tx).Cmp(q.MinValue) < 0 {
+		return false
+	}
+
+	return true
+}
+
+// txPosition orders transactions within the chain. The hash breaks ties
+// between transactions without a known index, the kind and the log index
+// order entries of the same transaction after the transaction itself.
+type txPosition struct {
+	block    int
+	index    int
+	hash     string
+	kind     string
+	logIndex int
+}
+
+func positionOf(tx Transaction) txPosition {
+	return txPosition{
+		block:    blockNumberOf(tx),
+		index:    transactionIndexOf(tx),
+		hash:     tx.Hash,
+		kind:     tx.Kind,
+		logIndex: logIndexOf(tx),
+	}
+}
+
+func (p txPosition) less(other txPosition) bool {
+	if p.block != other.block {
+		return p.block < other.block
+	}
+	if p.index != other.index {
+		return p.index < other.index
+	}
+
+	if p.hash != other.hash {
+		return p.hash < other.hash
+	}
+	if p.kind != other.kind {
+		return p.kind < other.kind
+	}
+
+	return p.logIndex < other.logIndex
+}
+
+// CursorOf returns the cursor pointing right after the transaction.
+func CursorOf(tx Transaction) string {
+	return encodeCursor(positionOf(tx))
+}
+
+// encodeCursor encodes "block:index:hash", followed by ":kind:logIndex" for entries other than transactions.
+func encodeCursor(p txPosition) string {
+	cursor := strconv.Itoa(p.block) + ":" + strconv.Itoa(p.index) + ":" + p.hash
+	if p.kind != "" || p.logIndex != 0 {
+		cursor += ":" + p.kind + ":" + strconv.Itoa(p.logIndex)
+	}
+
+	return base64.RawURLEncoding.EncodeToString([]byte(cursor))
+}
+
+func decodeCursor(cursor string) (txPosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return txPosition{}, ErrInvalidCursor
+	}
+
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 && len(parts) != 5 {
+		return txPosition{}, ErrInvalidCursor
+	}
+
+	block, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return txPosition{}, ErrInvalidCursor
+	}
+
+	index, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return txPosition{}, ErrInvalidCursor
+	}
+
+	position := txPosition{block: block, index: index, hash: parts[2]}
+	if len(parts) == 5 {
+		position.kind = parts[3]
+		position.logIndex, err = strconv.Atoi(parts[4])
+		if err != nil {
+			return txPosition{}, ErrInvalidCursor
+		}
+	}
+
+	return position, nil
+}
+
+// blockNumberOf returns the number of the transaction block or zero if it is unknown.
+func blockNumberOf(tx Transaction) int {
+	return hexToIntOrZero(tx.BlockNumber)
+}
+
+// transactionIndexOf returns the index of the transaction in its block or zero if it is unknown.
+func transactionIndexOf(tx Transaction) int {
+	return hexToIntOrZero(tx.TransactionIndex)
+}
+
+// logIndexOf returns the index of the log of the entry in its block or zero if it is unknown.
+func logIndexOf(tx Transaction) int {
+	return hexToIntOrZero(tx.LogIndex)
+}
+
+// valueOf returns the transaction value in wei or zero if it is unknown.
+func valueOf(tx Transaction) *big.Int {
+	n, err := convertHexToNum(tx.Value)
+	if err != nil {
+		return new(big.Int)
+	}
+
+	return n
+}
+
+func hexToIntOrZero(s string) int {
+	n, err := convertHexToNum(s)
+	if err != nil || !n.IsInt64() {
+		return 0
+	}
+
+	return int(n.Int64())
+}
@@ -0,0 +1,206 @@
+package txparser
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrNotRecorded is returned by ReplayClient for calls missing in the recording.
+var ErrNotRecorded = errors.New("call is not recorded")
+
+// RecordedCall is a line of a recording: a JSON-RPC call of a client and its result or error.
+// Errors other than JSONRPCError are recorded with the zero code.
+type RecordedCall struct {
+	Method string          `json:"method"`
+	Params []any           `json:"params"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *JSONRPCError   `json:"error,omitempty"`
+}
+
+// Recorder writes calls of clients to a writer as JSON lines, ReplayClient serves them back.
+// It is safe for concurrent use, clients recorded by the same Recorder share the writer.
+type Recorder struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{encoder: json.NewEncoder(w)}
+}
+
+// Record returns a client recording the calls of client. Calls interrupted by their context are not recorded.
+// A call fails if it can not be recorded, so that the recording has no gaps.
+func (r *Recorder) Record(client Client) Client {
+	return &recordingClient{client: client, recorder: r}
+}
+
+func (r *Recorder) write(call RecordedCall) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.encoder.Encode(call)
+	if err != nil {
+		return fmt.Errorf("record %s: %w", call.Method, err)
+	}
+
+	return nil
+}
+
+type recordingClient struct {
+	client   Client
+	recorder *Recorder
+}
+
+func (c *recordingClient) CurrentBlockNumber(ctx context.Context) (int, error) {
+	number, err := c.client.CurrentBlockNumber(ctx)
+	recordErr := c.record(ctx, "eth_blockNumber", []any{}, convertNumToHex(number), err)
+	if recordErr != nil {
+		return 0, recordErr
+	}
+
+	return number, err
+}
+
+func (c *recordingClient) GetBlockByNumber(ctx context.Context, number int) (*Block, error) {
+	block, err := c.client.GetBlockByNumber(ctx, number)
+	recordErr := c.record(ctx, "eth_getBlockByNumber", []any{convertNumToHex(number), true}, block, err)
+	if recordErr != nil {
+		return nil, recordErr
+	}
+
+	return block, err
+}
+
+func (c *recordingClient) record(ctx context.Context, method string, params []any, result any, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	call := RecordedCall{Method: method, Params: params}
+	if err != nil {
+		call.Error = &JSONRPCError{Message: err.Error()}
+		var rpcErr *JSONRPCError
+		if errors.As(err, &rpcErr) {
+			call.Error = rpcErr
+		}
+	} else {
+		raw, marshalErr := json.Marshal(result)
+		if marshalErr != nil {
+			return fmt.Errorf("record %s: %w", method, marshalErr)
+		}
+		call.Result = raw
+	}
+
+	return c.recorder.write(call)
+}
+
+// ReplayClient serves calls from a recording of a Recorder. Responses to the same call are served
+// in the recorded order and the last one repeats once they run out. It is safe for concurrent use.
+type ReplayClient struct {
+	mu        sync.Mutex
+	responses map[string][]RecordedCall
+	served    map[string]int
+}
+
+// NewReplayClient reads the recording from r.
+func NewReplayClient(r io.Reader) (*ReplayClient, error) {
+	c := &ReplayClient{
+		responses: make(map[string][]RecordedCall),
+		served:    make(map[string]int),
+	}
+
+	decoder := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var call RecordedCall
+		err := decoder.Decode(&call)
+		if errors.Is(err, io.EOF) {
+			return c, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read recorded call %d: %w", line, err)
+		}
+
+		key, err := callKey(call.Method, call.Params)
+		if err != nil {
+			return nil, fmt.Errorf("read recorded call %d: %w", line, err)
+		}
+		c.responses[key] = append(c.responses[key], call)
+	}
+}
+
+func (c *ReplayClient) CurrentBlockNumber(_ context.Context) (int, error) {
+	var result string
+	err := c.replay("eth_blockNumber", []any{}, &result)
+	if err != nil {
+		return 0, err
+	}
+
+	number, err := convertHexToNum(result)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(number.Int64()), nil
+}
+
+func (c *ReplayClient) GetBlockByNumber(_ context.Context, number int) (*Block, error) {
+	var block *Block
+	err := c.replay("eth_getBlockByNumber", []any{convertNumToHex(number), true}, &block)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, errors.New("invalid response from api")
+	}
+
+	return block, nil
+}
+
+// replay decodes the next recorded result of the call into result or returns its recorded error.
+func (c *ReplayClient) replay(method string, params []any, result any) error {
+	key, err := callKey(method, params)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	responses := c.responses[key]
+	served := c.served[key]
+	if served < len(responses)-1 {
+		c.served[key]++
+	}
+	c.mu.Unlock()
+
+	if len(responses) == 0 {
+		return fmt.Errorf("%w: %s", ErrNotRecorded, key)
+	}
+
+	call := responses[served]
+	if call.Error != nil {
+		if call.Error.Code == 0 {
+			return errors.New(call.Error.Message)
+		}
+		rpcErr := *call.Error
+		return &rpcErr
+	}
+
+	return json.Unmarshal(call.Result, result)
+}
+
+// callKey identifies calls of the method with the params, such as eth_getBlockByNumber["0x1",true].
+func callKey(method string, params []any) (string, error) {
+	if params == nil {
+		params = []any{}
+	}
+
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	return method + string(raw), nil
+}
@@ -0,0 +1,124 @@
+package txparser_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+
+	"txparser"
+	"txparser/txparsertest"
+)
+
+func Test_Recorder_Replay(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	node := txparsertest.NewNode()
+	defer node.Close()
+	node.Mine()
+	node.Send(txparsertest.Tx{Hash: "0xa20", From: "0x123", To: "0x321"})
+	node.Mine()
+	node.Send(txparsertest.Tx{Hash: "0xa30", From: "0x456", To: "0x123"})
+	node.Mine()
+
+	var recording bytes.Buffer
+	recorded := backfillHashes(t, txparser.NewRecorder(&recording).Record(node.Client()))
+	node.Close()
+
+	// Act
+	client, err := txparser.NewReplayClient(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	replayed := backfillHashes(t, client)
+	_, missingErr := client.GetBlockByNumber(ctx, 4)
+
+	// Assert
+	if strings.Count(recording.String(), "\n") != 4 {
+		t.Errorf("recording should have %d lines, but is %q", 4, recording.String())
+	}
+	if !areSlicesEqual([]string{"0xa20", "0xa30"}, recorded) {
+		t.Errorf("recorded transactions should be %v, but are %v", []string{"0xa20", "0xa30"}, recorded)
+	}
+	if !areSlicesEqual(recorded, replayed) {
+		t.Errorf("replayed transactions should be %v, but are %v", recorded, replayed)
+	}
+	if !errors.Is(missingErr, txparser.ErrNotRecorded) {
+		t.Errorf("error should be %v, but is %v", txparser.ErrNotRecorded, missingErr)
+	}
+}
+
+func Test_ReplayClient_Sequence(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	recording := strings.Join([]string{
+		`{"method":"eth_blockNumber","params":[],"result":"0x1"}`,
+		`{"method":"eth_blockNumber","params":[],"error":{"code":0,"message":"connection reset"}}`,
+		`{"method":"eth_getBlockByNumber","params":["0x5",true],"error":{"code":-32000,"message":"header not found"}}`,
+		`{"method":"eth_blockNumber","params":[],"result":"0x5"}`,
+	}, "\n")
+	client, err := txparser.NewReplayClient(strings.NewReader(recording))
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Act
+	first, _ := client.CurrentBlockNumber(ctx)
+	_, resetErr := client.CurrentBlockNumber(ctx)
+	last, _ := client.CurrentBlockNumber(ctx)
+	repeated, _ := client.CurrentBlockNumber(ctx)
+	_, blockErr := client.GetBlockByNumber(ctx, 5)
+
+	// Assert
+	if first != 1 || last != 5 || repeated != 5 {
+		t.Errorf("heads should be 1, 5 and 5, but are %d, %d and %d", first, last, repeated)
+	}
+	if resetErr == nil || resetErr.Error() != "connection reset" {
+		t.Errorf("error should be %q, but is %v", "connection reset", resetErr)
+	}
+	var rpcErr *txparser.JSONRPCError
+	if !errors.As(blockErr, &rpcErr) || rpcErr.Code != -32000 {
+		t.Errorf("error should be a json-rpc error %d, but is %v", -32000, blockErr)
+	}
+}
+
+func Test_NewReplayClient_Invalid(t *testing.T) {
+	// Act
+	_, err := txparser.NewReplayClient(strings.NewReader(`{"method":"eth_blockNumber","params":[]}` + "\n{"))
+
+	// Assert
+	if err == nil || !strings.Contains(err.Error(), "call 2") {
+		t.Errorf("error should report the call 2, but is %v", err)
+	}
+}
+
+// backfillHashes parses blocks 1 to the head for 0x123 and returns hashes of its transactions.
+func backfillHashes(t *testing.T, client txparser.Client) []string {
+	t.Helper()
+
+	ctx := context.Background()
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		client,
+	)
+	parser.Subscribe("0x123")
+	head, err := client.CurrentBlockNumber(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = parser.Backfill(ctx, 1, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashes := hashesOf(parser.GetTransactions("0x123"))
+	sort.Strings(hashes)
+
+	return hashes
+}
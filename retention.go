@@ -0,0 +1,284 @@
+package txparser
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// transactionOverhead approximates the memory taken by a stored transaction
+// besides its strings: string headers, the index entry and the hash set entry.
+const transactionOverhead = 200
+
+// RetentionPolicy limits stored transactions, zero values disable a limit.
+// Limits are enforced at block granularity: transactions of a block are either kept or deleted together.
+type RetentionPolicy struct {
+	// MaxPerAddress keeps about this many of the latest transactions of every address.
+	MaxPerAddress int
+
+	// MaxAgeBlocks keeps transactions of this many of the latest parsed blocks.
+	MaxAgeBlocks int
+
+	// MaxBytes caps the approximate memory taken by all stored transactions.
+	MaxBytes int64
+}
+
+type PruneStats struct {
+	Runs                int           `json:"runs"`
+	DeletedTransactions int           `json:"deletedTransactions"`
+	LastRunAt           time.Time     `json:"lastRunAt"`
+	LastDuration        time.Duration `json:"lastDuration"`
+	LastDeleted         int           `json:"lastDeleted"`
+	LastError           string        `json:"lastError,omitempty"`
+}
+
+// Pruner deletes stored transactions exceeding the retention policy.
+type Pruner struct {
+	transactionsStorage TransactionStorage
+	blocksStorage       BlockStorage
+	policy              RetentionPolicy
+	logger              *slog.Logger
+	clock               Clock
+
+	worker *worker
+
+	mu    sync.Mutex
+	stats PruneStats
+}
+
+type PrunerOption func(*Pruner)
+
+// WithPrunerLogger sets the logger of pruning runs, records are dropped by default or if it is nil.
+func WithPrunerLogger(logger *slog.Logger) PrunerOption {
+	return func(p *Pruner) {
+		if logger != nil {
+			p.logger = logger
+		}
+	}
+}
+
+// WithPrunerClock sets the clock of the worker and the statistics, SystemClock by default.
+func WithPrunerClock(clock Clock) PrunerOption {
+	return func(p *Pruner) {
+		p.clock = clock
+	}
+}
+
+func NewPruner(
+	transactionStorage TransactionStorage,
+	blockStorage BlockStorage,
+	policy RetentionPolicy,
+	opts ...PrunerOption,
+) *Pruner {
+	p := &Pruner{
+		transactionsStorage: transactionStorage,
+		blocksStorage:       blockStorage,
+		policy:              policy,
+		logger:              discardLogger(),
+		clock:               SystemClock(),
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	p.worker = newWorker(func(ctx context.Context) error {
+		deleted, err := p.Prune(ctx)
+		if err != nil {
+			p.logger.ErrorContext(ctx, "prune transactions", "deleted", deleted, "error", err)
+			return err
+		}
+		p.logger.DebugContext(ctx, "transactions pruned", "deleted", deleted)
+
+		return nil
+	}, nil)
+	p.worker.clock = p.clock
+
+	return p
+}
+
+// Run prunes transactions every period until ctx is done.
+func (p *Pruner) Run(ctx context.Context, period time.Duration) {
+	_ = p.worker.Run(ctx, period, nil)
+}
+
+// Stats returns the pruning statistics.
+func (p *Pruner) Stats() PruneStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.stats
+}
+
+// Prune enforces the retention policy once and returns the number of deleted transactions.
+func (p *Pruner) Prune(ctx context.Context) (int, error) {
+	start := p.clock.Now()
+
+	deleted, err := p.prune(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.stats.Runs++
+	p.stats.DeletedTransactions += deleted
+	p.stats.LastRunAt = start
+	p.stats.LastDuration = p.clock.Now().Sub(start)
+	p.stats.LastDeleted = deleted
+	p.stats.LastError = ""
+	if err != nil {
+		p.stats.LastError = err.Error()
+	}
+
+	return deleted, err
+}
+
+func (p *Pruner) prune(ctx context.Context) (int, error) {
+	addresses, err := p.transactionsStorage.Addresses(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+
+	if p.policy.MaxAgeBlocks > 0 {
+		lastBlock, err := p.blocksStorage.GetBlockID(ctx)
+		if err != nil {
+			return 0, err
+		}
+		cutoff := lastBlock - p.policy.MaxAgeBlocks
+		if cutoff >= 0 {
+			n, err := p.deleteUpTo(ctx, addresses, cutoff)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+
+	if p.policy.MaxPerAddress > 0 {
+		for _, address := range addresses {
+			n, err := p.pruneAddress(ctx, address)
+			deleted += n
+			if err != nil {
+				return deleted, err
+			}
+		}
+	}
+
+	if p.policy.MaxBytes > 0 {
+		n, err := p.pruneBytes(ctx, addresses)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	return deleted, nil
+}
+
+// pruneAddress deletes blocks older than the block of the oldest transaction within MaxPerAddress latest ones.
+func (p *Pruner) pruneAddress(ctx context.Context, address string) (int, error) {
+	query := TransactionsQuery{
+		Address: address,
+		Order:   SortDescending,
+		Limit:   MaxQueryLimit,
+	}
+
+	seen := 0
+	for {
+		page, err := p.transactionsStorage.QueryTransactions(ctx, query)
+		if err != nil {
+			return 0, err
+		}
+
+		if seen+len(page.Transactions) >= p.policy.MaxPerAddress {
+			oldestKept := page.Transactions[p.policy.MaxPerAddress-seen-1]
+			return p.deleteUpTo(ctx, []string{address}, blockNumberOf(oldestKept)-1)
+		}
+
+		if page.NextCursor == "" {
+			return 0, nil
+		}
+
+		seen += len(page.Transactions)
+		query.Cursor = page.NextCursor
+	}
+}
+
+// pruneBytes deletes the oldest blocks of all addresses until the stored transactions fit into MaxBytes.
+func (p *Pruner) pruneBytes(ctx context.Context, addresses []string) (int, error) {
+	var total int64
+	bytesByBlock := make(map[int]int64)
+
+	for _, address := range addresses {
+		query := TransactionsQuery{Address: address, Limit: MaxQueryLimit}
+		for {
+			page, err := p.transactionsStorage.QueryTransactions(ctx, query)
+			if err != nil {
+				return 0, err
+			}
+
+			for _, tx := range page.Transactions {
+				size := approximateSize(tx)
+				bytesByBlock[blockNumberOf(tx)] += size
+				total += size
+			}
+
+			if page.NextCursor == "" {
+				break
+			}
+			query.Cursor = page.NextCursor
+		}
+	}
+
+	if total <= p.policy.MaxBytes {
+		return 0, nil
+	}
+
+	blocks := make([]int, 0, len(bytesByBlock))
+	for block := range bytesByBlock {
+		blocks = append(blocks, block)
+	}
+	sort.Ints(blocks)
+
+	cutoff := 0
+	for _, block := range blocks {
+		total -= bytesByBlock[block]
+		cutoff = block
+		if total <= p.policy.MaxBytes {
+			break
+		}
+	}
+
+	return p.deleteUpTo(ctx, addresses, cutoff)
+}
+
+func (p *Pruner) deleteUpTo(ctx context.Context, addresses []string, toBlock int) (int, error) {
+	if toBlock < 0 {
+		return 0, nil
+	}
+
+	deleted := 0
+	for _, address := range addresses {
+		n, err := p.transactionsStorage.DeleteTransactionsByBlockRange(ctx, address, 0, toBlock)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	return deleted, nil
+}
+
+func approximateSize(tx Transaction) int64 {
+	return int64(transactionOverhead +
+		len(tx.BlockNumber) +
+		len(tx.BlockHash) +
+		len(tx.Hash) +
+		len(tx.TransactionIndex) +
+		len(tx.From) +
+		len(tx.To) +
+		len(tx.Value))
+}
@@ -0,0 +1,95 @@
+package txparser_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"txparser"
+)
+
+func Test_Pruner_Prune(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      txparser.RetentionPolicy
+		wantDeleted int
+		want123     int
+		want321     int
+	}{
+		{
+			name:        "no limits",
+			policy:      txparser.RetentionPolicy{},
+			wantDeleted: 0,
+			want123:     10,
+			want321:     5,
+		},
+		{
+			name:        "max per address",
+			policy:      txparser.RetentionPolicy{MaxPerAddress: 3},
+			wantDeleted: 9,
+			want123:     3,
+			want321:     3,
+		},
+		{
+			name:        "max age",
+			policy:      txparser.RetentionPolicy{MaxAgeBlocks: 4},
+			wantDeleted: 9,
+			want123:     4,
+			want321:     2,
+		},
+		{
+			name: "max bytes",
+			// Fits the transactions of the two latest blocks only
+			policy:      txparser.RetentionPolicy{MaxBytes: 3 * 290},
+			wantDeleted: 12,
+			want123:     2,
+			want321:     1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			txStorage := txparser.NewInmemoryTransactionsStorage()
+			blockStorage := txparser.NewInmemoryBlockStorage()
+			_ = blockStorage.SaveBlockID(ctx, 10)
+			for block := 1; block <= 10; block++ {
+				tx := txparser.Transaction{
+					BlockNumber: fmt.Sprintf("0x%x", block),
+					Hash:        fmt.Sprintf("0xa%x", block),
+					From:        "0x123",
+					To:          "0x321",
+				}
+				_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{tx})
+				if block%2 == 0 {
+					_ = txStorage.SaveTransactions(ctx, "0x321", []txparser.Transaction{tx})
+				}
+			}
+			pruner := txparser.NewPruner(txStorage, blockStorage, test.policy)
+
+			// Act
+			deleted, err := pruner.Prune(ctx)
+
+			// Assert
+			if err != nil {
+				t.Error(err)
+			}
+			if deleted != test.wantDeleted {
+				t.Errorf("deleted should be %d, but is %d", test.wantDeleted, deleted)
+			}
+			transactions, _ := txStorage.GetTransactionsByAddress(ctx, "0x123")
+			if len(transactions) != test.want123 {
+				t.Errorf("0x123 should have %d transaction(s), but has %d", test.want123, len(transactions))
+			}
+			transactions, _ = txStorage.GetTransactionsByAddress(ctx, "0x321")
+			if len(transactions) != test.want321 {
+				t.Errorf("0x321 should have %d transaction(s), but has %d", test.want321, len(transactions))
+			}
+			stats := pruner.Stats()
+			if stats.Runs != 1 || stats.DeletedTransactions != test.wantDeleted || stats.LastError != "" {
+				t.Errorf("unexpected stats %+v", stats)
+			}
+		})
+	}
+}
@@ -0,0 +1,852 @@
+package txparser
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+type SQLDialect string
+
+const (
+	DialectPostgres SQLDialect = "postgres"
+	DialectSQLite   SQLDialect = "sqlite"
+)
+
+func (d SQLDialect) validate() error {
+	switch d {
+	case DialectPostgres, DialectSQLite:
+		return nil
+	default:
+		return fmt.Errorf("unsupported sql dialect %q", d)
+	}
+}
+
+// rebind replaces '?' placeholders with the ones of the dialect.
+func (d SQLDialect) rebind(query string) string {
+	if d != DialectPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+
+	return b.String()
+}
+
+func (d SQLDialect) serialPrimaryKey() string {
+	if d == DialectPostgres {
+		return "BIGSERIAL PRIMARY KEY"
+	}
+
+	return "INTEGER PRIMARY KEY AUTOINCREMENT"
+}
+
+type sqlMigration func(d SQLDialect) []string
+
+// sqlMigrations are applied in order, the index of a migration plus one is its schema version.
+// Applied migrations must never be changed, add a new one instead.
+var sqlMigrations = []sqlMigration{
+	func(d SQLDialect) []string {
+		return []string{
+			`CREATE TABLE txparser_blocks (
+				id INTEGER PRIMARY KEY,
+				block_id BIGINT NOT NULL
+			)`,
+			`CREATE TABLE txparser_subscriptions (
+				address TEXT PRIMARY KEY
+			)`,
+			`CREATE TABLE txparser_transactions (
+				id ` + d.serialPrimaryKey() + `,
+				address TEXT NOT NULL,
+				hash TEXT NOT NULL,
+				block_number BIGINT NOT NULL,
+				block_number_hex TEXT NOT NULL,
+				block_hash TEXT NOT NULL,
+				from_address TEXT NOT NULL,
+				to_address TEXT NOT NULL,
+				value TEXT NOT NULL
+			)`,
+			`CREATE INDEX txparser_transactions_address_block_idx
+				ON txparser_transactions (address, block_number)`,
+			`CREATE INDEX txparser_transactions_hash_idx ON txparser_transactions (hash)`,
+			`CREATE INDEX txparser_transactions_block_idx ON txparser_transactions (block_number)`,
+		}
+	},
+	func(_ SQLDialect) []string {
+		return []string{
+			`DELETE FROM txparser_transactions WHERE id NOT IN (
+				SELECT MIN(id) FROM txparser_transactions GROUP BY address, hash
+			)`,
+			`CREATE UNIQUE INDEX txparser_transactions_address_hash_idx
+				ON txparser_transactions (address, hash)`,
+		}
+	},
+	func(_ SQLDialect) []string {
+		return []string{
+			`ALTER TABLE txparser_transactions ADD COLUMN transaction_index BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE txparser_transactions ADD COLUMN transaction_index_hex TEXT NOT NULL DEFAULT ''`,
+			`DROP INDEX txparser_transactions_address_block_idx`,
+			`CREATE INDEX txparser_transactions_address_position_idx
+				ON txparser_transactions (address, block_number, transaction_index, hash)`,
+		}
+	},
+	func(_ SQLDialect) []string {
+		return []string{
+			`CREATE TABLE txparser_cursors (
+				consumer TEXT NOT NULL,
+				address TEXT NOT NULL,
+				cursor TEXT NOT NULL,
+				PRIMARY KEY (consumer, address)
+			)`,
+		}
+	},
+	func(_ SQLDialect) []string {
+		return []string{
+			`CREATE TABLE txparser_api_keys (
+				key_hash TEXT PRIMARY KEY,
+				tenant_id TEXT NOT NULL,
+				max_subscriptions BIGINT NOT NULL
+			)`,
+			`CREATE TABLE txparser_tenant_subscriptions (
+				tenant_id TEXT NOT NULL,
+				address TEXT NOT NULL,
+				PRIMARY KEY (tenant_id, address)
+			)`,
+		}
+	},
+	func(_ SQLDialect) []string {
+		return []string{
+			`ALTER TABLE txparser_transactions ADD COLUMN kind TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE txparser_transactions ADD COLUMN log_index BIGINT NOT NULL DEFAULT 0`,
+			`ALTER TABLE txparser_transactions ADD COLUMN log_index_hex TEXT NOT NULL DEFAULT ''`,
+			`DROP INDEX txparser_transactions_address_hash_idx`,
+			`CREATE UNIQUE INDEX txparser_transactions_address_entry_idx
+				ON txparser_transactions (address, hash, kind, log_index)`,
+			`DROP INDEX txparser_transactions_address_position_idx`,
+			`CREATE INDEX txparser_transactions_address_position_idx
+				ON txparser_transactions (address, block_number, transaction_index, hash, kind, log_index)`,
+		}
+	},
+	func(_ SQLDialect) []string {
+		return []string{
+			`CREATE TABLE txparser_tenants (
+				tenant_id TEXT PRIMARY KEY
+			)`,
+		}
+	},
+	func(_ SQLDialect) []string {
+		return []string{
+			`ALTER TABLE txparser_subscriptions ADD COLUMN managed BIGINT NOT NULL DEFAULT 0`,
+		}
+	},
+}
+
+// MigrateSQL brings the database schema used by the SQL storages up to date.
+func MigrateSQL(ctx context.Context, db *sql.DB, dialect SQLDialect) error {
+	err := dialect.validate()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS txparser_schema_migrations (
+		version INTEGER PRIMARY KEY
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var version int
+	err = db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM txparser_schema_migrations`).Scan(&version)
+	if err != nil {
+		return err
+	}
+
+	for i := version; i < len(sqlMigrations); i++ {
+		err = applySQLMigration(ctx, db, dialect, i+1, sqlMigrations[i])
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func applySQLMigration(ctx context.Context, db *sql.DB, dialect SQLDialect, version int, migration sqlMigration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, statement := range migration(dialect) {
+		_, err = tx.ExecContext(ctx, statement)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		dialect.rebind(`INSERT INTO txparser_schema_migrations (version) VALUES (?)`),
+		version,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type sqlTxKey struct {
+	db *sql.DB
+}
+
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// sqlConn is shared by the SQL storages. Storages over the same *sql.DB
+// join a single sql.Tx carried in the context by WithDBTransaction.
+type sqlConn struct {
+	db      *sql.DB
+	dialect SQLDialect
+	logger  *slog.Logger
+}
+
+type SQLStorageOption func(*sqlConn)
+
+// WithSQLLogger sets the logger of errors of methods returning no error,
+// records are dropped by default or if it is nil.
+func WithSQLLogger(logger *slog.Logger) SQLStorageOption {
+	return func(c *sqlConn) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+func newSQLConn(db *sql.DB, dialect SQLDialect, opts []SQLStorageOption) sqlConn {
+	c := sqlConn{db: db, dialect: dialect, logger: discardLogger()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return c
+}
+
+func (c sqlConn) executor(ctx context.Context) sqlExecutor {
+	if tx, ok := ctx.Value(sqlTxKey{db: c.db}).(*sql.Tx); ok {
+		return tx
+	}
+
+	return c.db
+}
+
+func (c sqlConn) exec(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return c.executor(ctx).ExecContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c sqlConn) query(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return c.executor(ctx).QueryContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c sqlConn) queryRow(ctx context.Context, query string, args ...any) *sql.Row {
+	return c.executor(ctx).QueryRowContext(ctx, c.dialect.rebind(query), args...)
+}
+
+func (c sqlConn) WithDBTransaction(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(sqlTxKey{db: c.db}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+
+	err = fn(context.WithValue(ctx, sqlTxKey{db: c.db}, tx))
+	if err != nil {
+		rollbackErr := tx.Rollback()
+		if rollbackErr != nil {
+			return errors.Join(err, rollbackErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+type SQLBlockStorage struct {
+	sqlConn
+}
+
+func NewSQLBlockStorage(db *sql.DB, dialect SQLDialect, opts ...SQLStorageOption) *SQLBlockStorage {
+	return &SQLBlockStorage{newSQLConn(db, dialect, opts)}
+}
+
+func (s *SQLBlockStorage) SaveBlockID(ctx context.Context, blockID int) error {
+	_, err := s.exec(
+		ctx,
+		`INSERT INTO txparser_blocks (id, block_id) VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET block_id = excluded.block_id`,
+		blockID,
+	)
+
+	return err
+}
+
+func (s *SQLBlockStorage) GetBlockID(ctx context.Context) (int, error) {
+	var blockID int
+
+	err := s.queryRow(ctx, `SELECT block_id FROM txparser_blocks WHERE id = 1`).Scan(&blockID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get block id: %w", err)
+	}
+
+	return blockID, nil
+}
+
+type SQLSubscriptionsStorage struct {
+	sqlConn
+}
+
+func NewSQLSubscriptionsStorage(db *sql.DB, dialect SQLDialect, opts ...SQLStorageOption) *SQLSubscriptionsStorage {
+	return &SQLSubscriptionsStorage{newSQLConn(db, dialect, opts)}
+}
+
+func (s *SQLSubscriptionsStorage) PutAddress(ctx context.Context, address string) error {
+	_, err := s.exec(
+		ctx,
+		`INSERT INTO txparser_subscriptions (address, managed) VALUES (?, 0)
+			ON CONFLICT (address) DO UPDATE SET managed = 0`,
+		address,
+	)
+
+	return err
+}
+
+func (s *SQLSubscriptionsStorage) DeleteAddress(ctx context.Context, address string) error {
+	_, err := s.exec(ctx, `DELETE FROM txparser_subscriptions WHERE address = ?`, address)
+
+	return err
+}
+
+func (s *SQLSubscriptionsStorage) IsAddressExists(ctx context.Context, address string) (bool, error) {
+	var exists int
+
+	err := s.queryRow(ctx, `SELECT 1 FROM txparser_subscriptions WHERE address = ?`, address).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check subscription of %s: %w", address, err)
+	}
+
+	return true, nil
+}
+
+func (s *SQLSubscriptionsStorage) GetAddresses(ctx context.Context) ([]string, error) {
+	return s.list(ctx, `SELECT address FROM txparser_subscriptions ORDER BY address`)
+}
+
+func (s *SQLSubscriptionsStorage) PutManagedAddress(ctx context.Context, address string) error {
+	_, err := s.exec(
+		ctx,
+		`INSERT INTO txparser_subscriptions (address, managed) VALUES (?, 1) ON CONFLICT (address) DO NOTHING`,
+		address,
+	)
+
+	return err
+}
+
+func (s *SQLSubscriptionsStorage) DeleteManagedAddress(ctx context.Context, address string) error {
+	_, err := s.exec(ctx, `DELETE FROM txparser_subscriptions WHERE address = ? AND managed = 1`, address)
+
+	return err
+}
+
+func (s *SQLSubscriptionsStorage) GetManagedAddresses(ctx context.Context) ([]string, error) {
+	return s.list(ctx, `SELECT address FROM txparser_subscriptions WHERE managed = 1 ORDER BY address`)
+}
+
+func (s *SQLSubscriptionsStorage) list(ctx context.Context, query string) ([]string, error) {
+	rows, err := s.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make([]string, 0)
+	for rows.Next() {
+		var address string
+		err = rows.Scan(&address)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+
+	return addresses, rows.Err()
+}
+
+type SQLCursorStorage struct {
+	sqlConn
+}
+
+func NewSQLCursorStorage(db *sql.DB, dialect SQLDialect, opts ...SQLStorageOption) *SQLCursorStorage {
+	return &SQLCursorStorage{newSQLConn(db, dialect, opts)}
+}
+
+func (s *SQLCursorStorage) GetCursor(ctx context.Context, consumer, address string) (string, error) {
+	var cursor string
+
+	err := s.queryRow(
+		ctx,
+		`SELECT cursor FROM txparser_cursors WHERE consumer = ? AND address = ?`,
+		consumer,
+		address,
+	).Scan(&cursor)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return cursor, err
+}
+
+func (s *SQLCursorStorage) SaveCursor(ctx context.Context, consumer, address, cursor string) error {
+	_, err := s.exec(
+		ctx,
+		`INSERT INTO txparser_cursors (consumer, address, cursor) VALUES (?, ?, ?)
+		ON CONFLICT (consumer, address) DO UPDATE SET cursor = excluded.cursor`,
+		consumer,
+		address,
+		cursor,
+	)
+
+	return err
+}
+
+type SQLAPIKeyStorage struct {
+	sqlConn
+}
+
+func NewSQLAPIKeyStorage(db *sql.DB, dialect SQLDialect, opts ...SQLStorageOption) *SQLAPIKeyStorage {
+	return &SQLAPIKeyStorage{newSQLConn(db, dialect, opts)}
+}
+
+func (s *SQLAPIKeyStorage) PutAPIKey(ctx context.Context, keyHash string, tenant Tenant) error {
+	_, err := s.exec(
+		ctx,
+		`INSERT INTO txparser_api_keys (key_hash, tenant_id, max_subscriptions) VALUES (?, ?, ?)
+		ON CONFLICT (key_hash) DO UPDATE
+		SET tenant_id = excluded.tenant_id, max_subscriptions = excluded.max_subscriptions`,
+		keyHash,
+		tenant.ID,
+		tenant.MaxSubscriptions,
+	)
+
+	return err
+}
+
+func (s *SQLAPIKeyStorage) GetTenantByAPIKey(ctx context.Context, keyHash string) (*Tenant, error) {
+	var tenant Tenant
+
+	err := s.queryRow(
+		ctx,
+		`SELECT tenant_id, max_subscriptions FROM txparser_api_keys WHERE key_hash = ?`,
+		keyHash,
+	).Scan(&tenant.ID, &tenant.MaxSubscriptions)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUnknownAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &tenant, nil
+}
+
+type SQLTenantSubscriptionsStorage struct {
+	sqlConn
+}
+
+func NewSQLTenantSubscriptionsStorage(
+	db *sql.DB,
+	dialect SQLDialect,
+	opts ...SQLStorageOption,
+) *SQLTenantSubscriptionsStorage {
+	return &SQLTenantSubscriptionsStorage{newSQLConn(db, dialect, opts)}
+}
+
+func (s *SQLTenantSubscriptionsStorage) PutTenantAddress(
+	ctx context.Context,
+	tenantID, address string,
+	maxAddresses int,
+) error {
+	return s.WithDBTransaction(ctx, func(ctx context.Context) error {
+		// Locks the tenant row until the end of the transaction, so concurrent subscribes
+		// of the tenant count its addresses one after another and can not exceed the quota
+		_, err := s.exec(
+			ctx,
+			`INSERT INTO txparser_tenants (tenant_id) VALUES (?)
+			ON CONFLICT (tenant_id) DO UPDATE SET tenant_id = excluded.tenant_id`,
+			tenantID,
+		)
+		if err != nil {
+			return err
+		}
+
+		exists, err := s.IsTenantAddressExists(ctx, tenantID, address)
+		if err != nil || exists {
+			return err
+		}
+
+		if maxAddresses > 0 {
+			var count int
+			err := s.queryRow(
+				ctx,
+				`SELECT COUNT(*) FROM txparser_tenant_subscriptions WHERE tenant_id = ?`,
+				tenantID,
+			).Scan(&count)
+			if err != nil {
+				return err
+			}
+
+			if count >= maxAddresses {
+				return ErrSubscriptionQuotaExceeded
+			}
+		}
+
+		_, err = s.exec(
+			ctx,
+			`INSERT INTO txparser_tenant_subscriptions (tenant_id, address) VALUES (?, ?)
+			ON CONFLICT (tenant_id, address) DO NOTHING`,
+			tenantID,
+			address,
+		)
+
+		return err
+	})
+}
+
+func (s *SQLTenantSubscriptionsStorage) IsTenantAddressExists(
+	ctx context.Context,
+	tenantID, address string,
+) (bool, error) {
+	var exists int
+
+	err := s.queryRow(
+		ctx,
+		`SELECT 1 FROM txparser_tenant_subscriptions WHERE tenant_id = ? AND address = ?`,
+		tenantID,
+		address,
+	).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("check subscription of %s by tenant %s: %w", address, tenantID, err)
+	}
+
+	return true, nil
+}
+
+func (s *SQLTenantSubscriptionsStorage) GetTenantAddresses(ctx context.Context, tenantID string) ([]string, error) {
+	rows, err := s.query(
+		ctx,
+		`SELECT address FROM txparser_tenant_subscriptions WHERE tenant_id = ? ORDER BY address`,
+		tenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := make([]string, 0)
+	for rows.Next() {
+		var address string
+		err = rows.Scan(&address)
+		if err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, address)
+	}
+
+	return addresses, rows.Err()
+}
+
+type SQLTransactionsStorage struct {
+	sqlConn
+}
+
+func NewSQLTransactionsStorage(db *sql.DB, dialect SQLDialect, opts ...SQLStorageOption) *SQLTransactionsStorage {
+	return &SQLTransactionsStorage{newSQLConn(db, dialect, opts)}
+}
+
+func (s *SQLTransactionsStorage) GetTransactionsByAddress(
+	ctx context.Context,
+	address string,
+) ([]Transaction, error) {
+	return s.selectTransactions(
+		ctx,
+		`WHERE address = ? ORDER BY block_number, transaction_index, hash, kind, log_index`,
+		address,
+	)
+}
+
+func (s *SQLTransactionsStorage) QueryTransactions(
+	ctx context.Context,
+	query TransactionsQuery,
+) (*TransactionsPage, error) {
+	query, err := query.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"address = ?"}
+	args := []any{query.Address}
+
+	if query.FromBlock > 0 {
+		conditions = append(conditions, "block_number >= ?")
+		args = append(args, query.FromBlock)
+	}
+	if query.ToBlock > 0 {
+		conditions = append(conditions, "block_number <= ?")
+		args = append(args, query.ToBlock)
+	}
+
+	switch query.Direction {
+	case DirectionIncoming:
+		conditions = append(conditions, "LOWER(to_address) = LOWER(?) AND LOWER(from_address) <> LOWER(?)")
+		args = append(args, query.Address, query.Address)
+	case DirectionOutgoing:
+		conditions = append(conditions, "LOWER(from_address) = LOWER(?) AND LOWER(to_address) <> LOWER(?)")
+		args = append(args, query.Address, query.Address)
+	case DirectionSelf:
+		conditions = append(conditions, "LOWER(from_address) = LOWER(?) AND LOWER(to_address) = LOWER(?)")
+		args = append(args, query.Address, query.Address)
+	}
+
+	comparison, order := ">", "ASC"
+	if query.Order == SortDescending {
+		comparison, order = "<", "DESC"
+	}
+
+	page := &TransactionsPage{Transactions: []Transaction{}}
+	cursor := query.Cursor
+
+	// Values are stored as hex strings, so the minimum value is checked here
+	// and batches are fetched until the page and one more transaction are found.
+	for {
+		batchConditions := append([]string{}, conditions...)
+		batchArgs := append([]any{}, args...)
+		if cursor != "" {
+			position, err := decodeCursor(cursor)
+			if err != nil {
+				return nil, err
+			}
+
+			batchConditions = append(batchConditions,
+				"(block_number, transaction_index, hash, kind, log_index) "+comparison+" (?, ?, ?, ?, ?)")
+			batchArgs = append(batchArgs, position.block, position.index, position.hash, position.kind, position.logIndex)
+		}
+
+		batchArgs = append(batchArgs, query.Limit+1)
+		transactions, err := s.selectTransactions(
+			ctx,
+			"WHERE "+strings.Join(batchConditions, " AND ")+
+				" ORDER BY block_number "+order+", transaction_index "+order+", hash "+order+
+				", kind "+order+", log_index "+order+" LIMIT ?",
+			batchArgs...,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tx := range transactions {
+			if !query.matches(tx) {
+				continue
+			}
+
+			if len(page.Transactions) == query.Limit {
+				page.NextCursor = CursorOf(page.Transactions[len(page.Transactions)-1])
+				return page, nil
+			}
+
+			page.Transactions = append(page.Transactions, tx)
+		}
+
+		if len(transactions) <= query.Limit {
+			return page, nil
+		}
+
+		cursor = CursorOf(transactions[len(transactions)-1])
+	}
+}
+
+func (s *SQLTransactionsStorage) selectTransactions(
+	ctx context.Context,
+	clause string,
+	args ...any,
+) ([]Transaction, error) {
+	rows, err := s.query(
+		ctx,
+		`SELECT block_number_hex, block_hash, hash, transaction_index_hex, from_address, to_address, value,
+			kind, log_index_hex
+		FROM txparser_transactions `+clause,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var result []Transaction
+	for rows.Next() {
+		var tx Transaction
+
+		err = rows.Scan(
+			&tx.BlockNumber, &tx.BlockHash, &tx.Hash, &tx.TransactionIndex, &tx.From, &tx.To, &tx.Value,
+			&tx.Kind, &tx.LogIndex,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, tx)
+	}
+
+	return result, rows.Err()
+}
+
+func (s *SQLTransactionsStorage) SaveTransactions(
+	ctx context.Context,
+	address string,
+	transactions []Transaction,
+) error {
+	op, err := newSaveTransactionsOp(address, transactions)
+	if err != nil {
+		return err
+	}
+
+	return s.WithDBTransaction(ctx, func(ctx context.Context) error {
+		for _, tx := range op.Transactions {
+			_, err := s.exec(
+				ctx,
+				`INSERT INTO txparser_transactions (
+					address, hash, block_number, block_number_hex, transaction_index, transaction_index_hex,
+					block_hash, from_address, to_address, value, kind, log_index, log_index_hex
+				)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (address, hash, kind, log_index) DO NOTHING`,
+				address,
+				tx.Hash,
+				blockNumberOf(tx),
+				tx.BlockNumber,
+				transactionIndexOf(tx),
+				tx.TransactionIndex,
+				tx.BlockHash,
+				tx.From,
+				tx.To,
+				tx.Value,
+				tx.Kind,
+				logIndexOf(tx),
+				tx.LogIndex,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *SQLTransactionsStorage) DeleteTransactionsByAddress(ctx context.Context, address string) error {
+	_, err := s.exec(ctx, `DELETE FROM txparser_transactions WHERE address = ?`, address)
+
+	return err
+}
+
+// DeleteTransactionsByBlockRange deletes transactions of the address within the inclusive block range
+// and returns the number of deleted transactions.
+func (s *SQLTransactionsStorage) DeleteTransactionsByBlockRange(
+	ctx context.Context,
+	address string,
+	fromBlock, toBlock int,
+) (int, error) {
+	_, err := newDeleteRangeOp(address, fromBlock, toBlock)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := s.exec(
+		ctx,
+		`DELETE FROM txparser_transactions WHERE address = ? AND block_number >= ? AND block_number <= ?`,
+		address,
+		fromBlock,
+		toBlock,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := result.RowsAffected()
+
+	return int(deleted), err
+}
+
+// Addresses returns the addresses with stored transactions.
+func (s *SQLTransactionsStorage) Addresses(ctx context.Context) ([]string, error) {
+	rows, err := s.query(ctx, `SELECT DISTINCT address FROM txparser_transactions ORDER BY address`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var addresses []string
+	for rows.Next() {
+		var address string
+
+		err = rows.Scan(&address)
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = append(addresses, address)
+	}
+
+	return addresses, rows.Err()
+}
@@ -0,0 +1,197 @@
+package txparser_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+
+	"txparser"
+)
+
+// sqlTestDatabases returns the databases the SQL storages are tested against:
+// a local SQLite file and, if TXPARSER_TEST_POSTGRES_DSN is set, a PostgreSQL database.
+func sqlTestDatabases(t *testing.T) map[txparser.SQLDialect]*sql.DB {
+	t.Helper()
+
+	ctx := context.Background()
+	databases := map[txparser.SQLDialect]*sql.DB{}
+
+	sqliteDB, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "txparser.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqliteDB.SetMaxOpenConns(1)
+	databases[txparser.DialectSQLite] = sqliteDB
+
+	if dsn := os.Getenv("TXPARSER_TEST_POSTGRES_DSN"); dsn != "" {
+		postgresDB, err := sql.Open("postgres", dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, table := range []string{
+			"txparser_schema_migrations",
+			"txparser_blocks",
+			"txparser_subscriptions",
+			"txparser_transactions",
+			"txparser_cursors",
+			"txparser_api_keys",
+			"txparser_tenant_subscriptions",
+			"txparser_tenants",
+		} {
+			_, err = postgresDB.ExecContext(ctx, "DROP TABLE IF EXISTS "+table)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		databases[txparser.DialectPostgres] = postgresDB
+	}
+
+	for dialect, db := range databases {
+		db := db
+		t.Cleanup(func() {
+			_ = db.Close()
+		})
+
+		err = txparser.MigrateSQL(ctx, db, dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return databases
+}
+
+func Test_SQLStorage_MigrateIsIdempotent(t *testing.T) {
+	for dialect, db := range sqlTestDatabases(t) {
+		err := txparser.MigrateSQL(context.Background(), db, dialect)
+		if err != nil {
+			t.Errorf("%s: %v", dialect, err)
+		}
+	}
+}
+
+func Test_SQLStorage_SaveAndGet(t *testing.T) {
+	for dialect, db := range sqlTestDatabases(t) {
+		t.Run(string(dialect), func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			blockStorage := txparser.NewSQLBlockStorage(db, dialect)
+			txStorage := txparser.NewSQLTransactionsStorage(db, dialect)
+			subscriptionsStorage := txparser.NewSQLSubscriptionsStorage(db, dialect)
+
+			// Act
+			_ = blockStorage.SaveBlockID(ctx, 5)
+			_ = blockStorage.SaveBlockID(ctx, 6)
+			_ = subscriptionsStorage.PutAddress(ctx, "0x123")
+			_ = subscriptionsStorage.PutAddress(ctx, "0x123")
+			err := txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{
+				{BlockNumber: "0x5", Hash: "0xabc1", From: "0x123", To: "0x321", Value: "0x1"},
+				{BlockNumber: "0x6", Hash: "0xabc2", From: "0x456", To: "0x123", Value: "0x2"},
+			})
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+
+			// Assert
+			if blockIDOf(t, ctx, blockStorage) != 6 {
+				t.Errorf("block id should be %d, but is %d", 6, blockIDOf(t, ctx, blockStorage))
+			}
+			if !isSubscribed(t, ctx, subscriptionsStorage, "0x123") {
+				t.Error("address should exist")
+			}
+			if isSubscribed(t, ctx, subscriptionsStorage, "0x321") {
+				t.Error("address should not exist")
+			}
+			transactions, err := txStorage.GetTransactionsByAddress(ctx, "0x123")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if !areSlicesEqual([]txparser.Transaction{
+				{BlockNumber: "0x5", Hash: "0xabc1", From: "0x123", To: "0x321", Value: "0x1"},
+				{BlockNumber: "0x6", Hash: "0xabc2", From: "0x456", To: "0x123", Value: "0x2"},
+			}, transactions) {
+				t.Errorf("transactions slices should be equal, but got %v", transactions)
+			}
+
+			err = txStorage.DeleteTransactionsByAddress(ctx, "0x123")
+			if err != nil {
+				t.Error(err)
+			}
+			transactions, _ = txStorage.GetTransactionsByAddress(ctx, "0x123")
+			if len(transactions) != 0 {
+				t.Errorf("transactions slice should have %d item(s), but has %d", 0, len(transactions))
+			}
+		})
+	}
+}
+
+func Test_SQLStorage_WithDBTransaction(t *testing.T) {
+	for dialect, db := range sqlTestDatabases(t) {
+		t.Run(string(dialect), func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			blockStorage := txparser.NewSQLBlockStorage(db, dialect)
+			txStorage := txparser.NewSQLTransactionsStorage(db, dialect)
+			_ = blockStorage.SaveBlockID(ctx, 1)
+			errFailed := errors.New("failed")
+
+			// Act
+			err := blockStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+				return txStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+					_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc1"}})
+					_ = blockStorage.SaveBlockID(ctx, 2)
+
+					if blockIDOf(t, ctx, blockStorage) != 2 {
+						t.Error("staged block id should be visible within transaction")
+					}
+
+					return errFailed
+				})
+			})
+
+			// Assert
+			if !errors.Is(err, errFailed) {
+				t.Errorf("error should be %v, but is %v", errFailed, err)
+			}
+			if blockIDOf(t, ctx, blockStorage) != 1 {
+				t.Errorf("block id should be %d, but is %d", 1, blockIDOf(t, ctx, blockStorage))
+			}
+			transactions, _ := txStorage.GetTransactionsByAddress(ctx, "0x123")
+			if len(transactions) != 0 {
+				t.Errorf("transactions slice should have %d item(s), but has %d", 0, len(transactions))
+			}
+
+			// Act #2
+			err = blockStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+				return txStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+					err := txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc1"}})
+					if err != nil {
+						return err
+					}
+
+					return blockStorage.SaveBlockID(ctx, 2)
+				})
+			})
+
+			// Assert
+			if err != nil {
+				t.Error(err)
+			}
+			if blockIDOf(t, ctx, blockStorage) != 2 {
+				t.Errorf("block id should be %d, but is %d", 2, blockIDOf(t, ctx, blockStorage))
+			}
+			transactions, _ = txStorage.GetTransactionsByAddress(ctx, "0x123")
+			if len(transactions) != 1 {
+				t.Errorf("transactions slice should have %d item(s), but has %d", 1, len(transactions))
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package txparser
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DefaultMaxStartLag is the number of blocks behind the head the parser may start
+// without an explicit confirmation, about three hours of mainnet blocks.
+const DefaultMaxStartLag = 1000
+
+var ErrStartLagExceeded = errors.New("start block is too far behind the head")
+
+type StartMode int
+
+const (
+	// StartModeResume continues after the block saved in the block storage,
+	// or starts at the head if nothing has been saved yet.
+	StartModeResume StartMode = iota
+	// StartModeHead skips everything up to the current head.
+	StartModeHead
+	// StartModeBlock starts at a fixed block.
+	StartModeBlock
+	// StartModeBlocksBack starts a number of blocks before the current head.
+	StartModeBlocksBack
+)
+
+// StartPolicy defines the first block parsed by RunWorker.
+type StartPolicy struct {
+	Mode StartMode
+
+	// Block is the first block to parse in StartModeBlock.
+	Block int
+
+	// Blocks is the number of blocks before the head to parse in StartModeBlocksBack.
+	Blocks int
+
+	// MaxLag caps how many blocks behind the head the parser may start,
+	// zero means no limit. Starting further behind fails with ErrStartLagExceeded
+	// unless ConfirmLag is set.
+	MaxLag     int
+	ConfirmLag bool
+}
+
+func ResumeFromCursor() StartPolicy {
+	return StartPolicy{Mode: StartModeResume, MaxLag: DefaultMaxStartLag}
+}
+
+func StartAtHead() StartPolicy {
+	return StartPolicy{Mode: StartModeHead}
+}
+
+func StartAtBlock(block int) StartPolicy {
+	return StartPolicy{Mode: StartModeBlock, Block: block, MaxLag: DefaultMaxStartLag}
+}
+
+func StartBlocksBack(blocks int) StartPolicy {
+	return StartPolicy{Mode: StartModeBlocksBack, Blocks: blocks, MaxLag: DefaultMaxStartLag}
+}
+
+// Confirmed returns a copy of the policy allowed to start any number of blocks behind the head.
+func (p StartPolicy) Confirmed() StartPolicy {
+	p.ConfirmLag = true
+	return p
+}
+
+// cursor returns the block ID to save as the last parsed one before the first tick.
+func (p StartPolicy) cursor(head, saved int) (int, error) {
+	var cursor int
+
+	switch p.Mode {
+	case StartModeResume:
+		cursor = saved
+		if saved == 0 {
+			cursor = head
+		}
+	case StartModeHead:
+		cursor = head
+	case StartModeBlock:
+		if p.Block <= 0 {
+			return 0, fmt.Errorf("invalid start block %d", p.Block)
+		}
+		cursor = p.Block - 1
+	case StartModeBlocksBack:
+		if p.Blocks < 0 {
+			return 0, fmt.Errorf("invalid number of blocks back %d", p.Blocks)
+		}
+		cursor = head - p.Blocks
+	default:
+		return 0, fmt.Errorf("unknown start mode %d", p.Mode)
+	}
+
+	if cursor < 0 {
+		cursor = 0
+	}
+
+	lag := head - cursor
+	if p.MaxLag > 0 && lag > p.MaxLag && !p.ConfirmLag {
+		return 0, fmt.Errorf("%w: %d blocks behind, %d allowed", ErrStartLagExceeded, lag, p.MaxLag)
+	}
+
+	return cursor, nil
+}
@@ -0,0 +1,133 @@
+package txparser_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"txparser"
+)
+
+func Test_Parser_StartPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     txparser.StartPolicy
+		saved      int
+		head       int
+		wantFirst  int
+		wantBlock  int
+		wantErr    error
+		wantParsed bool
+	}{
+		{
+			name:       "resume from cursor",
+			policy:     txparser.ResumeFromCursor(),
+			saved:      95,
+			head:       100,
+			wantFirst:  96,
+			wantBlock:  100,
+			wantParsed: true,
+		},
+		{
+			name:      "resume without cursor starts at head",
+			policy:    txparser.ResumeFromCursor(),
+			saved:     0,
+			head:      100,
+			wantBlock: 100,
+		},
+		{
+			name:      "start at head",
+			policy:    txparser.StartAtHead(),
+			saved:     95,
+			head:      100,
+			wantBlock: 100,
+		},
+		{
+			name:       "start at block",
+			policy:     txparser.StartAtBlock(90),
+			saved:      95,
+			head:       100,
+			wantFirst:  90,
+			wantBlock:  100,
+			wantParsed: true,
+		},
+		{
+			name:       "start blocks back",
+			policy:     txparser.StartBlocksBack(5),
+			saved:      0,
+			head:       100,
+			wantFirst:  96,
+			wantBlock:  100,
+			wantParsed: true,
+		},
+		{
+			name:      "too far behind",
+			policy:    txparser.ResumeFromCursor(),
+			saved:     10,
+			head:      5000,
+			wantBlock: 10,
+			wantErr:   txparser.ErrStartLagExceeded,
+		},
+		{
+			name:       "too far behind confirmed",
+			policy:     txparser.StartAtBlock(4990).Confirmed(),
+			saved:      10,
+			head:       5000,
+			wantFirst:  4990,
+			wantBlock:  5000,
+			wantParsed: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			blockStorage := txparser.NewInmemoryBlockStorage()
+			_ = blockStorage.SaveBlockID(ctx, test.saved)
+			client := &headClient{head: test.head}
+			parser := txparser.NewTXParser(
+				blockStorage,
+				txparser.NewInmemoryTransactionsStorage(),
+				txparser.NewInmemorySubscriptionsStorage(),
+				client,
+				txparser.WithStartPolicy(test.policy),
+			)
+
+			// Act
+			err := parser.RunWorker(ctx, time.Second)
+
+			// Assert
+			if !errors.Is(err, test.wantErr) {
+				t.Errorf("error should be %v, but is %v", test.wantErr, err)
+			}
+			if parser.GetCurrentBlock() != test.wantBlock {
+				t.Errorf("current block should be %d, but is %d", test.wantBlock, parser.GetCurrentBlock())
+			}
+			if test.wantParsed != (len(client.requested) > 0) {
+				t.Errorf("requested blocks are %v", client.requested)
+				t.FailNow()
+			}
+			if test.wantParsed && client.requested[0] != test.wantFirst {
+				t.Errorf("first parsed block should be %d, but is %d", test.wantFirst, client.requested[0])
+			}
+		})
+	}
+}
+
+type headClient struct {
+	head      int
+	requested []int
+}
+
+func (c *headClient) CurrentBlockNumber(_ context.Context) (int, error) {
+	return c.head, nil
+}
+
+func (c *headClient) GetBlockByNumber(_ context.Context, number int) (*txparser.Block, error) {
+	c.requested = append(c.requested, number)
+
+	return &txparser.Block{}, nil
+}
@@ -0,0 +1,66 @@
+package txparser
+
+import (
+	"time"
+)
+
+type WorkerState string
+
+const (
+	// WorkerIdle is the state of a parser whose worker has not been run.
+	WorkerIdle WorkerState = "idle"
+	// WorkerStarting is the state while RunWorker applies the start policy.
+	WorkerStarting WorkerState = "starting"
+	WorkerRunning  WorkerState = "running"
+	// WorkerPaused is the state of a running worker between Pause and Resume.
+	WorkerPaused  WorkerState = "paused"
+	WorkerStopped WorkerState = "stopped"
+)
+
+// Status reports the progress of the parser worker.
+type Status struct {
+	State WorkerState `json:"state"`
+
+	// LastBlock is the last parsed block, Head is the latest block of the node seen by the worker.
+	LastBlock int `json:"lastBlock"`
+	Head      int `json:"head"`
+	Lag       int `json:"lag"`
+
+	StartedAt time.Time `json:"startedAt"`
+
+	// LastTickAt is when the worker last made progress: parsed a block or finished a pass,
+	// zero if it has not yet.
+	LastTickAt time.Time `json:"lastTickAt"`
+
+	LastError   string    `json:"lastError,omitempty"`
+	LastErrorAt time.Time `json:"lastErrorAt"`
+}
+
+// Status returns the status of the worker.
+func (p *TXParser) Status() Status {
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+
+	status := p.status
+	status.Lag = status.Head - status.LastBlock
+	if status.State == WorkerRunning && p.worker.Paused() {
+		status.State = WorkerPaused
+	}
+
+	return status
+}
+
+// updateStatus changes the status under its lock.
+func (p *TXParser) updateStatus(fn func(s *Status)) {
+	p.statusMu.Lock()
+	defer p.statusMu.Unlock()
+
+	fn(&p.status)
+}
+
+func (p *TXParser) setStatusError(err error) {
+	p.updateStatus(func(s *Status) {
+		s.LastError = err.Error()
+		s.LastErrorAt = p.clock.Now()
+	})
+}
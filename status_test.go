@@ -0,0 +1,103 @@
+package txparser_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/txparsertest"
+)
+
+func Test_Parser_Status(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	parser := txparser.NewTXParser(
+		blockStorage,
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		&blocksClient{head: 3},
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+		txparser.WithClock(clock),
+	)
+	idle := parser.Status()
+
+	// Act
+	runUntilBlock(t, parser, clock, blockStorage, 3)
+	stopped := parser.Status()
+
+	// Assert
+	if idle.State != txparser.WorkerIdle || !idle.LastTickAt.IsZero() {
+		t.Errorf("status should be idle, but is %+v", idle)
+	}
+	if stopped.State != txparser.WorkerStopped {
+		t.Errorf("state should be %q, but is %q", txparser.WorkerStopped, stopped.State)
+	}
+	if stopped.LastBlock != 3 || stopped.Head != 3 || stopped.Lag != 0 {
+		t.Errorf("status should be at block 3 of 3, but is %+v", stopped)
+	}
+	if !stopped.StartedAt.Equal(epoch) || !stopped.LastTickAt.Equal(epoch) {
+		t.Errorf("worker should tick after start, but status is %+v", stopped)
+	}
+	if stopped.LastError != "" {
+		t.Errorf("last error should be empty, but is %q", stopped.LastError)
+	}
+}
+
+func Test_Parser_StatusError(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		missingBlocksClient{head: 5},
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+		txparser.WithClock(clock),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	go func() {
+		_ = parser.RunWorker(ctx, time.Hour)
+	}()
+	clock.BlockUntil(1) // the retry of the failed pass
+	status := parser.Status()
+
+	// Assert
+	if status.State != txparser.WorkerRunning {
+		t.Errorf("state should be %q, but is %q", txparser.WorkerRunning, status.State)
+	}
+	if status.LastError != "block not found" || !status.LastErrorAt.Equal(epoch) {
+		t.Errorf("last error should be %q, but status is %+v", "block not found", status)
+	}
+	if status.Lag != 5 || !status.LastTickAt.IsZero() {
+		t.Errorf("worker should lag by 5 blocks without ticks, but status is %+v", status)
+	}
+}
+
+func Test_Parser_StatusStartError(t *testing.T) {
+	// Arrange
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		missingBlocksClient{head: 5},
+		txparser.WithStartPolicy(txparser.StartAtBlock(0)),
+	)
+
+	// Act
+	err := parser.RunWorker(context.Background(), time.Millisecond)
+	status := parser.Status()
+
+	// Assert
+	if err == nil {
+		t.Error("worker should fail to start")
+		t.FailNow()
+	}
+	if status.State != txparser.WorkerStopped || status.LastError != err.Error() {
+		t.Errorf("status should be stopped with error %v, but is %+v", err, status)
+	}
+}
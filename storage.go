@@ -3,35 +3,60 @@ package txparser
 import (
 	"context"
 	"errors"
-	"math/big"
+	"fmt"
+	"sort"
+	"strconv"
 	"sync"
-	"sync/atomic"
 )
 
+var ErrTransactionClosed = errors.New("storage transaction is already closed")
+
 type InmemoryBlockStorage struct {
-	blockID atomic.Int64
+	mu      sync.RWMutex
+	blockID int
 }
 
 func NewInmemoryBlockStorage() *InmemoryBlockStorage {
 	return &InmemoryBlockStorage{}
 }
 
-func (s *InmemoryBlockStorage) SaveBlockID(_ context.Context, blockID int) error {
-	s.blockID.Store(int64(blockID))
+func (s *InmemoryBlockStorage) SaveBlockID(ctx context.Context, blockID int) error {
+	if tx, ok := stagedTxFromContext[int](ctx, s); ok {
+		return tx.add(blockID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.blockID = blockID
+
 	return nil
 }
 
-func (s *InmemoryBlockStorage) GetBlockID(_ context.Context) int {
-	return int(s.blockID.Load())
+func (s *InmemoryBlockStorage) GetBlockID(ctx context.Context) (int, error) {
+	return s.get(ctx), nil
+}
+
+func (s *InmemoryBlockStorage) get(ctx context.Context) int {
+	tx, _ := stagedTxFromContext[int](ctx, s)
+	if staged := tx.staged(); len(staged) > 0 {
+		return staged[len(staged)-1]
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.blockID
 }
 
 func (s *InmemoryBlockStorage) WithDBTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	// TODO: Implement transactional storage.
-	return fn(ctx)
+	return runStagedTx(ctx, s, fn, func(ops []int) error {
+		return s.SaveBlockID(ctx, ops[len(ops)-1])
+	})
 }
 
 type InmemorySubscriptionsStorage struct {
-	addresses sync.Map // map[string]struct{}
+	addresses sync.Map // map[string]bool, true for managed addresses
 }
 
 func NewInmemorySubscriptionsStorage() *InmemorySubscriptionsStorage {
@@ -39,122 +64,822 @@ func NewInmemorySubscriptionsStorage() *InmemorySubscriptionsStorage {
 }
 
 func (s *InmemorySubscriptionsStorage) PutAddress(_ context.Context, address string) error {
-	s.addresses.Store(address, struct{}{})
+	s.addresses.Store(address, false)
+	return nil
+}
+
+func (s *InmemorySubscriptionsStorage) DeleteAddress(_ context.Context, address string) error {
+	s.addresses.Delete(address)
+	return nil
+}
+
+func (s *InmemorySubscriptionsStorage) PutManagedAddress(_ context.Context, address string) error {
+	s.addresses.LoadOrStore(address, true)
+	return nil
+}
+
+func (s *InmemorySubscriptionsStorage) DeleteManagedAddress(_ context.Context, address string) error {
+	s.addresses.CompareAndDelete(address, true)
 	return nil
 }
 
-func (s *InmemorySubscriptionsStorage) IsAddressExists(_ context.Context, address string) bool {
+func (s *InmemorySubscriptionsStorage) GetManagedAddresses(_ context.Context) ([]string, error) {
+	addresses := s.listManaged()
+	sort.Strings(addresses)
+
+	return addresses, nil
+}
+
+// isManaged reports whether the address is subscribed and managed.
+func (s *InmemorySubscriptionsStorage) isManaged(address string) bool {
+	value, _ := s.addresses.Load(address)
+	managed, _ := value.(bool)
+
+	return managed
+}
+
+func (s *InmemorySubscriptionsStorage) listManaged() []string {
+	var addresses []string
+	s.addresses.Range(func(key, value any) bool {
+		address, _ := key.(string)
+		if managed, _ := value.(bool); managed {
+			addresses = append(addresses, address)
+		}
+		return true
+	})
+
+	return addresses
+}
+
+func (s *InmemorySubscriptionsStorage) IsAddressExists(_ context.Context, address string) (bool, error) {
+	return s.has(address), nil
+}
+
+func (s *InmemorySubscriptionsStorage) has(address string) bool {
 	_, ok := s.addresses.Load(address)
 	return ok
 }
 
-type InmemoryTransactionsStorage struct {
-	// Transactions hashes by address index
-	// map[string][]*big.Int
-	transactionsByAddress sync.Map
+func (s *InmemorySubscriptionsStorage) GetAddresses(_ context.Context) ([]string, error) {
+	addresses := s.list()
+	sort.Strings(addresses)
+
+	return addresses, nil
+}
 
-	// Transactions by hash
-	// map[*big.Int]Transaction
-	transactions sync.Map
+func (s *InmemorySubscriptionsStorage) list() []string {
+	var addresses []string
+	s.addresses.Range(func(key, _ any) bool {
+		if address, ok := key.(string); ok {
+			addresses = append(addresses, address)
+		}
+		return true
+	})
 
-	mu sync.Mutex
+	return addresses
 }
 
-func NewInmemoryTransactionsStorage() *InmemoryTransactionsStorage {
-	return &InmemoryTransactionsStorage{}
+type InmemoryCursorStorage struct {
+	mu sync.RWMutex
+
+	// Cursors by consumer and address
+	cursors map[string]map[string]string
 }
 
-func (s *InmemoryTransactionsStorage) GetTransactionsByAddress(
-	_ context.Context,
-	address string,
-) ([]Transaction, error) {
-	v, ok := s.transactionsByAddress.Load(address)
+func NewInmemoryCursorStorage() *InmemoryCursorStorage {
+	return &InmemoryCursorStorage{
+		cursors: make(map[string]map[string]string),
+	}
+}
+
+func (s *InmemoryCursorStorage) GetCursor(_ context.Context, consumer, address string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.cursors[consumer][address], nil
+}
+
+func (s *InmemoryCursorStorage) SaveCursor(_ context.Context, consumer, address, cursor string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cursors[consumer]; !ok {
+		s.cursors[consumer] = make(map[string]string)
+	}
+	s.cursors[consumer][address] = cursor
+
+	return nil
+}
+
+// snapshot returns all the cursors grouped by consumer.
+func (s *InmemoryCursorStorage) snapshot() map[string]map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string]map[string]string, len(s.cursors))
+	for consumer, cursors := range s.cursors {
+		result[consumer] = make(map[string]string, len(cursors))
+		for address, cursor := range cursors {
+			result[consumer][address] = cursor
+		}
+	}
+
+	return result
+}
+
+type InmemoryAPIKeyStorage struct {
+	tenants sync.Map
+}
+
+func NewInmemoryAPIKeyStorage() *InmemoryAPIKeyStorage {
+	return &InmemoryAPIKeyStorage{}
+}
+
+func (s *InmemoryAPIKeyStorage) PutAPIKey(_ context.Context, keyHash string, tenant Tenant) error {
+	s.tenants.Store(keyHash, tenant)
+
+	return nil
+}
+
+func (s *InmemoryAPIKeyStorage) GetTenantByAPIKey(_ context.Context, keyHash string) (*Tenant, error) {
+	tenant, ok := s.tenants.Load(keyHash)
 	if !ok {
-		return nil, nil
+		return nil, ErrUnknownAPIKey
+	}
+
+	t := tenant.(Tenant)
+
+	return &t, nil
+}
+
+type InmemoryTenantSubscriptionsStorage struct {
+	mu sync.RWMutex
+
+	// Addresses by tenant
+	addresses map[string]map[string]struct{}
+}
+
+func NewInmemoryTenantSubscriptionsStorage() *InmemoryTenantSubscriptionsStorage {
+	return &InmemoryTenantSubscriptionsStorage{
+		addresses: make(map[string]map[string]struct{}),
 	}
+}
 
-	transactions, ok := v.([]*big.Int)
+func (s *InmemoryTenantSubscriptionsStorage) PutTenantAddress(
+	_ context.Context,
+	tenantID, address string,
+	maxAddresses int,
+) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addresses, ok := s.addresses[tenantID]
 	if !ok {
-		return nil, errors.New("invalid storage data")
+		addresses = make(map[string]struct{})
+		s.addresses[tenantID] = addresses
 	}
 
-	result := make([]Transaction, 0, len(transactions))
-	for _, hash := range transactions {
-		tx, ok := s.transactions.Load(hash)
-		if !ok {
-			return nil, nil
-		}
-		txValue, ok := tx.(Transaction)
-		if !ok {
-			return nil, errors.New("invalid storage data")
+	if _, ok := addresses[address]; ok {
+		return nil
+	}
+	if maxAddresses > 0 && len(addresses) >= maxAddresses {
+		return ErrSubscriptionQuotaExceeded
+	}
+
+	addresses[address] = struct{}{}
+
+	return nil
+}
+
+func (s *InmemoryTenantSubscriptionsStorage) IsTenantAddressExists(
+	_ context.Context,
+	tenantID, address string,
+) (bool, error) {
+	return s.has(tenantID, address), nil
+}
+
+func (s *InmemoryTenantSubscriptionsStorage) has(tenantID, address string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.addresses[tenantID][address]
+
+	return ok
+}
+
+func (s *InmemoryTenantSubscriptionsStorage) GetTenantAddresses(_ context.Context, tenantID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	addresses := make([]string, 0, len(s.addresses[tenantID]))
+	for address := range s.addresses[tenantID] {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	return addresses, nil
+}
+
+// snapshot returns sorted addresses by tenant.
+func (s *InmemoryTenantSubscriptionsStorage) snapshot() map[string][]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	addressesByTenant := make(map[string][]string, len(s.addresses))
+	for tenantID, addresses := range s.addresses {
+		list := make([]string, 0, len(addresses))
+		for address := range addresses {
+			list = append(list, address)
 		}
+		sort.Strings(list)
+		addressesByTenant[tenantID] = list
+	}
+
+	return addressesByTenant
+}
+
+type InmemoryTransactionsStorage struct {
+	mu sync.RWMutex
 
-		result = append(result, txValue)
+	// Transactions by address index
+	transactionsByAddress map[string]*addressTransactions
+}
+
+func NewInmemoryTransactionsStorage() *InmemoryTransactionsStorage {
+	return &InmemoryTransactionsStorage{
+		transactionsByAddress: make(map[string]*addressTransactions),
 	}
+}
+
+func (s *InmemoryTransactionsStorage) GetTransactionsByAddress(
+	ctx context.Context,
+	address string,
+) ([]Transaction, error) {
+	// Reads within a transaction see its own staged writes
+	tx, _ := stagedTxFromContext[transactionsOp](ctx, s)
 
-	return result, nil
+	return s.get(address, tx.staged()), nil
 }
 
 func (s *InmemoryTransactionsStorage) SaveTransactions(
-	_ context.Context,
+	ctx context.Context,
 	address string,
 	newTransactions []Transaction,
 ) error {
+	op, err := newSaveTransactionsOp(address, newTransactions)
+	if err != nil {
+		return err
+	}
+
+	return s.write(ctx, op)
+}
+
+func (s *InmemoryTransactionsStorage) QueryTransactions(
+	ctx context.Context,
+	query TransactionsQuery,
+) (*TransactionsPage, error) {
+	tx, _ := stagedTxFromContext[transactionsOp](ctx, s)
+
+	return s.query(query, tx.staged())
+}
+
+func (s *InmemoryTransactionsStorage) DeleteTransactionsByAddress(ctx context.Context, address string) error {
+	return s.write(ctx, transactionsOp{
+		Kind:    transactionsOpDelete,
+		Address: address,
+	})
+}
+
+// DeleteTransactionsByBlockRange deletes transactions of the address within the inclusive block range
+// and returns the number of deleted transactions.
+func (s *InmemoryTransactionsStorage) DeleteTransactionsByBlockRange(
+	ctx context.Context,
+	address string,
+	fromBlock, toBlock int,
+) (int, error) {
+	op, err := newDeleteRangeOp(address, fromBlock, toBlock)
+	if err != nil {
+		return 0, err
+	}
+
+	if tx, ok := stagedTxFromContext[transactionsOp](ctx, s); ok {
+		return tx.addCounted(op, func(staged []transactionsOp) int {
+			return s.countRange(address, fromBlock, toBlock, staged)
+		})
+	}
+
+	return s.deleteRange(op), nil
+}
+
+// Addresses returns the addresses with stored transactions.
+func (s *InmemoryTransactionsStorage) Addresses(ctx context.Context) ([]string, error) {
+	tx, _ := stagedTxFromContext[transactionsOp](ctx, s)
+
+	return s.addresses(tx.staged()), nil
+}
+
+func (s *InmemoryTransactionsStorage) WithDBTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return runStagedTx(ctx, s, fn, func(ops []transactionsOp) error {
+		s.apply(ops...)
+		return nil
+	})
+}
+
+// write applies op immediately or stages it when ctx carries a transaction.
+func (s *InmemoryTransactionsStorage) write(ctx context.Context, op transactionsOp) error {
+	if tx, ok := stagedTxFromContext[transactionsOp](ctx, s); ok {
+		return tx.add(op)
+	}
+
+	s.apply(op)
+
+	return nil
+}
+
+// get returns committed transactions of the address with staged ops applied on top of them.
+func (s *InmemoryTransactionsStorage) get(address string, staged []transactionsOp) []Transaction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry := s.entry(address, staged)
+	if entry == nil || len(entry.entries) == 0 {
+		return nil
+	}
+
+	return entry.list()
+}
+
+func (s *InmemoryTransactionsStorage) query(
+	query TransactionsQuery,
+	staged []transactionsOp,
+) (*TransactionsPage, error) {
+	query, err := query.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry := s.entry(query.Address, staged)
+	if entry == nil {
+		return &TransactionsPage{Transactions: []Transaction{}}, nil
+	}
+
+	return entry.query(query), nil
+}
+
+func (s *InmemoryTransactionsStorage) countRange(address string, fromBlock, toBlock int, staged []transactionsOp) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.countRangeLocked(address, fromBlock, toBlock, staged)
+}
+
+// deleteRange applies the delete range op and returns the number of deleted transactions,
+// they are counted under the same lock, so concurrent writes do not change the count.
+func (s *InmemoryTransactionsStorage) deleteRange(op transactionsOp) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := s.countRangeLocked(op.Address, op.FromBlock, op.ToBlock, nil)
+	s.applyLocked(op)
+
+	return deleted
+}
+
+// countRangeLocked is countRange for callers holding the lock.
+func (s *InmemoryTransactionsStorage) countRangeLocked(
+	address string,
+	fromBlock, toBlock int,
+	staged []transactionsOp,
+) int {
+	entry := s.entry(address, staged)
+	if entry == nil {
+		return 0
+	}
+
+	from, to := entry.blockRange(fromBlock, toBlock)
+
+	return to - from
+}
+
+func (s *InmemoryTransactionsStorage) addresses(staged []transactionsOp) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	addresses := make([]string, 0, len(s.transactionsByAddress))
+	for address := range s.transactionsByAddress {
+		addresses = append(addresses, address)
+	}
+
+	for _, op := range staged {
+		if op.Kind == transactionsOpSave && len(op.Transactions) > 0 && s.transactionsByAddress[op.Address] == nil {
+			addresses = append(addresses, op.Address)
+		}
+	}
+
+	sort.Strings(addresses)
+
+	return compactStrings(addresses)
+}
+
+// entry returns the committed transactions of the address or a copy of them with staged ops applied.
+// The caller must hold the lock.
+func (s *InmemoryTransactionsStorage) entry(address string, staged []transactionsOp) *addressTransactions {
+	entry := s.transactionsByAddress[address]
+
+	cloned := false
+	for _, op := range staged {
+		if op.Address != address {
+			continue
+		}
+
+		if !cloned {
+			entry = entry.clone()
+			cloned = true
+		}
+		op.apply(entry)
+	}
+
+	return entry
+}
+
+func (s *InmemoryTransactionsStorage) apply(ops ...transactionsOp) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	transactionHashes := make([]*big.Int, 0, len(newTransactions))
+	s.applyLocked(ops...)
+}
+
+// applyLocked is apply for callers holding the lock.
+func (s *InmemoryTransactionsStorage) applyLocked(ops ...transactionsOp) {
+	for _, op := range ops {
+		entry, ok := s.transactionsByAddress[op.Address]
+		if !ok {
+			entry = &addressTransactions{}
+		}
+
+		op.apply(entry)
+
+		if len(entry.entries) == 0 {
+			delete(s.transactionsByAddress, op.Address)
+			continue
+		}
+
+		s.transactionsByAddress[op.Address] = entry
+	}
+}
+
+// snapshot returns all the stored transactions grouped by address.
+func (s *InmemoryTransactionsStorage) snapshot() map[string][]Transaction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make(map[string][]Transaction, len(s.transactionsByAddress))
+	for address, entry := range s.transactionsByAddress {
+		result[address] = entry.list()
+	}
+
+	return result
+}
+
+// addressTransactions is the block-ordered index of transactions of a single address.
+type addressTransactions struct {
+	// Transactions ordered by their position in the chain
+	entries []indexedTransaction
+
+	// Keys of the stored transactions to keep saving idempotent
+	keys map[string]struct{}
+}
+
+type indexedTransaction struct {
+	position    txPosition
+	transaction Transaction
+}
+
+func (e *addressTransactions) clone() *addressTransactions {
+	if e == nil {
+		return &addressTransactions{}
+	}
+
+	entries := make([]indexedTransaction, len(e.entries))
+	copy(entries, e.entries)
+
+	keys := make(map[string]struct{}, len(e.keys))
+	for key := range e.keys {
+		keys[key] = struct{}{}
+	}
+
+	return &addressTransactions{entries: entries, keys: keys}
+}
+
+func (e *addressTransactions) list() []Transaction {
+	result := make([]Transaction, 0, len(e.entries))
+	for _, entry := range e.entries {
+		result = append(result, entry.transaction)
+	}
+
+	return result
+}
+
+// blockRange returns the bounds of entries within the inclusive block range.
+func (e *addressTransactions) blockRange(fromBlock, toBlock int) (int, int) {
+	from := sort.Search(len(e.entries), func(i int) bool {
+		return e.entries[i].position.block >= fromBlock
+	})
+	to := sort.Search(len(e.entries), func(i int) bool {
+		return e.entries[i].position.block > toBlock
+	})
+
+	return from, to
+}
+
+func (e *addressTransactions) deleteRange(fromBlock, toBlock int) {
+	from, to := e.blockRange(fromBlock, toBlock)
+	if from >= to {
+		return
+	}
+
+	for _, entry := range e.entries[from:to] {
+		delete(e.keys, transactionKey(entry.transaction))
+	}
+
+	e.entries = append(e.entries[:from], e.entries[to:]...)
+}
+
+func (e *addressTransactions) add(tx Transaction) {
+	key := transactionKey(tx)
+	if _, ok := e.keys[key]; ok {
+		return
+	}
 
+	if e.keys == nil {
+		e.keys = make(map[string]struct{})
+	}
+
+	e.keys[key] = struct{}{}
+
+	position := positionOf(tx)
+	i := sort.Search(len(e.entries), func(i int) bool {
+		return position.less(e.entries[i].position)
+	})
+
+	e.entries = append(e.entries, indexedTransaction{})
+	copy(e.entries[i+1:], e.entries[i:])
+	e.entries[i] = indexedTransaction{position: position, transaction: tx}
+}
+
+// query looks up the first transaction of the page in the index
+// and scans from it in the query order until the page is filled.
+func (e *addressTransactions) query(query TransactionsQuery) *TransactionsPage {
+	page := &TransactionsPage{Transactions: []Transaction{}}
+
+	ascending := query.Order == SortAscending
+
+	var i int
+	if ascending {
+		i = sort.Search(len(e.entries), func(i int) bool {
+			return e.entries[i].position.block >= query.FromBlock
+		})
+		if query.Cursor != "" {
+			cursor, _ := decodeCursor(query.Cursor)
+			afterCursor := sort.Search(len(e.entries), func(i int) bool {
+				return cursor.less(e.entries[i].position)
+			})
+			if afterCursor > i {
+				i = afterCursor
+			}
+		}
+	} else {
+		i = len(e.entries) - 1
+		if query.ToBlock > 0 {
+			i = sort.Search(len(e.entries), func(i int) bool {
+				return e.entries[i].position.block > query.ToBlock
+			}) - 1
+		}
+		if query.Cursor != "" {
+			cursor, _ := decodeCursor(query.Cursor)
+			beforeCursor := sort.Search(len(e.entries), func(i int) bool {
+				return !e.entries[i].position.less(cursor)
+			}) - 1
+			if beforeCursor < i {
+				i = beforeCursor
+			}
+		}
+	}
+
+	for ; i >= 0 && i < len(e.entries); i = next(i, ascending) {
+		entry := e.entries[i]
+
+		if ascending && query.ToBlock > 0 && entry.position.block > query.ToBlock {
+			break
+		}
+		if !ascending && entry.position.block < query.FromBlock {
+			break
+		}
+		if !query.matches(entry.transaction) {
+			continue
+		}
+
+		// One more matching transaction means there is a next page
+		if len(page.Transactions) == query.Limit {
+			page.NextCursor = CursorOf(page.Transactions[len(page.Transactions)-1])
+			break
+		}
+
+		page.Transactions = append(page.Transactions, entry.transaction)
+	}
+
+	return page
+}
+
+func next(i int, ascending bool) int {
+	if ascending {
+		return i + 1
+	}
+
+	return i - 1
+}
+
+// transactionKey identifies an entry stored for an address: the transaction itself
+// or one of its logs, such as a token transfer.
+func transactionKey(tx Transaction) string {
+	return tx.Hash + "/" + tx.Kind + "/" + strconv.Itoa(logIndexOf(tx))
+}
+
+const (
+	transactionsOpSave        = "save"
+	transactionsOpDelete      = "delete"
+	transactionsOpDeleteRange = "deleteRange"
+)
+
+// transactionsOp is a single write to the transactions storage.
+type transactionsOp struct {
+	Kind         string        `json:"kind"`
+	Address      string        `json:"address"`
+	Transactions []Transaction `json:"transactions,omitempty"`
+	FromBlock    int           `json:"fromBlock,omitempty"`
+	ToBlock      int           `json:"toBlock,omitempty"`
+}
+
+func newSaveTransactionsOp(address string, newTransactions []Transaction) (transactionsOp, error) {
 	for _, tx := range newTransactions {
-		hash, err := convertHexToNum(tx.Hash)
+		_, err := convertHexToNum(tx.Hash)
 		if err != nil {
-			return err
+			return transactionsOp{}, err
 		}
-		s.transactions.Store(hash, tx)
-		transactionHashes = append(transactionHashes, hash)
 	}
 
-	v, ok := s.transactionsByAddress.Load(address)
-	if !ok {
-		s.transactionsByAddress.Store(address, transactionHashes)
-		return nil
+	transactions := make([]Transaction, len(newTransactions))
+	copy(transactions, newTransactions)
+
+	return transactionsOp{
+		Kind:         transactionsOpSave,
+		Address:      address,
+		Transactions: transactions,
+	}, nil
+}
+
+func newDeleteRangeOp(address string, fromBlock, toBlock int) (transactionsOp, error) {
+	if fromBlock < 0 || fromBlock > toBlock {
+		return transactionsOp{}, fmt.Errorf("invalid block range %d-%d", fromBlock, toBlock)
 	}
 
-	transactions, ok := v.([]*big.Int)
-	if !ok {
-		return errors.New("invalid storage data")
+	return transactionsOp{
+		Kind:      transactionsOpDeleteRange,
+		Address:   address,
+		FromBlock: fromBlock,
+		ToBlock:   toBlock,
+	}, nil
+}
+
+// compactStrings removes consecutive duplicates from the sorted slice.
+func compactStrings(s []string) []string {
+	if len(s) == 0 {
+		return s
 	}
 
-	transactions = append(transactions, transactionHashes...)
-	s.transactionsByAddress.Store(address, transactions)
+	result := s[:1]
+	for _, v := range s[1:] {
+		if v != result[len(result)-1] {
+			result = append(result, v)
+		}
+	}
+
+	return result
+}
+
+func (op transactionsOp) apply(entry *addressTransactions) {
+	switch op.Kind {
+	case transactionsOpSave:
+		for _, tx := range op.Transactions {
+			entry.add(tx)
+		}
+	case transactionsOpDelete:
+		entry.entries = nil
+		entry.keys = nil
+	case transactionsOpDeleteRange:
+		entry.deleteRange(op.FromBlock, op.ToBlock)
+	}
+}
+
+type stagedTxKey struct {
+	storage any
+}
+
+// stagedTx stages the writes made to a single storage
+// within WithDBTransaction until the transaction function succeeds.
+type stagedTx[T any] struct {
+	mu     sync.Mutex
+	ops    []T
+	closed bool
+}
+
+func (tx *stagedTx[T]) add(op T) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	if tx.closed {
+		return ErrTransactionClosed
+	}
+
+	tx.ops = append(tx.ops, op)
 
 	return nil
 }
 
-func (s *InmemoryTransactionsStorage) DeleteTransactionsByAddress(_ context.Context, address string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// addCounted stages the op unless count of the staged ops is zero and returns the count.
+// Both are done under the lock, so other ops of the transaction do not change the count.
+func (tx *stagedTx[T]) addCounted(op T, count func(staged []T) int) (int, error) {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
 
-	v, ok := s.transactionsByAddress.Load(address)
-	if !ok {
-		return nil
+	if tx.closed {
+		return 0, ErrTransactionClosed
 	}
 
-	transactions, ok := v.([]*big.Int)
-	if !ok {
-		return errors.New("invalid storage data")
+	n := count(tx.ops)
+	if n > 0 {
+		tx.ops = append(tx.ops, op)
 	}
 
-	for _, hash := range transactions {
-		s.transactions.Delete(hash)
+	return n, nil
+}
+
+// staged returns a copy of the writes staged so far, it is safe to call on a nil transaction.
+func (tx *stagedTx[T]) staged() []T {
+	if tx == nil {
+		return nil
 	}
 
-	s.transactionsByAddress.Delete(address)
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
 
-	return nil
+	ops := make([]T, len(tx.ops))
+	copy(ops, tx.ops)
+
+	return ops
 }
 
-func (s *InmemoryTransactionsStorage) WithDBTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	// TODO: Implement transactional storage.
-	return fn(ctx)
+func stagedTxFromContext[T any](ctx context.Context, storage any) (*stagedTx[T], bool) {
+	tx, ok := ctx.Value(stagedTxKey{storage: storage}).(*stagedTx[T])
+	return tx, ok
+}
+
+// runStagedTx runs fn with a transaction of the storage carried in the context.
+// Staged writes are passed to commit only if fn succeeds,
+// on error or panic they are discarded. Nested calls join the outer transaction.
+func runStagedTx[T any](
+	ctx context.Context,
+	storage any,
+	fn func(ctx context.Context) error,
+	commit func(ops []T) error,
+) error {
+	if _, ok := stagedTxFromContext[T](ctx, storage); ok {
+		return fn(ctx)
+	}
+
+	tx := &stagedTx[T]{}
+	defer func() {
+		tx.mu.Lock()
+		tx.closed = true
+		tx.mu.Unlock()
+	}()
+
+	err := fn(context.WithValue(ctx, stagedTxKey{storage: storage}, tx))
+	if err != nil {
+		return err
+	}
+
+	tx.mu.Lock()
+	tx.closed = true
+	ops := tx.ops
+	tx.mu.Unlock()
+
+	if len(ops) == 0 {
+		return nil
+	}
+
+	return commit(ops)
 }
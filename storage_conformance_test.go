@@ -0,0 +1,504 @@
+package txparser_test
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/txparsertest"
+)
+
+type conformanceStorages struct {
+	blocks        txparser.BlockStorage
+	transactions  txparser.TransactionStorage
+	subscriptions txparser.SubscriptionsStorage
+	cursors       txparser.CursorStorage
+}
+
+// storagesUnderTest returns fresh instances of every storage implementation,
+// conformance tests run the same scenarios against all of them.
+func storagesUnderTest(t *testing.T) map[string]conformanceStorages {
+	t.Helper()
+
+	result := map[string]conformanceStorages{
+		"inmemory": {
+			blocks:        txparser.NewInmemoryBlockStorage(),
+			transactions:  txparser.NewInmemoryTransactionsStorage(),
+			subscriptions: txparser.NewInmemorySubscriptionsStorage(),
+			cursors:       txparser.NewInmemoryCursorStorage(),
+		},
+	}
+
+	dir := t.TempDir()
+	blockStorage, txStorage, subscriptionsStorage := openFileStorages(t, dir)
+	cursorStorage, err := txparser.NewFileCursorStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		closeFileStorages(t, blockStorage, txStorage, subscriptionsStorage, cursorStorage)
+	})
+	result["file"] = conformanceStorages{
+		blocks:        blockStorage,
+		transactions:  txStorage,
+		subscriptions: subscriptionsStorage,
+		cursors:       cursorStorage,
+	}
+
+	for dialect, db := range sqlTestDatabases(t) {
+		result["sql/"+string(dialect)] = conformanceStorages{
+			blocks:        txparser.NewSQLBlockStorage(db, dialect),
+			transactions:  txparser.NewSQLTransactionsStorage(db, dialect),
+			subscriptions: txparser.NewSQLSubscriptionsStorage(db, dialect),
+			cursors:       txparser.NewSQLCursorStorage(db, dialect),
+		}
+	}
+
+	return result
+}
+
+func Test_StorageConformance_SaveIsIdempotent(t *testing.T) {
+	for name, storages := range storagesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			transactions := []txparser.Transaction{
+				{BlockNumber: "0x1", Hash: "0xabc1", From: "0x123", To: "0x321"},
+				{BlockNumber: "0x1", Hash: "0xabc2", From: "0x321", To: "0x123"},
+			}
+
+			// Act
+			_ = storages.transactions.SaveTransactions(ctx, "0x123", transactions)
+			_ = storages.transactions.SaveTransactions(ctx, "0x123", transactions)
+			_ = storages.transactions.SaveTransactions(ctx, "0x123", transactions[:1])
+
+			// Assert
+			saved, err := storages.transactions.GetTransactionsByAddress(ctx, "0x123")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if !areSlicesEqual(transactions, saved) {
+				t.Errorf("transactions should be saved once, but are %v", saved)
+			}
+		})
+	}
+}
+
+func Test_StorageConformance_EntriesShareHash(t *testing.T) {
+	for name, storages := range storagesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			transactions := []txparser.Transaction{
+				{BlockNumber: "0x1", Hash: "0xabc1", From: "0x123", To: "0xc0de"},
+				{BlockNumber: "0x1", Hash: "0xabc1", From: "0x123", To: "0x321", Kind: txparser.KindTokenTransfer, LogIndex: "0x0"},
+				{BlockNumber: "0x1", Hash: "0xabc1", From: "0x123", To: "0x456", Kind: txparser.KindTokenTransfer, LogIndex: "0x2"},
+			}
+
+			// Act
+			_ = storages.transactions.SaveTransactions(ctx, "0x123", transactions)
+			_ = storages.transactions.SaveTransactions(ctx, "0x123", transactions)
+			var paged []txparser.Transaction
+			query := txparser.TransactionsQuery{Address: "0x123", Limit: 1}
+			for {
+				page, err := storages.transactions.QueryTransactions(ctx, query)
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+				paged = append(paged, page.Transactions...)
+				if page.NextCursor == "" || len(paged) > len(transactions) {
+					break
+				}
+				query.Cursor = page.NextCursor
+			}
+
+			// Assert
+			saved, err := storages.transactions.GetTransactionsByAddress(ctx, "0x123")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if !areSlicesEqual(transactions, saved) {
+				t.Errorf("every entry should be saved once, but are %v", saved)
+			}
+			if !areSlicesEqual(transactions, paged) {
+				t.Errorf("pages should walk every entry, but are %v", paged)
+			}
+		})
+	}
+}
+
+func Test_StorageConformance_DeleteByBlockRange(t *testing.T) {
+	for name, storages := range storagesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			_ = storages.transactions.SaveTransactions(ctx, "0x123", []txparser.Transaction{
+				{BlockNumber: "0x1", Hash: "0xa1"},
+				{BlockNumber: "0x2", Hash: "0xa2"},
+				{BlockNumber: "0x2", Hash: "0xa3"},
+				{BlockNumber: "0x4", Hash: "0xa4"},
+			})
+			_ = storages.transactions.SaveTransactions(ctx, "0x321", []txparser.Transaction{
+				{BlockNumber: "0x2", Hash: "0xa2"},
+			})
+
+			// Act
+			deleted, err := storages.transactions.DeleteTransactionsByBlockRange(ctx, "0x123", 2, 3)
+
+			// Assert
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if deleted != 2 {
+				t.Errorf("deleted should be %d, but is %d", 2, deleted)
+			}
+			transactions, _ := storages.transactions.GetTransactionsByAddress(ctx, "0x123")
+			if !areSlicesEqual([]string{"0xa1", "0xa4"}, hashesOf(transactions)) {
+				t.Errorf("transactions should be %v, but are %v", []string{"0xa1", "0xa4"}, hashesOf(transactions))
+			}
+			transactions, _ = storages.transactions.GetTransactionsByAddress(ctx, "0x321")
+			if len(transactions) != 1 {
+				t.Errorf("transactions of another address should be kept, but are %v", hashesOf(transactions))
+			}
+
+			// Act #2
+			_, _ = storages.transactions.DeleteTransactionsByBlockRange(ctx, "0x321", 0, 10)
+			addresses, err := storages.transactions.Addresses(ctx)
+
+			// Assert
+			if err != nil {
+				t.Error(err)
+			}
+			if !areSlicesEqual([]string{"0x123"}, addresses) {
+				t.Errorf("addresses should be %v, but are %v", []string{"0x123"}, addresses)
+			}
+
+			// Act #3: the context of a finished transaction fails writes
+			var txCtx context.Context
+			_ = storages.transactions.WithDBTransaction(ctx, func(ctx context.Context) error {
+				txCtx = ctx
+				return nil
+			})
+			deleted, err = storages.transactions.DeleteTransactionsByBlockRange(txCtx, "0x123", 0, 10)
+
+			// Assert
+			if err == nil || deleted != 0 {
+				t.Errorf("failed delete should return 0 and an error, but returns %d and %v", deleted, err)
+			}
+		})
+	}
+}
+
+func Test_StorageConformance_Cursors(t *testing.T) {
+	for name, storages := range storagesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+
+			// Act
+			_ = storages.cursors.SaveCursor(ctx, "billing", "0x123", "cursor1")
+			_ = storages.cursors.SaveCursor(ctx, "billing", "0x123", "cursor2")
+			_ = storages.cursors.SaveCursor(ctx, "notifier", "0x123", "cursor3")
+
+			// Assert
+			for _, test := range []struct {
+				consumer string
+				address  string
+				want     string
+			}{
+				{consumer: "billing", address: "0x123", want: "cursor2"},
+				{consumer: "notifier", address: "0x123", want: "cursor3"},
+				{consumer: "notifier", address: "0x321", want: ""},
+				{consumer: "unknown", address: "0x123", want: ""},
+			} {
+				cursor, err := storages.cursors.GetCursor(ctx, test.consumer, test.address)
+				if err != nil {
+					t.Error(err)
+				}
+				if cursor != test.want {
+					t.Errorf("cursor of %s should be %q, but is %q", test.consumer, test.want, cursor)
+				}
+			}
+		})
+	}
+}
+
+func Test_StorageConformance_Subscriptions(t *testing.T) {
+	for name, storages := range storagesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+
+			// Act
+			_ = storages.subscriptions.PutAddress(ctx, "0x321")
+			_ = storages.subscriptions.PutAddress(ctx, "0x123")
+			_ = storages.subscriptions.PutAddress(ctx, "0x321")
+			_ = storages.subscriptions.PutAddress(ctx, "0x789")
+			deleteErr := storages.subscriptions.DeleteAddress(ctx, "0x789")
+			missingErr := storages.subscriptions.DeleteAddress(ctx, "0x456")
+			addresses, err := storages.subscriptions.GetAddresses(ctx)
+
+			// Assert
+			for _, err := range []error{err, deleteErr, missingErr} {
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+			}
+			if !areSlicesEqual(addresses, []string{"0x123", "0x321"}) {
+				t.Errorf("addresses should be %v, but are %v", []string{"0x123", "0x321"}, addresses)
+			}
+			if !isSubscribed(t, ctx, storages.subscriptions, "0x123") || isSubscribed(t, ctx, storages.subscriptions, "0x789") {
+				t.Error("only subscribed addresses should exist")
+			}
+		})
+	}
+}
+
+func Test_StorageConformance_ManagedSubscriptions(t *testing.T) {
+	for name, storages := range storagesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			_ = storages.subscriptions.PutAddress(ctx, "0x123")
+			_ = storages.subscriptions.PutManagedAddress(ctx, "0x456")
+			_ = storages.subscriptions.PutManagedAddress(ctx, "0x789")
+
+			// Act
+			_ = storages.subscriptions.PutManagedAddress(ctx, "0x123")
+			_ = storages.subscriptions.PutAddress(ctx, "0x789")
+			unmanagedErr := storages.subscriptions.DeleteManagedAddress(ctx, "0x123")
+			managedErr := storages.subscriptions.DeleteManagedAddress(ctx, "0x456")
+			missingErr := storages.subscriptions.DeleteManagedAddress(ctx, "0xabc")
+			addresses, err := storages.subscriptions.GetAddresses(ctx)
+			managed, managedListErr := storages.subscriptions.GetManagedAddresses(ctx)
+
+			// Assert
+			for _, err := range []error{err, unmanagedErr, managedErr, missingErr, managedListErr} {
+				if err != nil {
+					t.Error(err)
+					t.FailNow()
+				}
+			}
+			if !areSlicesEqual(addresses, []string{"0x123", "0x789"}) {
+				t.Errorf("addresses should be %v, but are %v", []string{"0x123", "0x789"}, addresses)
+			}
+			if len(managed) != 0 {
+				t.Errorf("managed addresses should be empty, but are %v", managed)
+			}
+		})
+	}
+}
+
+func Test_StorageConformance_ReprocessBlock(t *testing.T) {
+	for name, storages := range storagesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			clock := txparsertest.NewFakeClock(epoch)
+			client := &blocksClient{
+				head: 7,
+				blocks: map[int][]txparser.Transaction{
+					7: {
+						{BlockNumber: "0x7", Hash: "0xabc70", From: "0x123", To: "0x123"},
+						{BlockNumber: "0x7", Hash: "0xabc71", From: "0x123", To: "0x321"},
+						{BlockNumber: "0x7", Hash: "0xabc72", From: "0x456", To: "0x321"},
+					},
+				},
+			}
+			_ = storages.subscriptions.PutAddress(ctx, "0x123")
+			_ = storages.subscriptions.PutAddress(ctx, "0x321")
+
+			// Act: the second run simulates a restart before the cursor was saved
+			for i := 0; i < 2; i++ {
+				_ = storages.blocks.SaveBlockID(ctx, 6)
+				parser := txparser.NewTXParser(
+					storages.blocks,
+					storages.transactions,
+					storages.subscriptions,
+					client,
+					txparser.WithClock(clock),
+				)
+				runUntilBlock(t, parser, clock, storages.blocks, 7)
+			}
+
+			// Assert
+			transactions, err := storages.transactions.GetTransactionsByAddress(ctx, "0x123")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if len(transactions) != 2 {
+				t.Errorf("transactions slice should have %d item(s), but has %d", 2, len(transactions))
+			}
+			transactions, err = storages.transactions.GetTransactionsByAddress(ctx, "0x321")
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if len(transactions) != 2 {
+				t.Errorf("transactions slice should have %d item(s), but has %d", 2, len(transactions))
+			}
+			if blockIDOf(t, ctx, storages.blocks) != 7 {
+				t.Errorf("block id should be %d, but is %d", 7, blockIDOf(t, ctx, storages.blocks))
+			}
+		})
+	}
+}
+
+// runUntilBlock runs the parser worker until the block is parsed.
+// runUntilBlock runs the worker of the parser made with the clock for one pass, which parses
+// blocks up to the head, and stops it.
+func runUntilBlock(
+	t *testing.T,
+	parser *txparser.TXParser,
+	clock *txparsertest.FakeClock,
+	blockStorage txparser.BlockStorage,
+	block int,
+) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- parser.RunWorker(ctx, time.Hour)
+	}()
+	clock.BlockUntil(1)
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if blockIDOf(t, context.Background(), blockStorage) != block {
+		t.Fatalf("block id should be %d, but is %d", block, blockIDOf(t, context.Background(), blockStorage))
+	}
+}
+
+// blocksClient serves a fixed chain of blocks.
+type blocksClient struct {
+	head   int
+	blocks map[int][]txparser.Transaction
+}
+
+func (c *blocksClient) CurrentBlockNumber(_ context.Context) (int, error) {
+	return c.head, nil
+}
+
+func (c *blocksClient) GetBlockByNumber(_ context.Context, number int) (*txparser.Block, error) {
+	return &txparser.Block{Transactions: c.blocks[number]}, nil
+}
+
+func Test_StorageConformance_QueryTransactions(t *testing.T) {
+	fixtures := []txparser.Transaction{
+		{BlockNumber: "0x1", TransactionIndex: "0x0", Hash: "0xa10", From: "0x123", To: "0x321", Value: "0x10"},
+		{BlockNumber: "0x1", TransactionIndex: "0x1", Hash: "0xa11", From: "0x321", To: "0x123", Value: "0x1"},
+		{BlockNumber: "0x2", TransactionIndex: "0x0", Hash: "0xa20", From: "0x123", To: "0x123", Value: "0x0"},
+		{BlockNumber: "0x3", TransactionIndex: "0x2", Hash: "0xa32", From: "0x456", To: "0x123", Value: "0x100"},
+		{BlockNumber: "0x3", TransactionIndex: "0xa", Hash: "0xa3a", From: "0x123", To: "0x456", Value: "0x5"},
+		{BlockNumber: "0x5", TransactionIndex: "0x0", Hash: "0xa50", From: "0x789", To: "0x123", Value: "0x20"},
+	}
+
+	tests := []struct {
+		name  string
+		query txparser.TransactionsQuery
+		want  []string
+	}{
+		{
+			name:  "all ascending",
+			query: txparser.TransactionsQuery{Limit: 2},
+			want:  []string{"0xa10", "0xa11", "0xa20", "0xa32", "0xa3a", "0xa50"},
+		},
+		{
+			name:  "all descending",
+			query: txparser.TransactionsQuery{Limit: 4, Order: txparser.SortDescending},
+			want:  []string{"0xa50", "0xa3a", "0xa32", "0xa20", "0xa11", "0xa10"},
+		},
+		{
+			name:  "block range",
+			query: txparser.TransactionsQuery{Limit: 1, FromBlock: 2, ToBlock: 3},
+			want:  []string{"0xa20", "0xa32", "0xa3a"},
+		},
+		{
+			name:  "block range descending",
+			query: txparser.TransactionsQuery{Limit: 1, FromBlock: 2, ToBlock: 3, Order: txparser.SortDescending},
+			want:  []string{"0xa3a", "0xa32", "0xa20"},
+		},
+		{
+			name:  "incoming",
+			query: txparser.TransactionsQuery{Limit: 2, Direction: txparser.DirectionIncoming},
+			want:  []string{"0xa11", "0xa32", "0xa50"},
+		},
+		{
+			name:  "outgoing",
+			query: txparser.TransactionsQuery{Direction: txparser.DirectionOutgoing},
+			want:  []string{"0xa10", "0xa3a"},
+		},
+		{
+			name:  "self",
+			query: txparser.TransactionsQuery{Direction: txparser.DirectionSelf},
+			want:  []string{"0xa20"},
+		},
+		{
+			name:  "min value",
+			query: txparser.TransactionsQuery{Limit: 1, MinValue: big.NewInt(0x10)},
+			want:  []string{"0xa10", "0xa32", "0xa50"},
+		},
+	}
+
+	for name, storages := range storagesUnderTest(t) {
+		ctx := context.Background()
+		err := storages.transactions.SaveTransactions(ctx, "0x123", fixtures)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+
+		for _, test := range tests {
+			t.Run(name+"/"+test.name, func(t *testing.T) {
+				// Act
+				var hashes []string
+				query := test.query
+				query.Address = "0x123"
+				for pages := 0; ; pages++ {
+					page, err := storages.transactions.QueryTransactions(ctx, query)
+					if err != nil {
+						t.Error(err)
+						t.FailNow()
+					}
+					if len(page.Transactions) > query.Limit && query.Limit > 0 {
+						t.Errorf("page should have at most %d item(s), but has %d", query.Limit, len(page.Transactions))
+					}
+					for _, tx := range page.Transactions {
+						hashes = append(hashes, tx.Hash)
+					}
+					if page.NextCursor == "" || pages > len(fixtures) {
+						break
+					}
+					query.Cursor = page.NextCursor
+				}
+
+				// Assert
+				if !areSlicesEqual(test.want, hashes) {
+					t.Errorf("transactions should be %v, but are %v", test.want, hashes)
+				}
+			})
+		}
+
+		t.Run(name+"/invalid cursor", func(t *testing.T) {
+			_, err := storages.transactions.QueryTransactions(ctx, txparser.TransactionsQuery{
+				Address: "0x123",
+				Cursor:  "invalid",
+			})
+			if !errors.Is(err, txparser.ErrInvalidCursor) {
+				t.Errorf("error should be %v, but is %v", txparser.ErrInvalidCursor, err)
+			}
+		})
+	}
+}
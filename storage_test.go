@@ -2,6 +2,7 @@ package txparser_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
 	"sync"
@@ -89,3 +90,151 @@ func Test_Storage_BigInt(t *testing.T) {
 		t.Error("hashes should be equal")
 	}
 }
+
+func Test_Storage_WithDBTransaction_Commit(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	storage := txparser.NewInmemoryTransactionsStorage()
+
+	// Act
+	err := storage.WithDBTransaction(ctx, func(ctx context.Context) error {
+		err := storage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc1"}})
+		if err != nil {
+			return err
+		}
+
+		// Assert isolation
+		transactions, err := storage.GetTransactionsByAddress(context.Background(), "0x123")
+		if err != nil {
+			return err
+		}
+		if len(transactions) != 0 {
+			t.Errorf("uncommitted transactions should not be visible, but %d found", len(transactions))
+		}
+
+		transactions, err = storage.GetTransactionsByAddress(ctx, "0x123")
+		if err != nil {
+			return err
+		}
+		if len(transactions) != 1 {
+			t.Errorf("staged transactions should be visible within transaction, but %d found", len(transactions))
+		}
+
+		return nil
+	})
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	transactions, err := storage.GetTransactionsByAddress(ctx, "0x123")
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	if len(transactions) != 1 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 1, len(transactions))
+	}
+}
+
+func Test_Storage_WithDBTransaction_RollbackOnError(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	txStorage := txparser.NewInmemoryTransactionsStorage()
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	_ = blockStorage.SaveBlockID(ctx, 1)
+	_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc1"}})
+	errFailed := errors.New("failed")
+
+	// Act
+	err := blockStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+		return txStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+			_ = txStorage.DeleteTransactionsByAddress(ctx, "0x123")
+			_ = txStorage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc2"}})
+			_ = blockStorage.SaveBlockID(ctx, 2)
+
+			return errFailed
+		})
+	})
+
+	// Assert
+	if !errors.Is(err, errFailed) {
+		t.Errorf("error should be %v, but is %v", errFailed, err)
+	}
+	if blockIDOf(t, ctx, blockStorage) != 1 {
+		t.Errorf("block id should be %d, but is %d", 1, blockIDOf(t, ctx, blockStorage))
+	}
+	transactions, _ := txStorage.GetTransactionsByAddress(ctx, "0x123")
+	if len(transactions) != 1 || transactions[0].Hash != "0xabc1" {
+		t.Errorf("transactions should not be changed, but are %v", transactions)
+	}
+}
+
+func Test_Storage_WithDBTransaction_RollbackOnPanic(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	storage := txparser.NewInmemoryTransactionsStorage()
+
+	// Act
+	func() {
+		defer func() {
+			_ = recover()
+		}()
+
+		_ = storage.WithDBTransaction(ctx, func(ctx context.Context) error {
+			_ = storage.SaveTransactions(ctx, "0x123", []txparser.Transaction{{Hash: "0xabc1"}})
+			panic("failed")
+		})
+	}()
+
+	// Assert
+	transactions, _ := storage.GetTransactionsByAddress(ctx, "0x123")
+	if len(transactions) != 0 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 0, len(transactions))
+	}
+}
+
+func Test_Storage_WithDBTransaction_ClosedTransaction(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	storage := txparser.NewInmemoryTransactionsStorage()
+	var txCtx context.Context
+
+	_ = storage.WithDBTransaction(ctx, func(ctx context.Context) error {
+		txCtx = ctx
+		return nil
+	})
+
+	// Act
+	err := storage.SaveTransactions(txCtx, "0x123", []txparser.Transaction{{Hash: "0xabc1"}})
+
+	// Assert
+	if !errors.Is(err, txparser.ErrTransactionClosed) {
+		t.Errorf("error should be %v, but is %v", txparser.ErrTransactionClosed, err)
+	}
+}
+
+// blockIDOf returns the saved block ID, failing the test if it can not be read.
+func blockIDOf(t *testing.T, ctx context.Context, storage txparser.BlockStorage) int {
+	t.Helper()
+
+	blockID, err := storage.GetBlockID(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return blockID
+}
+
+// isSubscribed reports whether the address is subscribed, failing the test if it can not be checked.
+func isSubscribed(t *testing.T, ctx context.Context, storage txparser.SubscriptionsStorage, address string) bool {
+	t.Helper()
+
+	exists, err := storage.IsAddressExists(ctx, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exists
+}
@@ -0,0 +1,40 @@
+package txparser
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+var (
+	ErrUnknownAPIKey             = errors.New("unknown api key")
+	ErrSubscriptionQuotaExceeded = errors.New("subscription quota exceeded")
+)
+
+// Tenant owns API keys and the addresses subscribed with them.
+type Tenant struct {
+	ID string `json:"id"`
+
+	// MaxSubscriptions limits the number of addresses the tenant subscribes to, zero disables the limit.
+	MaxSubscriptions int `json:"maxSubscriptions"`
+}
+
+// HashAPIKey returns the hex encoded SHA-256 hash API keys are stored by, keys themselves are never stored.
+func HashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(hash[:])
+}
+
+// GenerateAPIKey returns a new random API key.
+func GenerateAPIKey() (string, error) {
+	key := make([]byte, 32)
+
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(key), nil
+}
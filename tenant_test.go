@@ -0,0 +1,165 @@
+package txparser_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"txparser"
+)
+
+type tenantStorages struct {
+	apiKeys       txparser.APIKeyStorage
+	subscriptions txparser.TenantSubscriptionsStorage
+}
+
+// tenantStoragesUnderTest returns fresh instances of every tenant storage implementation.
+func tenantStoragesUnderTest(t *testing.T) map[string]tenantStorages {
+	t.Helper()
+
+	result := map[string]tenantStorages{
+		"inmemory": {
+			apiKeys:       txparser.NewInmemoryAPIKeyStorage(),
+			subscriptions: txparser.NewInmemoryTenantSubscriptionsStorage(),
+		},
+	}
+
+	fileSubscriptions, err := txparser.NewFileTenantSubscriptionsStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		closeFileStorages(t, fileSubscriptions)
+	})
+	result["file"] = tenantStorages{
+		apiKeys:       txparser.NewInmemoryAPIKeyStorage(),
+		subscriptions: fileSubscriptions,
+	}
+
+	for dialect, db := range sqlTestDatabases(t) {
+		result["sql/"+string(dialect)] = tenantStorages{
+			apiKeys:       txparser.NewSQLAPIKeyStorage(db, dialect),
+			subscriptions: txparser.NewSQLTenantSubscriptionsStorage(db, dialect),
+		}
+	}
+
+	return result
+}
+
+func Test_TenantStorage_APIKeys(t *testing.T) {
+	for name, storages := range tenantStoragesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			key, err := txparser.GenerateAPIKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Act
+			_ = storages.apiKeys.PutAPIKey(ctx, txparser.HashAPIKey(key), txparser.Tenant{ID: "acme", MaxSubscriptions: 1})
+			err = storages.apiKeys.PutAPIKey(ctx, txparser.HashAPIKey(key), txparser.Tenant{ID: "acme", MaxSubscriptions: 2})
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+
+			// Assert
+			tenant, err := storages.apiKeys.GetTenantByAPIKey(ctx, txparser.HashAPIKey(key))
+			if err != nil {
+				t.Error(err)
+				t.FailNow()
+			}
+			if *tenant != (txparser.Tenant{ID: "acme", MaxSubscriptions: 2}) {
+				t.Errorf("unexpected tenant %+v", tenant)
+			}
+			_, err = storages.apiKeys.GetTenantByAPIKey(ctx, txparser.HashAPIKey(key+"0"))
+			if !errors.Is(err, txparser.ErrUnknownAPIKey) {
+				t.Errorf("error should be %v, but is %v", txparser.ErrUnknownAPIKey, err)
+			}
+		})
+	}
+}
+
+func Test_TenantStorage_SubscriptionQuota(t *testing.T) {
+	for name, storages := range tenantStoragesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			_ = storages.subscriptions.PutTenantAddress(ctx, "acme", "0x123", 2)
+			_ = storages.subscriptions.PutTenantAddress(ctx, "acme", "0x321", 2)
+
+			// Act
+			errResubscribe := storages.subscriptions.PutTenantAddress(ctx, "acme", "0x123", 2)
+			errExceeded := storages.subscriptions.PutTenantAddress(ctx, "acme", "0x456", 2)
+			errOtherTenant := storages.subscriptions.PutTenantAddress(ctx, "globex", "0x456", 2)
+
+			// Assert
+			if errResubscribe != nil {
+				t.Error(errResubscribe)
+			}
+			if !errors.Is(errExceeded, txparser.ErrSubscriptionQuotaExceeded) {
+				t.Errorf("error should be %v, but is %v", txparser.ErrSubscriptionQuotaExceeded, errExceeded)
+			}
+			if errOtherTenant != nil {
+				t.Error(errOtherTenant)
+			}
+			exists, err := storages.subscriptions.IsTenantAddressExists(ctx, "acme", "0x321")
+			if err != nil || !exists {
+				t.Errorf("address should exist, but exists is %v (%v)", exists, err)
+			}
+			foreign, err := storages.subscriptions.IsTenantAddressExists(ctx, "acme", "0x456")
+			if err != nil || foreign {
+				t.Errorf("address of another tenant should not exist, but exists is %v (%v)", foreign, err)
+			}
+			addresses, err := storages.subscriptions.GetTenantAddresses(ctx, "acme")
+			if err != nil {
+				t.Error(err)
+			}
+			if !areSlicesEqual([]string{"0x123", "0x321"}, addresses) {
+				t.Errorf("addresses should be %v, but are %v", []string{"0x123", "0x321"}, addresses)
+			}
+		})
+	}
+}
+
+func Test_TenantStorage_ConcurrentSubscriptionQuota(t *testing.T) {
+	for name, storages := range tenantStoragesUnderTest(t) {
+		t.Run(name, func(t *testing.T) {
+			// Arrange
+			ctx := context.Background()
+			errs := make(chan error, 10)
+
+			// Act
+			var wg sync.WaitGroup
+			for i := 0; i < cap(errs); i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs <- storages.subscriptions.PutTenantAddress(ctx, "acme", fmt.Sprintf("0x%d", i), 3)
+				}(i)
+			}
+			wg.Wait()
+			close(errs)
+
+			// Assert
+			exceeded := 0
+			for err := range errs {
+				if errors.Is(err, txparser.ErrSubscriptionQuotaExceeded) {
+					exceeded++
+				} else if err != nil {
+					t.Error(err)
+				}
+			}
+			addresses, err := storages.subscriptions.GetTenantAddresses(ctx, "acme")
+			if err != nil {
+				t.Error(err)
+			}
+			if len(addresses) != 3 || exceeded != 7 {
+				t.Errorf("quota should admit %d of %d addresses, but admitted %v", 3, 10, addresses)
+			}
+		})
+	}
+}
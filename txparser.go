@@ -2,7 +2,8 @@ package txparser
 
 import (
 	"context"
-	"log"
+	"log/slog"
+	"sync"
 	"time"
 )
 
@@ -13,24 +14,108 @@ type Block struct {
 }
 
 type Transaction struct {
-	BlockNumber string `json:"blockNumber"`
-	BlockHash   string `json:"blockHash"`
-	Hash        string `json:"hash"`
-	From        string `json:"from"`
-	To          string `json:"to"`
-	Value       string `json:"value"`
+	BlockNumber      string `json:"blockNumber"`
+	BlockHash        string `json:"blockHash"`
+	Hash             string `json:"hash"`
+	TransactionIndex string `json:"transactionIndex"`
+	From             string `json:"from"`
+	To               string `json:"to"`
+	Value            string `json:"value"`
+
+	// Kind tells entries of the same transaction for an address apart, it is empty for the transaction itself.
+	Kind string `json:"kind,omitempty"`
+	// LogIndex is the index in the block of the log the entry comes from, such as a token transfer.
+	LogIndex string `json:"logIndex,omitempty"`
 }
 
+// KindTokenTransfer is the Kind of entries of token transfers logged by a transaction.
+const KindTokenTransfer = "token_transfer"
+
 type TXParser struct {
 	ctx context.Context //TODO: Ask about context in interface
 
 	blocksStorage       BlockStorage
 	transactionsStorage TransactionStorage
 	subscriptionStorage SubscriptionsStorage
+	cursorStorage       CursorStorage
+
+	clientMu sync.RWMutex
+	client   Client
+
+	startPolicy StartPolicy
+
+	metrics *Metrics
+	logger  *slog.Logger
+	clock   Clock
+
+	worker       *worker
+	maxBackoff   time.Duration
+	errorHandler func(err *WorkerError)
+
+	statusMu sync.Mutex
+	status   Status
+
+	// Serializes consumer acks
+	ackMu sync.Mutex
+
+	listenersMu sync.Mutex
+	listeners   map[chan Event]struct{}
+}
+
+type Option func(*TXParser)
+
+// WithStartPolicy sets the block RunWorker starts parsing from, ResumeFromCursor by default.
+func WithStartPolicy(policy StartPolicy) Option {
+	return func(p *TXParser) {
+		p.startPolicy = policy
+	}
+}
+
+// WithCursorStorage sets the storage of consumer cursors used by Fetch and Ack,
+// in-memory storage by default.
+func WithCursorStorage(storage CursorStorage) Option {
+	return func(p *TXParser) {
+		p.cursorStorage = storage
+	}
+}
 
-	client Client
+// WithLogger sets the logger of the parser and its worker, records are dropped by default or if it is nil.
+func WithLogger(logger *slog.Logger) Option {
+	return func(p *TXParser) {
+		if logger != nil {
+			p.logger = logger
+		}
+	}
+}
 
-	worker *worker
+// WithErrorHandler calls handler with errors of failed passes of the worker and failed attempts to start it.
+// The handler is called by the worker goroutine, so it should not block.
+func WithErrorHandler(handler func(err *WorkerError)) Option {
+	return func(p *TXParser) {
+		p.errorHandler = handler
+	}
+}
+
+// WithMaxBackoff caps the delay between passes of a failing worker, a minute by default.
+// The delay doubles after every consecutive failure and is never shorter than the poll period.
+func WithMaxBackoff(maxBackoff time.Duration) Option {
+	return func(p *TXParser) {
+		p.maxBackoff = maxBackoff
+	}
+}
+
+// WithClock sets the clock of the worker and the status, SystemClock by default.
+func WithClock(clock Clock) Option {
+	return func(p *TXParser) {
+		p.clock = clock
+	}
+}
+
+// WithMetrics collects metrics of parsed blocks, the head lag and subscriptions.
+func WithMetrics(metrics *Metrics) Option {
+	return func(p *TXParser) {
+		p.metrics = metrics
+	}
 }
 
 func NewTXParser(
@@ -38,16 +123,38 @@ func NewTXParser(
 	transactionStorage TransactionStorage,
 	subscriptionStorage SubscriptionsStorage,
 	client Client,
+	opts ...Option,
 ) *TXParser {
 	txParser := &TXParser{
 		ctx:                 context.Background(),
 		blocksStorage:       blockStorage,
 		transactionsStorage: transactionStorage,
 		subscriptionStorage: subscriptionStorage,
+		cursorStorage:       NewInmemoryCursorStorage(),
 		client:              client,
+		startPolicy:         ResumeFromCursor(),
+		logger:              discardLogger(),
+		clock:               SystemClock(),
+		status:              Status{State: WorkerIdle},
+		listeners:           make(map[chan Event]struct{}),
+	}
+
+	for _, opt := range opts {
+		opt(txParser)
+	}
+
+	txParser.worker = newWorker(txParser.parseProcess, txParser.handleWorkerError)
+	txParser.worker.clock = txParser.clock
+	if txParser.maxBackoff > 0 {
+		txParser.worker.maxBackoff = txParser.maxBackoff
 	}
 
-	txParser.worker = newWorker(txParser.parseProcess)
+	if txParser.metrics != nil {
+		txParser.metrics.subscriptions.setCollect(func(ctx context.Context) (float64, error) {
+			addresses, err := subscriptionStorage.GetAddresses(ctx)
+			return float64(len(addresses)), err
+		})
+	}
 
 	return txParser
 }
@@ -56,30 +163,149 @@ func (p *TXParser) SetContext(ctx context.Context) {
 	p.ctx = ctx
 }
 
-func (p *TXParser) RunWorker(ctx context.Context, period time.Duration) {
-	// We don't need to scan all the blocks from the beginning
-	currentBlockNumber, err := p.client.CurrentBlockNumber(ctx)
+// RunWorker parses new blocks every period until ctx is done or Stop is called.
+// The first parsed block is defined by the start policy. Failed attempts to start are
+// retried with backoff, RunWorker returns the error if the start policy fails or
+// ctx is done before the worker started.
+func (p *TXParser) RunWorker(ctx context.Context, period time.Duration) error {
+	p.updateStatus(func(s *Status) {
+		s.State = WorkerStarting
+		s.StartedAt = p.clock.Now()
+	})
+	defer p.updateStatus(func(s *Status) {
+		s.State = WorkerStopped
+	})
+
+	err := p.worker.Run(ctx, period, func(ctx context.Context) error {
+		return p.startWorker(ctx, period)
+	})
+	if err != nil {
+		return err
+	}
+	p.logger.InfoContext(ctx, "worker stopped")
+
+	return nil
+}
+
+// startWorker saves the block preceding the first parsed one, errors of the start policy are permanent.
+func (p *TXParser) startWorker(ctx context.Context, period time.Duration) error {
+	currentBlockNumber, err := p.getClient().CurrentBlockNumber(ctx)
+	if err != nil {
+		p.logger.ErrorContext(ctx, "get head block", "error", err)
+		p.setStatusError(err)
+		return err
+	}
+
+	savedBlock, err := p.blocksStorage.GetBlockID(ctx)
+	if err != nil {
+		p.logger.ErrorContext(ctx, "get saved block", "error", err)
+		p.setStatusError(err)
+		return err
+	}
+	cursor, err := p.startPolicy.cursor(currentBlockNumber, savedBlock)
 	if err != nil {
-		log.Print(err)
-		return
+		p.logger.ErrorContext(ctx, "apply start policy", "head", currentBlockNumber, "saved_block", savedBlock, "error", err)
+		p.setStatusError(err)
+		return permanentError{err: err}
 	}
-	err = p.blocksStorage.SaveBlockID(ctx, currentBlockNumber)
+
+	err = p.blocksStorage.SaveBlockID(ctx, cursor)
 	if err != nil {
-		log.Print(err)
-		return
+		p.logger.ErrorContext(ctx, "save start block", "block", cursor, "error", err)
+		p.setStatusError(err)
+		return err
+	}
+
+	p.updateStatus(func(s *Status) {
+		s.State = WorkerRunning
+		s.Head = currentBlockNumber
+		s.LastBlock = cursor
+	})
+	p.logger.InfoContext(ctx, "worker started", "last_parsed_block", cursor, "head", currentBlockNumber, "period", period)
+
+	return nil
+}
+
+// handleWorkerError logs the backoff and passes the error to the error handler.
+func (p *TXParser) handleWorkerError(ctx context.Context, err *WorkerError) {
+	p.logger.WarnContext(ctx, "retry worker", "attempt", err.Attempt, "retry_in", err.RetryIn, "error", err.Err)
+	if p.errorHandler != nil {
+		p.errorHandler(err)
+	}
+}
+
+// Pause stops parsing blocks until Resume, the worker keeps running.
+func (p *TXParser) Pause() {
+	p.worker.Pause()
+}
+
+// Resume parses blocks right away and continues parsing stopped by Pause.
+func (p *TXParser) Resume() {
+	p.worker.Resume()
+}
+
+// Stop stops the worker after the block being parsed, RunWorker returns and Done is closed.
+// A parser whose worker has not been run yet returns from the next RunWorker right away.
+func (p *TXParser) Stop() {
+	p.worker.Stop()
+}
+
+// Done returns a channel closed when RunWorker returns.
+func (p *TXParser) Done() <-chan struct{} {
+	return p.worker.Done()
+}
+
+// SetPollPeriod changes the period of the running worker without restarting it,
+// it reports false if the worker is not running or the period is not positive.
+func (p *TXParser) SetPollPeriod(period time.Duration) bool {
+	if period <= 0 {
+		return false
 	}
 
-	p.worker.Run(ctx, period)
+	return p.worker.SetPeriod(period)
+}
+
+// SetClient replaces the client of the node, blocks being parsed are finished by the previous one.
+func (p *TXParser) SetClient(client Client) {
+	p.clientMu.Lock()
+	defer p.clientMu.Unlock()
+
+	p.client = client
 }
 
+func (p *TXParser) getClient() Client {
+	p.clientMu.RLock()
+	defer p.clientMu.RUnlock()
+
+	return p.client
+}
+
+// GetCurrentBlock returns the last parsed block, it is 0 if the storage fails to read it.
 func (p *TXParser) GetCurrentBlock() int {
-	return p.blocksStorage.GetBlockID(p.ctx)
+	blockID, err := p.blocksStorage.GetBlockID(p.ctx)
+	if err != nil {
+		p.logger.ErrorContext(p.ctx, "get current block", "error", err)
+		return 0
+	}
+
+	return blockID
 }
 
 func (p *TXParser) Subscribe(address string) bool {
 	err := p.subscriptionStorage.PutAddress(p.ctx, address)
 	if err != nil {
-		log.Print(err)
+		p.logger.ErrorContext(p.ctx, "subscribe", "address", address, "error", err)
+		return false
+	}
+
+	return true
+}
+
+// Unsubscribe stops saving transactions of the address, saved ones are kept.
+func (p *TXParser) Unsubscribe(address string) bool {
+	err := p.subscriptionStorage.DeleteAddress(p.ctx, address)
+	if err != nil {
+		p.logger.ErrorContext(p.ctx, "unsubscribe", "address", address, "error", err)
 		return false
 	}
 
@@ -89,73 +315,157 @@ func (p *TXParser) Subscribe(address string) bool {
 func (p *TXParser) GetTransactions(address string) []Transaction {
 	transactions, err := p.transactionsStorage.GetTransactionsByAddress(p.ctx, address)
 	if err != nil {
-		log.Print(err)
+		p.logger.ErrorContext(p.ctx, "get transactions", "address", address, "error", err)
 		return nil
 	}
 
-	// TODO: Clarify whether transactions need to be deleted
-	// err = p.transactionsStorage.DeleteTransactionsByAddress(p.ctx, address)
-	// if err != nil {
-	//	log.Print(err)
-	// }
-
 	return transactions
 }
 
+// QueryTransactions returns a page of the address transactions matching the query.
+func (p *TXParser) QueryTransactions(ctx context.Context, query TransactionsQuery) (*TransactionsPage, error) {
+	query, err := query.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	return p.transactionsStorage.QueryTransactions(ctx, query)
+}
+
+// parseProcess is a pass of the worker, the next pass retries failed blocks.
 func (p *TXParser) parseProcess(ctx context.Context) error {
-	currentBlockNumber, err := p.client.CurrentBlockNumber(ctx)
+	err := p.parse(ctx)
+	if err != nil {
+		p.setStatusError(err)
+		if p.metrics != nil {
+			p.metrics.parseErrors.add(1)
+		}
+		return err
+	}
+
+	p.updateStatus(func(s *Status) {
+		s.LastTickAt = p.clock.Now()
+	})
+
+	return nil
+}
+
+func (p *TXParser) parse(ctx context.Context) error {
+	// A pass sticks to one client, so the blocks up to its head exist on it
+	client := p.getClient()
+
+	currentBlockNumber, err := client.CurrentBlockNumber(ctx)
 	if err != nil {
+		p.logger.ErrorContext(ctx, "get head block", "error", err)
 		return err
 	}
 
-	lastSavedBlockNumber := p.blocksStorage.GetBlockID(ctx)
+	lastSavedBlockNumber, err := p.blocksStorage.GetBlockID(ctx)
 	if err != nil {
+		p.logger.ErrorContext(ctx, "get saved block", "error", err)
 		return err
 	}
+	p.updateStatus(func(s *Status) {
+		s.Head = currentBlockNumber
+		s.LastBlock = lastSavedBlockNumber
+	})
+	if p.metrics != nil {
+		p.metrics.observeHead(currentBlockNumber, lastSavedBlockNumber)
+	}
 
 	if currentBlockNumber <= lastSavedBlockNumber {
 		return nil
 	}
 
 	for blockID := lastSavedBlockNumber + 1; blockID <= currentBlockNumber; blockID++ {
-		err := p.singleBlockProcess(ctx, blockID)
+		start := p.clock.Now()
+		stored, err := p.singleBlockProcess(ctx, client, blockID)
 		if err != nil {
+			p.logger.ErrorContext(ctx, "parse block", "block", blockID, "head", currentBlockNumber, "error", err)
 			return err
 		}
+		p.logger.DebugContext(ctx, "block parsed", "block", blockID, "head", currentBlockNumber, "transactions", stored)
+		p.updateStatus(func(s *Status) {
+			s.LastBlock = blockID
+			s.LastTickAt = p.clock.Now()
+		})
+		if p.metrics != nil {
+			p.metrics.observeBlock(currentBlockNumber, blockID, p.clock.Now().Sub(start), stored)
+		}
 	}
 
 	return nil
 }
 
-func (p *TXParser) singleBlockProcess(ctx context.Context, blockID int) error {
-	block, err := p.client.GetBlockByNumber(ctx, blockID)
+// singleBlockProcess parses the block and returns the number of saved transactions.
+func (p *TXParser) singleBlockProcess(ctx context.Context, client Client, blockID int) (int, error) {
+	block, err := client.GetBlockByNumber(ctx, blockID)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return p.transactionsStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
-		var err error
-		for _, transaction := range block.Transactions {
-			if p.subscriptionStorage.IsAddressExists(ctx, transaction.From) {
-				err = p.transactionsStorage.SaveTransactions(ctx, transaction.From, []Transaction{transaction})
-				if err != nil {
-					return err
-				}
-			}
+	var events []Event
 
-			if p.subscriptionStorage.IsAddressExists(ctx, transaction.To) {
-				err = p.transactionsStorage.SaveTransactions(ctx, transaction.To, []Transaction{transaction})
-				if err != nil {
-					return err
-				}
-			}
+	err = p.withDBTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		events, err = p.saveBlockTransactions(ctx, block)
+		if err != nil {
+			return err
 		}
 
 		err = p.blocksStorage.SaveBlockID(ctx, blockID)
 		if err != nil {
 			return err
 		}
+		events = append(events, Event{Type: EventBlock, BlockNumber: blockID})
 
 		return nil
 	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Listeners are notified only about committed transactions
+	p.publish(events)
+
+	return len(events) - 1, nil
+}
+
+// saveBlockTransactions saves transactions of the block from or to subscribed addresses
+// and returns their events.
+func (p *TXParser) saveBlockTransactions(ctx context.Context, block *Block) ([]Event, error) {
+	var events []Event
+
+	for _, transaction := range block.Transactions {
+		addresses := []string{transaction.From}
+		if transaction.To != transaction.From {
+			addresses = append(addresses, transaction.To)
+		}
+
+		for _, address := range addresses {
+			subscribed, err := p.subscriptionStorage.IsAddressExists(ctx, address)
+			if err != nil {
+				return nil, err
+			}
+			if !subscribed {
+				continue
+			}
+
+			err = p.transactionsStorage.SaveTransactions(ctx, address, []Transaction{transaction})
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, newTransactionEvent(address, transaction))
+		}
+	}
+
+	return events, nil
+}
+
+// withDBTransaction runs fn within transactions of both the blocks and the transactions storages,
+// so a failed block leaves neither saved transactions nor an advanced block ID behind.
+func (p *TXParser) withDBTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return p.blocksStorage.WithDBTransaction(ctx, func(ctx context.Context) error {
+		return p.transactionsStorage.WithDBTransaction(ctx, fn)
+	})
 }
@@ -9,14 +9,22 @@ import (
 	"time"
 
 	"txparser"
+	"txparser/txparsertest"
 )
 
-// TODO: Improve tests.
-// Clarify github.com/stretchr/testify usage ability.
+// epoch is the start time of fake clocks.
+var epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
 func Test_Parser(t *testing.T) {
 	// Arrange
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
+	clock := txparsertest.NewFakeClock(epoch)
+	node := txparsertest.NewNode()
+	defer node.Close()
+	node.Send(txparsertest.Tx{Hash: "0xabc10", From: "0x123", To: "0x321"})
+	node.Mine()
+
 	blockStorage := txparser.NewInmemoryBlockStorage()
 	txStorage := txparser.NewInmemoryTransactionsStorage()
 	subscriptionsStorage := txparser.NewInmemorySubscriptionsStorage()
@@ -24,15 +32,21 @@ func Test_Parser(t *testing.T) {
 		blockStorage,
 		txStorage,
 		subscriptionsStorage,
-		newClient(),
+		node.Client(),
+		txparser.WithClock(clock),
 	)
+	parser.Subscribe("0x123")
 
 	go parser.RunWorker(ctx, 1*time.Second)
-
-	parser.Subscribe("0x123")
-	time.Sleep(2 * time.Second)
+	clock.BlockUntil(1)
 
 	// Act
+	node.Send(
+		txparsertest.Tx{Hash: "0xabc20", From: "0x123", To: "0x321"},
+		txparsertest.Tx{Hash: "0xabc21", From: "0x321", To: "0x1337"},
+	)
+	node.Mine()
+	advance(clock, time.Second)
 	transactions := parser.GetTransactions("0x123")
 	if len(transactions) != 1 {
 		t.Errorf("transactions slice should have %d item(s), but has %d", 1, len(transactions))
@@ -41,152 +55,189 @@ func Test_Parser(t *testing.T) {
 
 	// Assert
 	if !areStructsEqual(transactions[0], txparser.Transaction{
-		BlockNumber: "0x2",
-		Hash:        "0xabc20",
-		From:        "0x123",
-		To:          "0x321",
+		BlockNumber:      "0x2",
+		BlockHash:        node.BlockHash(2),
+		Hash:             "0xabc20",
+		TransactionIndex: "0x0",
+		From:             "0x123",
+		To:               "0x321",
+		Value:            "0x0",
 	}) {
 		t.Error("structures should be equal")
 		t.FailNow()
 	}
 
 	// Act #2
-	time.Sleep(3 * time.Second)
-	transactions = parser.GetTransactions("0x123")
-	sort.Slice(transactions, func(i, j int) bool {
-		return transactions[i].Hash < transactions[j].Hash
-	})
+	node.Send(
+		txparsertest.Tx{Hash: "0xabc30", From: "0x456", To: "0x123"},
+		txparsertest.Tx{Hash: "0xabc31", From: "0x321", To: "0x1337"},
+		txparsertest.Tx{Hash: "0xabc32", From: "0x123", To: "0x678"},
+	)
+	node.Mine()
+	node.Send(txparsertest.Tx{Hash: "0xabc40", From: "0x789", To: "0x123"})
+	node.Mine()
+	advance(clock, time.Second)
+	hashes := hashesOf(parser.GetTransactions("0x123"))
+	sort.Strings(hashes)
 
 	// Assert
-	if len(transactions) != 4 {
-		t.Errorf("transactions slice should have %d item(s), but has %d", 4, len(transactions))
-		t.FailNow()
+	if !areSlicesEqual([]string{"0xabc20", "0xabc30", "0xabc32", "0xabc40"}, hashes) {
+		t.Errorf("transactions should be %v, but are %v", []string{"0xabc20", "0xabc30", "0xabc32", "0xabc40"}, hashes)
 	}
-	if !areSlicesEqual([]txparser.Transaction{
-		{
-			BlockNumber: "0x2",
-			Hash:        "0xabc20",
-			From:        "0x123",
-			To:          "0x321",
-		},
-		{
-			BlockNumber: "0x3",
-			Hash:        "0xabc30",
-			From:        "0x456",
-			To:          "0x123",
+}
+
+func Test_Parser_Reconfigure(t *testing.T) {
+	// Arrange
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	clock := txparsertest.NewFakeClock(epoch)
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	subscriptionsStorage := txparser.NewInmemorySubscriptionsStorage()
+	parser := txparser.NewTXParser(
+		blockStorage,
+		txparser.NewInmemoryTransactionsStorage(),
+		subscriptionsStorage,
+		&blocksClient{head: 1},
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+		txparser.WithClock(clock),
+	)
+	parser.Subscribe("0x123")
+	parser.Subscribe("0x456")
+	notRunning := parser.SetPollPeriod(time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		done <- parser.RunWorker(ctx, time.Hour)
+	}()
+	clock.BlockUntil(1)
+
+	// Act
+	parser.SetClient(&blocksClient{
+		head: 3,
+		blocks: map[int][]txparser.Transaction{
+			3: {
+				{BlockNumber: "0x3", Hash: "0xabc30", From: "0x123", To: "0x321"},
+				{BlockNumber: "0x3", Hash: "0xabc31", From: "0x456", To: "0x321"},
+			},
 		},
+	})
+	parser.Unsubscribe("0x456")
+	running := parser.SetPollPeriod(time.Millisecond)
+	invalid := parser.SetPollPeriod(0)
+	advanceUntilBlock(t, clock, blockStorage, 3, time.Hour)
+	cancel()
+	err := <-done
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+	}
+	if notRunning || !running || invalid {
+		t.Errorf("poll period should be set only on the running worker, but results are %v, %v, %v", notRunning, running, invalid)
+	}
+	if transactions := parser.GetTransactions("0x123"); len(transactions) != 1 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 1, len(transactions))
+	}
+	if transactions := parser.GetTransactions("0x456"); len(transactions) != 0 {
+		t.Errorf("transactions slice should have %d item(s), but has %d", 0, len(transactions))
+	}
+}
+
+func Test_Parser_StorageReadFails(t *testing.T) {
+	node := txparsertest.NewNode()
+	defer node.Close()
+	node.Send(txparsertest.Tx{From: "0x123", To: "0x321"})
+	node.Mine()
+
+	tests := []struct {
+		name          string
+		blocks        func(txparser.BlockStorage) txparser.BlockStorage
+		subscriptions func(txparser.SubscriptionsStorage) txparser.SubscriptionsStorage
+	}{
 		{
-			BlockNumber: "0x3",
-			Hash:        "0xabc32",
-			From:        "0x123",
-			To:          "0x678",
+			name: "saved block on start",
+			blocks: func(s txparser.BlockStorage) txparser.BlockStorage {
+				return unreadableBlockStorage{BlockStorage: s}
+			},
+			subscriptions: func(s txparser.SubscriptionsStorage) txparser.SubscriptionsStorage { return s },
 		},
 		{
-			BlockNumber: "0x4",
-			Hash:        "0xabc40",
-			From:        "0x789",
-			To:          "0x123",
+			name:   "subscription of a transaction",
+			blocks: func(s txparser.BlockStorage) txparser.BlockStorage { return s },
+			subscriptions: func(s txparser.SubscriptionsStorage) txparser.SubscriptionsStorage {
+				return uncheckableSubscriptionsStorage{SubscriptionsStorage: s}
+			},
 		},
-	}, transactions) {
-		t.Error("transactions slices should be equal")
-		t.FailNow()
 	}
-}
 
-type client struct {
-	start time.Time
-}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			clock := txparsertest.NewFakeClock(epoch)
+			blockStorage := txparser.NewInmemoryBlockStorage()
+			subscriptionsStorage := txparser.NewInmemorySubscriptionsStorage()
+			_ = subscriptionsStorage.PutAddress(ctx, "0x123")
+			errs := make(chan *txparser.WorkerError, 1)
+			parser := txparser.NewTXParser(
+				test.blocks(blockStorage),
+				txparser.NewInmemoryTransactionsStorage(),
+				test.subscriptions(subscriptionsStorage),
+				node.Client(),
+				txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+				txparser.WithClock(clock),
+				txparser.WithErrorHandler(func(err *txparser.WorkerError) {
+					errs <- err
+				}),
+			)
 
-func newClient() *client {
-	return &client{start: time.Now()}
-}
+			// Act
+			go func() {
+				_ = parser.RunWorker(ctx, time.Second)
+			}()
+			clock.BlockUntil(1)
+			err := <-errs
 
-func (c *client) CurrentBlockNumber(_ context.Context) (int, error) {
-	if time.Since(c.start) < 500*time.Millisecond {
-		return 1, nil
-	} else if time.Since(c.start) < 4*time.Second {
-		return 2, nil
+			// Assert
+			if !errors.Is(err, errStorageDown) {
+				t.Errorf("error should be %v, but is %v", errStorageDown, err)
+			}
+			if blockIDOf(t, ctx, blockStorage) != 0 {
+				t.Errorf("block id should be %d, but is %d", 0, blockIDOf(t, ctx, blockStorage))
+			}
+			if transactions := parser.GetTransactions("0x123"); len(transactions) != 0 {
+				t.Errorf("transactions slice should have %d item(s), but has %d", 0, len(transactions))
+			}
+		})
 	}
+}
 
-	return 4, nil
+// advance fires the timer of the worker waiting for its next pass and waits for the pass to finish.
+func advance(clock *txparsertest.FakeClock, d time.Duration) {
+	clock.Advance(d)
+	clock.BlockUntil(1)
 }
 
-func (c *client) GetBlockByNumber(_ context.Context, number int) (*txparser.Block, error) {
-	if number == 1 {
-		return &txparser.Block{
-			Number: "0x1",
-			Transactions: []txparser.Transaction{
-				{
-					BlockNumber: "0x1",
-					Hash:        "0xabc10",
-					From:        "0x123",
-					To:          "0x321",
-				},
-			},
-		}, nil
-	}
-
-	if number == 2 {
-		return &txparser.Block{
-			Number: "0x2",
-			Transactions: []txparser.Transaction{
-				{
-					BlockNumber: "0x2",
-					Hash:        "0xabc20",
-					From:        "0x123",
-					To:          "0x321",
-				},
-				{
-					BlockNumber: "0x2",
-					Hash:        "0xabc21",
-					From:        "0x321",
-					To:          "0x1337",
-				},
-			},
-		}, nil
-	}
-
-	if number == 3 {
-		return &txparser.Block{
-			Number: "0x3",
-			Transactions: []txparser.Transaction{
-				{
-					BlockNumber: "0x3",
-					Hash:        "0xabc30",
-					From:        "0x456",
-					To:          "0x123",
-				},
-				{
-					BlockNumber: "0x3",
-					Hash:        "0xabc31",
-					From:        "0x321",
-					To:          "0x1337",
-				},
-				{
-					BlockNumber: "0x3",
-					Hash:        "0xabc32",
-					From:        "0x123",
-					To:          "0x678",
-				},
-			},
-		}, nil
-	}
-
-	if number == 4 {
-		return &txparser.Block{
-			Number: "0x4",
-			Transactions: []txparser.Transaction{
-				{
-					BlockNumber: "0x4",
-					Hash:        "0xabc40",
-					From:        "0x789",
-					To:          "0x123",
-				},
-			},
-		}, nil
-	}
+// advanceUntilBlock advances the clock by period until the block is parsed. Unlike advance it does not
+// depend on the worker waiting for the next pass, such as after a change of the period: a timer reset
+// while the clock is advanced waits for the next advance.
+func advanceUntilBlock(
+	t *testing.T,
+	clock *txparsertest.FakeClock,
+	blockStorage txparser.BlockStorage,
+	block int,
+	period time.Duration,
+) {
+	t.Helper()
 
-	return nil, errors.New("invalid block")
+	for advances := 0; blockIDOf(t, context.Background(), blockStorage) != block; advances++ {
+		if advances == 3 {
+			t.Fatalf("block id should be %d, but is %d", block, blockIDOf(t, context.Background(), blockStorage))
+		}
+		clock.Advance(period)
+		clock.BlockUntil(1)
+	}
 }
 
 func areStructsEqual(a, b interface{}) bool {
@@ -238,3 +289,23 @@ func areSlicesEqual(a, b interface{}) bool {
 
 	return true
 }
+
+var errStorageDown = errors.New("storage is down")
+
+// unreadableBlockStorage fails to read the saved block.
+type unreadableBlockStorage struct {
+	txparser.BlockStorage
+}
+
+func (s unreadableBlockStorage) GetBlockID(_ context.Context) (int, error) {
+	return 0, errStorageDown
+}
+
+// uncheckableSubscriptionsStorage fails to check subscriptions.
+type uncheckableSubscriptionsStorage struct {
+	txparser.SubscriptionsStorage
+}
+
+func (s uncheckableSubscriptionsStorage) IsAddressExists(_ context.Context, _ string) (bool, error) {
+	return false, errStorageDown
+}
@@ -0,0 +1,123 @@
+// Package txparsertest provides fakes for tests of code using the parser.
+package txparsertest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"txparser"
+)
+
+// FakeClock is a txparser.Clock whose time moves only by Advance. It is safe for concurrent use.
+type FakeClock struct {
+	mu sync.Mutex
+	// changed is signaled when timers start or stop waiting
+	changed *sync.Cond
+	now     time.Time
+	waiting []*fakeTimer
+}
+
+// NewFakeClock returns a clock stopped at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.changed = sync.NewCond(&c.mu)
+
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTimer returns a timer firing when the clock is advanced by d, right away if d is not positive.
+func (c *FakeClock) NewTimer(d time.Duration) txparser.Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+
+	return t
+}
+
+// Advance moves the time forward by d firing due timers in order of their deadlines.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	sort.SliceStable(c.waiting, func(i, j int) bool {
+		return c.waiting[i].deadline.Before(c.waiting[j].deadline)
+	})
+	for len(c.waiting) > 0 && !c.waiting[0].deadline.After(c.now) {
+		c.fire(c.waiting[0])
+	}
+}
+
+// BlockUntil blocks until exactly n timers are waiting, such as a worker waiting for its next pass.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiting) != n {
+		c.changed.Wait()
+	}
+}
+
+// fire sends the deadline to the timer and stops it, the lock is held.
+func (c *FakeClock) fire(t *fakeTimer) {
+	c.remove(t)
+	select {
+	case t.c <- t.deadline:
+	default:
+	}
+}
+
+// remove stops the timer reporting whether it was waiting, the lock is held.
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	for i, waiting := range c.waiting {
+		if waiting == t {
+			c.waiting = append(c.waiting[:i], c.waiting[i+1:]...)
+			c.changed.Broadcast()
+			return true
+		}
+	}
+
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	return t.clock.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wasWaiting := c.remove(t)
+	t.deadline = c.now.Add(d)
+	if d <= 0 {
+		c.fire(t)
+		return wasWaiting
+	}
+	c.waiting = append(c.waiting, t)
+	c.changed.Broadcast()
+
+	return wasWaiting
+}
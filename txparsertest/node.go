@@ -0,0 +1,183 @@
+package txparsertest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+
+	"txparser"
+)
+
+// Tx is a transaction sent to the Node. A Hash is generated if it is empty and Value is "0x0" if it is empty.
+type Tx struct {
+	Hash  string
+	From  string
+	To    string
+	Value string
+	Logs  []Log
+}
+
+// Log is an event emitted by a transaction.
+type Log struct {
+	Address string
+	Topics  []string
+	Data    string
+}
+
+// Node is a simulated Ethereum node serving a programmable chain over JSON-RPC: eth_blockNumber,
+// eth_getBlockByNumber, eth_getBlockReceipts and eth_getLogs. The chain starts with the empty block 0
+// and grows by Mine, Reorg replaces its last blocks. It is safe for concurrent use.
+type Node struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	blocks  []nodeBlock
+	pending []Tx
+	// sent counts sent transactions to generate unique hashes
+	sent int
+	// forks counts reorgs so that replaced blocks get other hashes
+	forks int
+}
+
+type nodeBlock struct {
+	number     int
+	hash       string
+	parentHash string
+	txs        []Tx
+}
+
+// NewNode starts a node, it should be closed by Close.
+func NewNode() *Node {
+	n := &Node{}
+	n.blocks = []nodeBlock{n.newBlock(0, "0x"+strings.Repeat("0", 64), nil)}
+	n.server = httptest.NewServer(http.HandlerFunc(n.handle))
+
+	return n
+}
+
+// URL returns the JSON-RPC endpoint of the node.
+func (n *Node) URL() string {
+	return n.server.URL
+}
+
+// Client returns a client of the node.
+func (n *Node) Client(opts ...txparser.ClientOption) *txparser.JSONRPCClient {
+	return txparser.NewJSONRPCClient(n.server.Client(), n.server.URL, opts...)
+}
+
+func (n *Node) Close() {
+	n.server.Close()
+}
+
+// Send adds the transactions to the next mined block and returns their hashes.
+func (n *Node) Send(txs ...Tx) []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	hashes := make([]string, 0, len(txs))
+	for _, tx := range txs {
+		n.sent++
+		if tx.Hash == "" {
+			tx.Hash = hashOf("tx", strconv.Itoa(n.sent), tx.From, tx.To)
+		}
+		if tx.Value == "" {
+			tx.Value = "0x0"
+		}
+		n.pending = append(n.pending, tx)
+		hashes = append(hashes, tx.Hash)
+	}
+
+	return hashes
+}
+
+// Mine appends a block with the sent transactions and returns its number.
+func (n *Node) Mine() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.mine()
+
+	return len(n.blocks) - 1
+}
+
+// Reorg replaces the last depth blocks with empty blocks of other hashes, the head stays the same.
+// Transactions of the replaced blocks are sent again and go to the next mined block.
+// It panics if depth reaches the block 0.
+func (n *Node) Reorg(depth int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if depth <= 0 || depth >= len(n.blocks) {
+		panic(fmt.Sprintf("txparsertest: reorg depth %d should be from 1 to the head %d", depth, len(n.blocks)-1))
+	}
+
+	fork := len(n.blocks) - depth
+	var dropped []Tx
+	for _, block := range n.blocks[fork:] {
+		dropped = append(dropped, block.txs...)
+	}
+	n.blocks = n.blocks[:fork]
+	n.forks++
+
+	pending := n.pending
+	n.pending = nil
+	for i := 0; i < depth; i++ {
+		n.mine()
+	}
+	n.pending = append(dropped, pending...)
+}
+
+// Head returns the number of the last block.
+func (n *Node) Head() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return len(n.blocks) - 1
+}
+
+// BlockHash returns the hash of the block, it is empty for blocks after the head.
+func (n *Node) BlockHash(number int) string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if number < 0 || number >= len(n.blocks) {
+		return ""
+	}
+
+	return n.blocks[number].hash
+}
+
+// mine appends a block with the pending transactions, the lock is held.
+func (n *Node) mine() {
+	parent := n.blocks[len(n.blocks)-1]
+	n.blocks = append(n.blocks, n.newBlock(parent.number+1, parent.hash, n.pending))
+	n.pending = nil
+}
+
+func (n *Node) newBlock(number int, parentHash string, txs []Tx) nodeBlock {
+	parts := []string{"block", strconv.Itoa(number), strconv.Itoa(n.forks), parentHash}
+	for _, tx := range txs {
+		parts = append(parts, tx.Hash)
+	}
+
+	return nodeBlock{
+		number:     number,
+		hash:       hashOf(parts...),
+		parentHash: parentHash,
+		txs:        txs,
+	}
+}
+
+func hashOf(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "/")))
+	return "0x" + hex.EncodeToString(sum[:])
+}
+
+func toHex(n int) string {
+	return "0x" + strconv.FormatInt(int64(n), 16)
+}
@@ -0,0 +1,204 @@
+package txparsertest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"txparser/txparsertest"
+)
+
+func Test_Node_Reorg(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	node := txparsertest.NewNode()
+	defer node.Close()
+	client := node.Client()
+	node.Send(txparsertest.Tx{From: "0x123", To: "0x321"})
+	node.Mine()
+	hashes := node.Send(txparsertest.Tx{From: "0x456", To: "0x123"})
+	node.Mine()
+	node.Mine()
+	replaced := node.BlockHash(2)
+
+	// Act
+	node.Reorg(2)
+	head, err := client.CurrentBlockNumber(ctx)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	reorged, err := client.GetBlockByNumber(ctx, 2)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	node.Mine()
+	remined, err := client.GetBlockByNumber(ctx, 4)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	kept, err := client.GetBlockByNumber(ctx, 1)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// Assert
+	if head != 3 {
+		t.Errorf("head should be %d, but is %d", 3, head)
+	}
+	if reorged.Hash == replaced || len(reorged.Transactions) != 0 {
+		t.Errorf("block should be replaced with an empty block, but is %+v", reorged)
+	}
+	if len(remined.Transactions) != 1 || remined.Transactions[0].Hash != hashes[0] {
+		t.Errorf("transactions of replaced blocks should be mined again, but are %+v", remined.Transactions)
+	}
+	if len(kept.Transactions) != 1 || kept.Transactions[0].BlockHash != node.BlockHash(1) {
+		t.Errorf("blocks before the reorg should be kept, but is %+v", kept)
+	}
+}
+
+func Test_Node_GetBlockReceipts(t *testing.T) {
+	// Arrange
+	node := txparsertest.NewNode()
+	defer node.Close()
+	hashes := node.Send(
+		txparsertest.Tx{From: "0x123", To: "0x321"},
+		txparsertest.Tx{From: "0x123", To: "0xc0de", Logs: []txparsertest.Log{
+			{Address: "0xc0de", Topics: []string{"0xaa"}, Data: "0x01"},
+			{Address: "0xc0de", Topics: []string{"0xbb"}},
+		}},
+	)
+	node.Mine()
+
+	// Act
+	var receipts []struct {
+		TransactionHash string `json:"transactionHash"`
+		Status          string `json:"status"`
+		Logs            []struct {
+			LogIndex string `json:"logIndex"`
+		} `json:"logs"`
+	}
+	call(t, node, "eth_getBlockReceipts", []any{"0x1"}, &receipts)
+
+	// Assert
+	if len(receipts) != 2 {
+		t.Errorf("receipts slice should have %d item(s), but has %d", 2, len(receipts))
+		t.FailNow()
+	}
+	if receipts[1].TransactionHash != hashes[1] || receipts[1].Status != "0x1" {
+		t.Errorf("receipt should be of %s, but is %+v", hashes[1], receipts[1])
+	}
+	if len(receipts[0].Logs) != 0 || len(receipts[1].Logs) != 2 || receipts[1].Logs[1].LogIndex != "0x1" {
+		t.Errorf("logs should be in the receipt of their transaction, but are %+v", receipts)
+	}
+}
+
+func Test_Node_GetLogs(t *testing.T) {
+	node := txparsertest.NewNode()
+	defer node.Close()
+	node.Send(txparsertest.Tx{From: "0x123", To: "0xc0de", Logs: []txparsertest.Log{
+		{Address: "0xc0de", Topics: []string{"0xaa", "0x01"}},
+		{Address: "0xbeef", Topics: []string{"0xaa", "0x02"}},
+	}})
+	node.Mine()
+	node.Send(txparsertest.Tx{From: "0x123", To: "0xc0de", Logs: []txparsertest.Log{
+		{Address: "0xC0DE", Topics: []string{"0xbb"}},
+	}})
+	node.Mine()
+
+	tests := []struct {
+		name   string
+		filter map[string]any
+		want   []string
+	}{
+		{
+			name:   "latest by default",
+			filter: map[string]any{},
+			want:   []string{"0xC0DE/0xbb"},
+		},
+		{
+			name:   "range",
+			filter: map[string]any{"fromBlock": "0x1", "toBlock": "latest"},
+			want:   []string{"0xc0de/0xaa", "0xbeef/0xaa", "0xC0DE/0xbb"},
+		},
+		{
+			name:   "address",
+			filter: map[string]any{"fromBlock": "earliest", "address": "0xc0de"},
+			want:   []string{"0xc0de/0xaa", "0xC0DE/0xbb"},
+		},
+		{
+			name:   "topics",
+			filter: map[string]any{"fromBlock": "0x0", "topics": []any{"0xaa", []string{"0x02", "0x03"}}},
+			want:   []string{"0xbeef/0xaa"},
+		},
+		{
+			name:   "wildcard topic",
+			filter: map[string]any{"fromBlock": "0x0", "topics": []any{nil, "0x01"}},
+			want:   []string{"0xc0de/0xaa"},
+		},
+		{
+			name:   "block hash",
+			filter: map[string]any{"blockHash": node.BlockHash(1), "address": []string{"0xbeef"}},
+			want:   []string{"0xbeef/0xaa"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act
+			var logs []struct {
+				Address string   `json:"address"`
+				Topics  []string `json:"topics"`
+			}
+			call(t, node, "eth_getLogs", []any{test.filter}, &logs)
+
+			// Assert
+			got := []string{}
+			for _, log := range logs {
+				got = append(got, log.Address+"/"+log.Topics[0])
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("logs should be %v, but are %v", test.want, got)
+			}
+		})
+	}
+}
+
+// call makes a JSON-RPC call to the node and decodes its result.
+func call(t *testing.T, node *txparsertest.Node, method string, params []any, result any) {
+	t.Helper()
+
+	payload, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "method": method, "params": params, "id": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.Post(node.URL(), "application/json", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var response struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response.Error != nil {
+		t.Fatalf("call should succeed, but failed: %s", response.Error.Message)
+	}
+	err = json.Unmarshal(response.Result, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
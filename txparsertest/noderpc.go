@@ -0,0 +1,417 @@
+package txparsertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"txparser"
+)
+
+type nodeCall struct {
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+	ID     json.RawMessage   `json:"id"`
+}
+
+type nodeMethod func(params []json.RawMessage) (any, *txparser.JSONRPCError)
+
+type rpcBlock struct {
+	Number       string `json:"number"`
+	Hash         string `json:"hash"`
+	ParentHash   string `json:"parentHash"`
+	Timestamp    string `json:"timestamp"`
+	Transactions any    `json:"transactions"`
+}
+
+type rpcTransaction struct {
+	BlockNumber      string `json:"blockNumber"`
+	BlockHash        string `json:"blockHash"`
+	Hash             string `json:"hash"`
+	TransactionIndex string `json:"transactionIndex"`
+	From             string `json:"from"`
+	To               string `json:"to"`
+	Value            string `json:"value"`
+	Input            string `json:"input"`
+}
+
+type rpcReceipt struct {
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	BlockHash        string   `json:"blockHash"`
+	BlockNumber      string   `json:"blockNumber"`
+	From             string   `json:"from"`
+	To               string   `json:"to"`
+	Status           string   `json:"status"`
+	Logs             []rpcLog `json:"logs"`
+}
+
+type rpcLog struct {
+	Address          string   `json:"address"`
+	Topics           []string `json:"topics"`
+	Data             string   `json:"data"`
+	BlockNumber      string   `json:"blockNumber"`
+	BlockHash        string   `json:"blockHash"`
+	TransactionHash  string   `json:"transactionHash"`
+	TransactionIndex string   `json:"transactionIndex"`
+	LogIndex         string   `json:"logIndex"`
+	Removed          bool     `json:"removed"`
+}
+
+// logFilter is the parameter of eth_getLogs. Address is a string or a list of them, topics are
+// matched by position: null matches any topic, a list matches any of its topics.
+type logFilter struct {
+	FromBlock string            `json:"fromBlock"`
+	ToBlock   string            `json:"toBlock"`
+	BlockHash string            `json:"blockHash"`
+	Address   json.RawMessage   `json:"address"`
+	Topics    []json.RawMessage `json:"topics"`
+}
+
+func (n *Node) methods() map[string]nodeMethod {
+	return map[string]nodeMethod{
+		"eth_blockNumber":      n.rpcBlockNumber,
+		"eth_getBlockByNumber": n.rpcGetBlockByNumber,
+		"eth_getBlockReceipts": n.rpcGetBlockReceipts,
+		"eth_getLogs":          n.rpcGetLogs,
+	}
+}
+
+func (n *Node) handle(w http.ResponseWriter, r *http.Request) {
+	var call nodeCall
+	err := json.NewDecoder(r.Body).Decode(&call)
+	if err != nil {
+		writeResponse(w, txparser.JSONRPCResponse{
+			Jsonrpc: "2.0",
+			Error:   &txparser.JSONRPCError{Code: txparser.JSONRPCParseError, Message: "parse error: " + err.Error()},
+		})
+		return
+	}
+
+	method, ok := n.methods()[call.Method]
+	if !ok {
+		writeResponse(w, txparser.JSONRPCResponse{
+			Jsonrpc: "2.0",
+			Error:   &txparser.JSONRPCError{Code: txparser.JSONRPCMethodNotFound, Message: "method not found: " + call.Method},
+			ID:      call.ID,
+		})
+		return
+	}
+
+	n.mu.Lock()
+	result, rpcErr := method(call.Params)
+	n.mu.Unlock()
+
+	response := txparser.JSONRPCResponse{Jsonrpc: "2.0", ID: call.ID}
+	if rpcErr != nil {
+		response.Error = rpcErr
+	} else {
+		response.Result = result
+	}
+	writeResponse(w, response)
+}
+
+func writeResponse(w http.ResponseWriter, response txparser.JSONRPCResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (n *Node) rpcBlockNumber(_ []json.RawMessage) (any, *txparser.JSONRPCError) {
+	return toHex(len(n.blocks) - 1), nil
+}
+
+func (n *Node) rpcGetBlockByNumber(params []json.RawMessage) (any, *txparser.JSONRPCError) {
+	var ref string
+	rpcErr := param(params, 0, &ref)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	full := false
+	if len(params) > 1 {
+		rpcErr = param(params, 1, &full)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+	}
+
+	block, ok, rpcErr := n.blockAt(ref)
+	if rpcErr != nil || !ok {
+		return nil, rpcErr
+	}
+
+	result := rpcBlock{
+		Number:     toHex(block.number),
+		Hash:       block.hash,
+		ParentHash: block.parentHash,
+		Timestamp:  toHex(block.number * 12),
+	}
+	if full {
+		txs := make([]rpcTransaction, 0, len(block.txs))
+		for i, tx := range block.txs {
+			txs = append(txs, rpcTransaction{
+				BlockNumber:      toHex(block.number),
+				BlockHash:        block.hash,
+				Hash:             tx.Hash,
+				TransactionIndex: toHex(i),
+				From:             tx.From,
+				To:               tx.To,
+				Value:            tx.Value,
+				Input:            "0x",
+			})
+		}
+		result.Transactions = txs
+	} else {
+		hashes := make([]string, 0, len(block.txs))
+		for _, tx := range block.txs {
+			hashes = append(hashes, tx.Hash)
+		}
+		result.Transactions = hashes
+	}
+
+	return result, nil
+}
+
+func (n *Node) rpcGetBlockReceipts(params []json.RawMessage) (any, *txparser.JSONRPCError) {
+	var ref string
+	rpcErr := param(params, 0, &ref)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	block, ok, rpcErr := n.blockAt(ref)
+	if rpcErr != nil || !ok {
+		return nil, rpcErr
+	}
+
+	logs := logsOf(block)
+	receipts := make([]rpcReceipt, 0, len(block.txs))
+	for i, tx := range block.txs {
+		receipt := rpcReceipt{
+			TransactionHash:  tx.Hash,
+			TransactionIndex: toHex(i),
+			BlockHash:        block.hash,
+			BlockNumber:      toHex(block.number),
+			From:             tx.From,
+			To:               tx.To,
+			Status:           "0x1",
+			Logs:             []rpcLog{},
+		}
+		for _, log := range logs {
+			if log.TransactionHash == tx.Hash {
+				receipt.Logs = append(receipt.Logs, log)
+			}
+		}
+		receipts = append(receipts, receipt)
+	}
+
+	return receipts, nil
+}
+
+func (n *Node) rpcGetLogs(params []json.RawMessage) (any, *txparser.JSONRPCError) {
+	var filter logFilter
+	rpcErr := param(params, 0, &filter)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+	addresses, topics, rpcErr := parseLogFilter(filter)
+	if rpcErr != nil {
+		return nil, rpcErr
+	}
+
+	var blocks []nodeBlock
+	if filter.BlockHash != "" {
+		if filter.FromBlock != "" || filter.ToBlock != "" {
+			return nil, invalidParams("blockHash can not be combined with fromBlock and toBlock")
+		}
+		block, ok, rpcErr := n.blockAt(filter.BlockHash)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		if !ok {
+			return nil, &txparser.JSONRPCError{Code: -32000, Message: "unknown block"}
+		}
+		blocks = []nodeBlock{block}
+	} else {
+		from, rpcErr := n.numberOf(filter.FromBlock)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		to, rpcErr := n.numberOf(filter.ToBlock)
+		if rpcErr != nil {
+			return nil, rpcErr
+		}
+		if from > to {
+			return nil, invalidParams("invalid block range")
+		}
+		if to > len(n.blocks)-1 {
+			to = len(n.blocks) - 1
+		}
+		if from <= to {
+			blocks = n.blocks[from : to+1]
+		}
+	}
+
+	logs := []rpcLog{}
+	for _, block := range blocks {
+		for _, log := range logsOf(block) {
+			if matchesLog(log, addresses, topics) {
+				logs = append(logs, log)
+			}
+		}
+	}
+
+	return logs, nil
+}
+
+// blockAt returns the block of a tag, a number or a hash, the lock is held.
+func (n *Node) blockAt(ref string) (nodeBlock, bool, *txparser.JSONRPCError) {
+	if len(ref) == 66 {
+		for _, block := range n.blocks {
+			if strings.EqualFold(block.hash, ref) {
+				return block, true, nil
+			}
+		}
+		return nodeBlock{}, false, nil
+	}
+
+	number, rpcErr := n.numberOf(ref)
+	if rpcErr != nil {
+		return nodeBlock{}, false, rpcErr
+	}
+	if number >= len(n.blocks) {
+		return nodeBlock{}, false, nil
+	}
+
+	return n.blocks[number], true, nil
+}
+
+// numberOf returns the number of a block tag or a hex number, an empty tag is "latest". The lock is held.
+func (n *Node) numberOf(ref string) (int, *txparser.JSONRPCError) {
+	switch ref {
+	case "", "latest", "pending", "safe", "finalized":
+		return len(n.blocks) - 1, nil
+	case "earliest":
+		return 0, nil
+	}
+
+	if !strings.HasPrefix(ref, "0x") {
+		return 0, invalidParams("invalid block number " + strconv.Quote(ref))
+	}
+	number, err := strconv.ParseInt(ref[2:], 16, 64)
+	if err != nil || number < 0 {
+		return 0, invalidParams("invalid block number " + strconv.Quote(ref))
+	}
+
+	return int(number), nil
+}
+
+func logsOf(block nodeBlock) []rpcLog {
+	var logs []rpcLog
+	for i, tx := range block.txs {
+		for _, log := range tx.Logs {
+			topics := log.Topics
+			if topics == nil {
+				topics = []string{}
+			}
+			data := log.Data
+			if data == "" {
+				data = "0x"
+			}
+			logs = append(logs, rpcLog{
+				Address:          log.Address,
+				Topics:           topics,
+				Data:             data,
+				BlockNumber:      toHex(block.number),
+				BlockHash:        block.hash,
+				TransactionHash:  tx.Hash,
+				TransactionIndex: toHex(i),
+				LogIndex:         toHex(len(logs)),
+			})
+		}
+	}
+
+	return logs
+}
+
+// parseLogFilter returns the addresses and the topics of the filter, nil matches anything.
+func parseLogFilter(filter logFilter) ([]string, [][]string, *txparser.JSONRPCError) {
+	addresses, err := oneOrMany(filter.Address)
+	if err != nil {
+		return nil, nil, invalidParams("invalid address: " + err.Error())
+	}
+
+	topics := make([][]string, 0, len(filter.Topics))
+	for i, raw := range filter.Topics {
+		alternatives, err := oneOrMany(raw)
+		if err != nil {
+			return nil, nil, invalidParams(fmt.Sprintf("invalid topic %d: %v", i, err))
+		}
+		topics = append(topics, alternatives)
+	}
+
+	return addresses, topics, nil
+}
+
+// oneOrMany decodes a string or a list of strings, null is nil.
+func oneOrMany(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var one string
+	if json.Unmarshal(raw, &one) == nil {
+		return []string{one}, nil
+	}
+	var many []string
+	err := json.Unmarshal(raw, &many)
+	if err != nil {
+		return nil, err
+	}
+
+	return many, nil
+}
+
+func matchesLog(log rpcLog, addresses []string, topics [][]string) bool {
+	if addresses != nil && !containsFold(addresses, log.Address) {
+		return false
+	}
+	for i, alternatives := range topics {
+		if alternatives == nil {
+			continue
+		}
+		if i >= len(log.Topics) || !containsFold(alternatives, log.Topics[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func param(params []json.RawMessage, i int, v any) *txparser.JSONRPCError {
+	if i >= len(params) {
+		return invalidParams(fmt.Sprintf("missing parameter %d", i))
+	}
+
+	err := json.Unmarshal(params[i], v)
+	if err != nil {
+		return invalidParams(fmt.Sprintf("invalid parameter %d: %v", i, err))
+	}
+
+	return nil
+}
+
+func invalidParams(message string) *txparser.JSONRPCError {
+	return &txparser.JSONRPCError{Code: txparser.JSONRPCInvalidParams, Message: message}
+}
@@ -0,0 +1,372 @@
+package txparser
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	walHeaderSize = 8 // record length and checksum
+	walSeqSize    = 8
+
+	defaultCompactionThreshold = 10000
+)
+
+// ErrCorruptedWAL is returned by file storages whose log has a damaged record followed by
+// more data. Unlike a torn record at the end such a log is not truncated, it needs a repair.
+var ErrCorruptedWAL = errors.New("corrupted wal record")
+
+var (
+	errWALTornRecord = errors.New("torn wal record")
+
+	walChecksumTable = crc32.MakeTable(crc32.Castagnoli)
+)
+
+// walLog is an append-only write-ahead log with a snapshot it is compacted into.
+//
+// Every record is framed as
+//
+//	length uint32 | crc32c uint32 | seq uint64 | payload
+//
+// where length and checksum cover seq and payload. Records with seq
+// less than or equal to the snapshot seq are already part of the snapshot.
+type walLog struct {
+	mu sync.Mutex
+
+	dir  string
+	name string
+
+	file *os.File
+
+	seq                 uint64
+	records             int
+	size                int64
+	compactionThreshold int
+
+	logger *slog.Logger
+}
+
+// openWAL opens the log and the snapshot named name in dir, creating them if needed.
+// The snapshot payload is passed to restore and every record appended after it to replay.
+// A torn record at the tail of the log, left by a crash in the middle of a write, is truncated.
+// A damaged record followed by more data fails with ErrCorruptedWAL and the log is kept as is.
+func openWAL(
+	dir, name string,
+	o fileStorageOptions,
+	restore func(payload []byte) error,
+	replay func(payload []byte) error,
+) (*walLog, error) {
+	err := os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &walLog{
+		dir:                 dir,
+		name:                name,
+		compactionThreshold: o.compactionThreshold,
+		logger:              o.logger,
+	}
+
+	err = l.loadSnapshot(restore)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:gosec
+	l.file, err = os.OpenFile(l.logPath(), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	err = l.replay(replay)
+	if err != nil {
+		_ = l.file.Close()
+		return nil, err
+	}
+
+	err = syncDir(dir)
+	if err != nil {
+		_ = l.file.Close()
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Append durably writes payload as a single record.
+func (l *walLog) Append(payload []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return os.ErrClosed
+	}
+
+	record := encodeWALRecord(l.seq+1, payload)
+
+	_, err := l.file.Write(record)
+	if err == nil {
+		err = l.file.Sync()
+	}
+	if err != nil {
+		// Drop a partially written record, otherwise recovery
+		// would stop at it and lose every record appended later.
+		return errors.Join(err, l.rewind())
+	}
+
+	l.seq++
+	l.records++
+	l.size += int64(len(record))
+
+	return nil
+}
+
+func (l *walLog) rewind() error {
+	err := l.file.Truncate(l.size)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.file.Seek(l.size, io.SeekStart)
+
+	return err
+}
+
+// NeedsCompaction reports whether the log has grown enough to be compacted.
+func (l *walLog) NeedsCompaction() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.compactionThreshold > 0 && l.records >= l.compactionThreshold
+}
+
+// Compact atomically replaces the snapshot with payload and truncates the log.
+// The payload must reflect every record appended so far.
+func (l *walLog) Compact(payload []byte) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return os.ErrClosed
+	}
+
+	tmpPath := l.snapshotPath() + ".tmp"
+
+	err := writeFileSync(tmpPath, encodeWALRecord(l.seq, payload))
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, l.snapshotPath())
+	if err != nil {
+		return err
+	}
+
+	err = syncDir(l.dir)
+	if err != nil {
+		return err
+	}
+
+	// Records left in the log after a crash at this point
+	// are already in the snapshot and are skipped on recovery by their seq.
+	err = l.file.Truncate(0)
+	if err != nil {
+		return err
+	}
+
+	_, err = l.file.Seek(0, io.SeekStart)
+	if err != nil {
+		return err
+	}
+
+	err = l.file.Sync()
+	if err != nil {
+		return err
+	}
+
+	l.records = 0
+	l.size = 0
+
+	return nil
+}
+
+func (l *walLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+
+	return err
+}
+
+func (l *walLog) logPath() string {
+	return filepath.Join(l.dir, l.name+".wal")
+}
+
+func (l *walLog) snapshotPath() string {
+	return filepath.Join(l.dir, l.name+".snapshot")
+}
+
+func (l *walLog) loadSnapshot(restore func(payload []byte) error) error {
+	data, err := os.ReadFile(l.snapshotPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// Snapshots are written to a temporary file and renamed,
+	// so unlike the log a broken snapshot is never expected.
+	seq, payload, _, err := decodeWALRecord(data)
+	if err != nil {
+		return fmt.Errorf("invalid snapshot %s: %w", l.snapshotPath(), err)
+	}
+
+	l.seq = seq
+
+	return restore(payload)
+}
+
+func (l *walLog) replay(replay func(payload []byte) error) error {
+	data, err := io.ReadAll(bufio.NewReader(l.file))
+	if err != nil {
+		return err
+	}
+
+	offset := 0
+	for offset < len(data) {
+		seq, payload, n, err := decodeWALRecord(data[offset:])
+		if errors.Is(err, errWALTornRecord) {
+			break
+		}
+		// Only the last record may be left damaged by a crash
+		if errors.Is(err, ErrCorruptedWAL) && offset+n == len(data) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("wal %s at offset %d: %w", l.name, offset, err)
+		}
+
+		if seq > l.seq {
+			err = replay(payload)
+			if err != nil {
+				return fmt.Errorf("failed to replay wal record %d: %w", seq, err)
+			}
+
+			l.seq = seq
+		}
+
+		offset += n
+		l.records++
+	}
+
+	if offset < len(data) {
+		l.logger.Warn("truncate torn wal record", "log", l.name, "offset", offset, "dropped_bytes", len(data)-offset)
+		err = l.file.Truncate(int64(offset))
+		if err != nil {
+			return err
+		}
+
+		err = l.file.Sync()
+		if err != nil {
+			return err
+		}
+	}
+
+	l.size = int64(offset)
+
+	_, err = l.file.Seek(l.size, io.SeekStart)
+
+	return err
+}
+
+func encodeWALRecord(seq uint64, payload []byte) []byte {
+	record := make([]byte, walHeaderSize+walSeqSize+len(payload))
+
+	body := record[walHeaderSize:]
+	binary.BigEndian.PutUint64(body, seq)
+	copy(body[walSeqSize:], payload)
+
+	binary.BigEndian.PutUint32(record, uint32(len(body)))
+	binary.BigEndian.PutUint32(record[4:], crc32.Checksum(body, walChecksumTable))
+
+	return record
+}
+
+// decodeWALRecord decodes the record at the beginning of data and returns its size.
+// A record cut short by the end of data is torn, a complete one failing the checksum
+// is corrupted, its size is returned as well.
+func decodeWALRecord(data []byte) (seq uint64, payload []byte, size int, err error) {
+	if len(data) < walHeaderSize {
+		return 0, nil, 0, errWALTornRecord
+	}
+
+	length := int(binary.BigEndian.Uint32(data))
+	checksum := binary.BigEndian.Uint32(data[4:])
+
+	if length > len(data)-walHeaderSize {
+		return 0, nil, 0, errWALTornRecord
+	}
+	if length < walSeqSize {
+		return 0, nil, walHeaderSize + length, ErrCorruptedWAL
+	}
+
+	body := data[walHeaderSize : walHeaderSize+length]
+	if crc32.Checksum(body, walChecksumTable) != checksum {
+		return 0, nil, walHeaderSize + length, ErrCorruptedWAL
+	}
+
+	return binary.BigEndian.Uint64(body), body[walSeqSize:], walHeaderSize + length, nil
+}
+
+func writeFileSync(path string, data []byte) error {
+	//nolint:gosec
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	err = f.Sync()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+
+	err = d.Sync()
+	if err != nil {
+		_ = d.Close()
+		return err
+	}
+
+	return d.Close()
+}
@@ -2,36 +2,332 @@ package txparser
 
 import (
 	"context"
-	"log"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 )
 
+// defaultMaxBackoff caps the delay between passes of a failing worker.
+const defaultMaxBackoff = time.Minute
+
+// WorkerError is an error of a failed pass of the worker or a failed attempt to start it.
+type WorkerError struct {
+	Err error
+
+	// Attempt counts consecutive failures from 1.
+	Attempt int
+
+	// RetryIn is the delay before the next attempt.
+	RetryIn time.Duration
+}
+
+func (e *WorkerError) Error() string {
+	return fmt.Sprintf("attempt %d failed, retrying in %s: %v", e.Attempt, e.RetryIn, e.Err)
+}
+
+func (e *WorkerError) Unwrap() error {
+	return e.Err
+}
+
+// permanentError stops retries of the worker startup.
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+// worker runs fn every period, doubling the delay after every consecutive failure up to maxBackoff.
+// Pause, Resume, SetPeriod and Stop may be called from other goroutines.
 type worker struct {
-	fn func(context.Context) error
+	fn         func(context.Context) error
+	onError    func(context.Context, *WorkerError)
+	maxBackoff time.Duration
+	clock      Clock
+
+	// wake interrupts waiting for the next pass after Pause, Resume and SetPeriod
+	wake chan struct{}
+
+	mu       sync.Mutex
+	period   time.Duration
+	running  bool
+	paused   bool
+	runNow   bool
+	stopped  bool
+	finished bool
+	stop     chan struct{}
+	done     chan struct{}
 }
 
-func newWorker(fn func(context.Context) error) *worker {
-	return &worker{fn: fn}
+func newWorker(fn func(context.Context) error, onError func(context.Context, *WorkerError)) *worker {
+	return &worker{
+		fn:         fn,
+		onError:    onError,
+		maxBackoff: defaultMaxBackoff,
+		clock:      SystemClock(),
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
 }
 
-func (w *worker) Run(ctx context.Context, period time.Duration) {
-	ticker := time.NewTicker(period)
+// Run runs start until it succeeds, then fn every period until ctx is done or Stop is called.
+// It returns the last error of start if it gave up: start failed permanently, ctx is done or Stop is called.
+// A nil start is skipped.
+func (w *worker) Run(ctx context.Context, period time.Duration, start func(context.Context) error) error {
+	stop, done := w.begin(period)
+	defer w.end(done)
+
+	// Stop called before Run or during start ends the run before touching the node and storages
+	if isClosed(stop) {
+		return nil
+	}
+	if start != nil {
+		err := w.retry(ctx, stop, start)
+		if err != nil {
+			return err
+		}
+	}
+
+	failures := 0
+	if isClosed(stop) {
+		return nil
+	}
+	if !w.Paused() {
+		failures = w.pass(ctx, failures)
+	}
 
-	w.tick(ctx)
+	timer := w.clock.NewTimer(w.delay(failures))
+	defer timer.Stop()
 
 	for {
 		select {
 		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			w.tick(ctx)
+			return nil
+		case <-stop:
+			return nil
+		case <-w.wake:
+			paused, runNow := w.takeWake()
+			fired := stopTimer(timer)
+			switch {
+			case paused:
+			case runNow || fired:
+				// A pass due before the wake is not skipped
+				timer.Reset(0)
+			default:
+				timer.Reset(w.delay(failures))
+			}
+			continue
+		case <-timer.C():
+		}
+
+		if w.Paused() {
+			continue
+		}
+		failures = w.pass(ctx, failures)
+		timer.Reset(w.delay(failures))
+	}
+}
+
+// retry runs start until it succeeds, backing off like passes.
+func (w *worker) retry(ctx context.Context, stop <-chan struct{}, start func(context.Context) error) error {
+	for attempt := 1; ; attempt++ {
+		err := start(ctx)
+		var permanent permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+
+		w.report(ctx, err, attempt)
+
+		timer := w.clock.NewTimer(w.delay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-stop:
+			timer.Stop()
+			return err
+		case <-timer.C():
 		}
 	}
 }
 
-func (w *worker) tick(ctx context.Context) {
+// pass runs fn and returns the number of consecutive failures.
+func (w *worker) pass(ctx context.Context, failures int) int {
 	err := w.fn(ctx)
-	if err != nil {
-		log.Print(err)
+	if err == nil {
+		return 0
+	}
+	// Passes interrupted by the shutdown are not failures
+	if ctx.Err() != nil {
+		return failures
+	}
+
+	failures++
+	w.report(ctx, err, failures)
+
+	return failures
+}
+
+func (w *worker) report(ctx context.Context, err error, attempt int) {
+	if w.onError != nil {
+		w.onError(ctx, &WorkerError{Err: err, Attempt: attempt, RetryIn: w.delay(attempt)})
+	}
+}
+
+// delay returns the delay before the next pass after the consecutive failures.
+func (w *worker) delay(failures int) time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delay := w.period
+	for i := 0; i < failures; i++ {
+		delay *= 2
+		if delay >= w.maxBackoff {
+			return max(w.maxBackoff, w.period)
+		}
+	}
+
+	return delay
+}
+
+// begin starts a run, channels of a finished run are replaced.
+func (w *worker) begin(period time.Duration) (stop, done chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.finished {
+		w.stop = make(chan struct{})
+		w.done = make(chan struct{})
+		w.stopped = false
+		w.finished = false
+	}
+	w.period = period
+	w.running = true
+
+	return w.stop, w.done
+}
+
+func (w *worker) end(done chan struct{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.running = false
+	w.finished = true
+	close(done)
+}
+
+// SetPeriod changes the period of the running worker from the next pass on,
+// it reports false if the worker is not running.
+func (w *worker) SetPeriod(period time.Duration) bool {
+	w.mu.Lock()
+	if !w.running {
+		w.mu.Unlock()
+		return false
+	}
+	w.period = period
+	w.mu.Unlock()
+
+	w.signal()
+
+	return true
+}
+
+// Pause skips passes until Resume, the worker keeps running.
+func (w *worker) Pause() {
+	w.mu.Lock()
+	w.paused = true
+	w.mu.Unlock()
+
+	w.signal()
+}
+
+// Resume makes a pass right away and continues passes skipped since Pause.
+func (w *worker) Resume() {
+	w.mu.Lock()
+	if !w.paused {
+		w.mu.Unlock()
+		return
+	}
+	w.paused = false
+	w.runNow = true
+	w.mu.Unlock()
+
+	w.signal()
+}
+
+func (w *worker) Paused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.paused
+}
+
+// Stop stops the running worker after its current pass, a worker not run yet returns right away.
+// Stopping a finished worker does nothing.
+func (w *worker) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stopped || w.finished {
+		return
+	}
+	w.stopped = true
+	close(w.stop)
+}
+
+// Done returns a channel closed when the current or the next run ends.
+// It stays closed after the run until the worker is run again.
+func (w *worker) Done() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.done
+}
+
+func (w *worker) signal() {
+	select {
+	case w.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (w *worker) takeWake() (paused, runNow bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	runNow = w.runNow
+	w.runNow = false
+
+	return w.paused, runNow
+}
+
+// stopTimer stops the timer draining its channel, it reports whether the timer has fired.
+func stopTimer(timer Timer) bool {
+	if timer.Stop() {
+		return false
+	}
+
+	select {
+	case <-timer.C():
+		return true
+	default:
+		return false
+	}
+}
+
+// isClosed reports whether the channel is closed without waiting.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
 	}
 }
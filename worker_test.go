@@ -0,0 +1,275 @@
+package txparser_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"txparser"
+	"txparser/txparsertest"
+)
+
+func Test_Worker_BacksOff(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	errs := make(chan *txparser.WorkerError, 1)
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		missingBlocksClient{head: 1},
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+		txparser.WithMaxBackoff(8*time.Second),
+		txparser.WithClock(clock),
+		txparser.WithErrorHandler(func(err *txparser.WorkerError) {
+			errs <- err
+		}),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	go func() {
+		_ = parser.RunWorker(ctx, time.Second)
+	}()
+	var attempts []int
+	var delays []time.Duration
+	for i := 0; i < 5; i++ {
+		// Errors are reported before the worker waits for the next pass
+		clock.BlockUntil(1)
+		err := <-errs
+		attempts = append(attempts, err.Attempt)
+		delays = append(delays, err.RetryIn)
+		clock.Advance(err.RetryIn)
+	}
+
+	// Assert
+	if !reflect.DeepEqual(attempts, []int{1, 2, 3, 4, 5}) {
+		t.Errorf("attempts should be %v, but are %v", []int{1, 2, 3, 4, 5}, attempts)
+	}
+	wantDelays := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second, 8 * time.Second}
+	if !reflect.DeepEqual(delays, wantDelays) {
+		t.Errorf("delays should be %v, but are %v", wantDelays, delays)
+	}
+}
+
+func Test_Worker_RetriesStart(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	errs := make(chan *txparser.WorkerError, 2)
+	client := &flakyClient{head: 3, headFailures: 2}
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		client,
+		txparser.WithClock(clock),
+		txparser.WithErrorHandler(func(err *txparser.WorkerError) {
+			errs <- err
+		}),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	go func() {
+		_ = parser.RunWorker(ctx, time.Second)
+	}()
+	clock.BlockUntil(1)
+	first := <-errs
+	clock.Advance(first.RetryIn)
+	clock.BlockUntil(1)
+	second := <-errs
+	clock.Advance(second.RetryIn)
+	// The worker waits for the next pass after it started and parsed the blocks
+	clock.BlockUntil(1)
+	status := parser.Status()
+
+	// Assert
+	if first.Attempt != 1 || second.Attempt != 2 {
+		t.Errorf("attempts should be 1 and 2, but are %d and %d", first.Attempt, second.Attempt)
+	}
+	if !errors.Is(second, errNodeDown) {
+		t.Errorf("error should be %v, but is %v", errNodeDown, second)
+	}
+	if status.State != txparser.WorkerRunning || status.LastBlock != 3 {
+		t.Errorf("worker should run at block %d, but is %+v", 3, status)
+	}
+}
+
+func Test_Worker_StartCanceled(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		&flakyClient{head: 3, headFailures: 1000},
+		txparser.WithClock(clock),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- parser.RunWorker(ctx, time.Second)
+	}()
+
+	// Act
+	clock.BlockUntil(1)
+	cancel()
+	err := <-done
+
+	// Assert
+	if !errors.Is(err, errNodeDown) {
+		t.Errorf("error should be %v, but is %v", errNodeDown, err)
+	}
+	select {
+	case <-parser.Done():
+	default:
+		t.Error("done should be closed")
+	}
+}
+
+func Test_Worker_PauseResumeStop(t *testing.T) {
+	// Arrange
+	clock := txparsertest.NewFakeClock(epoch)
+	client := &flakyClient{head: 2}
+	parser := txparser.NewTXParser(
+		txparser.NewInmemoryBlockStorage(),
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		client,
+		txparser.WithStartPolicy(txparser.StartAtBlock(1)),
+		txparser.WithClock(clock),
+	)
+	done := make(chan error, 1)
+	go func() {
+		done <- parser.RunWorker(context.Background(), time.Second)
+	}()
+	clock.BlockUntil(1)
+
+	// Act
+	parser.Pause()
+	paused := parser.Status()
+	clock.BlockUntil(0)
+	client.setHead(4)
+	clock.Advance(time.Minute)
+	pausedBlock := parser.GetCurrentBlock()
+
+	parser.Resume()
+	clock.BlockUntil(1)
+	resumed := parser.Status()
+
+	parser.Stop()
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker should stop")
+	}
+	<-parser.Done()
+
+	// Assert
+	if paused.State != txparser.WorkerPaused {
+		t.Errorf("state should be %q, but is %q", txparser.WorkerPaused, paused.State)
+	}
+	if pausedBlock != 2 {
+		t.Errorf("paused worker should stay at block %d, but is at %d", 2, pausedBlock)
+	}
+	if resumed.State != txparser.WorkerRunning {
+		t.Errorf("state should be %q, but is %q", txparser.WorkerRunning, resumed.State)
+	}
+	if resumed.LastBlock != 4 {
+		t.Errorf("resumed worker should catch up to block %d, but is at %d", 4, resumed.LastBlock)
+	}
+	if err != nil {
+		t.Error(err)
+	}
+	if parser.Status().State != txparser.WorkerStopped {
+		t.Errorf("state should be %q, but is %q", txparser.WorkerStopped, parser.Status().State)
+	}
+}
+
+func Test_Worker_StopBeforeRun(t *testing.T) {
+	// Arrange
+	ctx := context.Background()
+	blockStorage := txparser.NewInmemoryBlockStorage()
+	client := &flakyClient{head: 2}
+	parser := txparser.NewTXParser(
+		blockStorage,
+		txparser.NewInmemoryTransactionsStorage(),
+		txparser.NewInmemorySubscriptionsStorage(),
+		client,
+		txparser.WithClock(txparsertest.NewFakeClock(epoch)),
+	)
+	blockID := blockIDOf(t, ctx, blockStorage)
+
+	// Act
+	parser.Stop()
+	err := parser.RunWorker(ctx, time.Second)
+
+	// Assert
+	if err != nil {
+		t.Error(err)
+	}
+	if client.callCount() != 0 {
+		t.Errorf("client should not be called, but is called %d time(s)", client.callCount())
+	}
+	if blockIDOf(t, ctx, blockStorage) != blockID {
+		t.Errorf("block id should be %d, but is %d", blockID, blockIDOf(t, ctx, blockStorage))
+	}
+	select {
+	case <-parser.Done():
+	default:
+		t.Error("done should be closed")
+	}
+}
+
+var errNodeDown = errors.New("node is down")
+
+// flakyClient serves empty blocks up to the head, CurrentBlockNumber fails headFailures times first.
+type flakyClient struct {
+	mu           sync.Mutex
+	head         int
+	headFailures int
+	calls        int
+}
+
+func (c *flakyClient) CurrentBlockNumber(_ context.Context) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+	if c.headFailures > 0 {
+		c.headFailures--
+		return 0, errNodeDown
+	}
+
+	return c.head, nil
+}
+
+func (c *flakyClient) GetBlockByNumber(_ context.Context, _ int) (*txparser.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.calls++
+
+	return &txparser.Block{}, nil
+}
+
+func (c *flakyClient) callCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.calls
+}
+
+func (c *flakyClient) setHead(head int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.head = head
+}